@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/MosinFAM/graphql-posts/internal/auth"
 	"github.com/MosinFAM/graphql-posts/internal/db"
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/feed"
+	"github.com/MosinFAM/graphql-posts/internal/filter"
 	"github.com/MosinFAM/graphql-posts/internal/graph"
+	"github.com/MosinFAM/graphql-posts/internal/ratelimit"
 	"github.com/MosinFAM/graphql-posts/internal/storage"
 
 	"github.com/99designs/gqlgen/graphql/handler"
@@ -19,30 +29,94 @@ import (
 
 func main() {
 	storeType := os.Getenv("STORAGE_TYPE")
-	var store storage.Storage
+	var (
+		store      storage.Storage
+		limitStore ratelimit.Store
+		broker     events.Broker
+		dbConn     *sql.DB
+	)
 
-	if storeType == "postgres" {
-		dbConn, err := db.Connect()
-		if err != nil {
-			log.Fatal("Failed to connect to DB:", err)
+	// connectDB открывает одно подключение к базе на хранилище и лимиты
+	connectDB := func() *sql.DB {
+		if dbConn == nil {
+			conn, err := db.Connect()
+			if err != nil {
+				log.Fatal("Failed to connect to DB:", err)
+			}
+			dbConn = conn
 		}
+		return dbConn
+	}
+
+	if storeType == "postgres" {
+		connectDB()
 
 		dsn := os.Getenv("DATABASE_URL")
 		if dsn == "" {
 			log.Fatal("DATABASE_URL is not set")
 		}
 
-		store = storage.NewPostgresStorage(dbConn, dsn)
+		// События расходятся через LISTEN/NOTIFY по всем экземплярам сервера
+		broker = events.NewPostgresBroker(dbConn, dsn)
+		store = storage.NewPostgresStorage(dbConn, broker)
 	} else {
-		store = storage.NewMemoryStorage()
+		broker = events.NewMemoryBroker()
+		memoryStore := storage.NewMemoryStorage()
+		memoryStore.Events = broker
+		store = memoryStore
 	}
 
-	resolver := &graph.Resolver{Storage: store}
+	// RATE_LIMIT_STORE выбирает хранилище корзин независимо от STORAGE_TYPE:
+	// memory (по умолчанию) или postgres - общее для всех экземпляров сервера
+	switch limitStoreType := os.Getenv("RATE_LIMIT_STORE"); limitStoreType {
+	case "", "memory":
+		limitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		limitStore = ratelimit.NewPostgresStore(connectDB())
+	default:
+		log.Fatalf("Invalid RATE_LIMIT_STORE: %q", limitStoreType)
+	}
+
+	// Лимиты мутаций: RATE_LIMITS переопределяет значения по умолчанию,
+	// например "addComment.viewer=10/1m,*.ip=200/1m"
+	limitConfig := ratelimit.DefaultConfig()
+	limitConfig.TrustProxy = os.Getenv("RATE_LIMIT_TRUST_PROXY") == "true"
+	limitConfig, err := ratelimit.ParseConfig(limitConfig, os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatal("Invalid RATE_LIMITS:", err)
+	}
+	limiter := ratelimit.New(limitConfig, limitStore)
+
+	// Фильтр контента: списки слов и доменов задаются через запятую
+	filterConfig := filter.Config{
+		BannedWords:     splitList(os.Getenv("FILTER_BANNED_WORDS")),
+		BlockedDomains:  splitList(os.Getenv("FILTER_BLOCKED_DOMAINS")),
+		MaxLinks:        5,
+		DuplicateWindow: 10 * time.Minute,
+	}
+	if maxLinks := os.Getenv("FILTER_MAX_LINKS"); maxLinks != "" {
+		filterConfig.MaxLinks, err = strconv.Atoi(maxLinks)
+		if err != nil {
+			log.Fatal("Invalid FILTER_MAX_LINKS:", err)
+		}
+	}
+
+	// ADMIN_USER_IDS - ID зарегистрированных пользователей через запятую, которые
+	// получают роль администратора при запуске; остальные роли назначает администратор через setRole
+	authService := auth.NewService(store)
+	if err := authService.GrantAdmins(context.Background(), splitList(os.Getenv("ADMIN_USER_IDS"))); err != nil {
+		log.Fatal("Invalid ADMIN_USER_IDS:", err)
+	}
+	resolver := &graph.Resolver{Storage: store, Auth: authService, Feed: feed.New(store),
+		Filter: filter.Default(store, filterConfig), Events: broker}
 	schema := graph.NewExecutableSchema(graph.Config{Resolvers: resolver})
 	srv := handler.New(schema)
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	srv.AroundFields(limiter.AroundFields)
 
 	// Поддержка WebSockets
 	srv.AddTransport(transport.Websocket{
+		InitFunc: auth.WebsocketInitFunc(authService),
 		Upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true
@@ -70,8 +144,10 @@ func main() {
 		c.Next()
 	})
 
-	r.POST("/query", gin.WrapH(c.Handler(srv)))
-	r.GET("/query", gin.WrapH(srv))
+	// Пользователь сессии и адрес клиента попадают в контекст резолверов
+	authenticated := limiter.Middleware(auth.Middleware(authService)(srv))
+	r.POST("/query", gin.WrapH(c.Handler(authenticated)))
+	r.GET("/query", gin.WrapH(authenticated))
 
 	r.GET("/", gin.WrapH(playground.Handler("GraphQL Playground", "/query")))
 
@@ -80,3 +156,14 @@ func main() {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
+
+// splitList разбирает список значений через запятую, пустые значения пропускаются
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
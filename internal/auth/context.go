@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+type contextKey int
+
+const (
+	viewerKey contextKey = iota
+	tokenKey
+)
+
+// WithViewer кладёт текущего пользователя и токен его сессии в контекст запроса
+func WithViewer(ctx context.Context, viewer *models.User, token string) context.Context {
+	ctx = context.WithValue(ctx, viewerKey, viewer)
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// ViewerFromContext возвращает текущего пользователя или nil для анонимного запроса
+func ViewerFromContext(ctx context.Context) *models.User {
+	viewer, _ := ctx.Value(viewerKey).(*models.User)
+	return viewer
+}
+
+// TokenFromContext возвращает токен сессии текущего запроса
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey).(string)
+	return token
+}
+
+// RequireViewer возвращает текущего пользователя или ErrUnauthenticated
+func RequireViewer(ctx context.Context) (*models.User, error) {
+	viewer := ViewerFromContext(ctx)
+	if viewer == nil {
+		return nil, ErrUnauthenticated
+	}
+	return viewer, nil
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// Middleware читает заголовок "Authorization: Bearer <token>" и кладёт
+// пользователя в контекст запроса. Запрос без токена обрабатывается как
+// анонимный, недействительный токен отклоняется с кодом 401.
+func Middleware(svc *Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r.Header.Get("Authorization"))
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, err := svc.withSession(r.Context(), token)
+			if errors.Is(err, ErrUnauthenticated) {
+				http.Error(w, "invalid or expired session", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to authenticate request: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WebsocketInitFunc аутентифицирует подписки по полю "Authorization"
+// (или "authToken") в connection_init, так как браузер не позволяет
+// задать заголовки для WebSocket. Если токена нет в payload, остаётся
+// пользователь, найденный Middleware по заголовку запроса.
+func WebsocketInitFunc(svc *Service) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		token := bearerToken(payload.Authorization())
+		if token == "" {
+			token = payload.GetString("authToken")
+		}
+		if token == "" {
+			return ctx, nil, nil
+		}
+
+		ctx, err := svc.withSession(ctx, token)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ctx, nil, nil
+	}
+}
+
+// withSession проверяет токен и возвращает контекст с пользователем
+func (s *Service) withSession(ctx context.Context, token string) (context.Context, error) {
+	viewer, err := s.Authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return WithViewer(ctx, viewer, token), nil
+}
+
+// bearerToken извлекает токен из значения "Bearer <token>"
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
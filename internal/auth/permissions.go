@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// ErrForbidden - у пользователя нет прав на действие
+var ErrForbidden = errors.New("permission denied")
+
+// CanModify проверяет, что пользователь - автор контента или модератор.
+// Анонимный контент могут менять только модераторы
+func CanModify(viewer *models.User, authorID *string) error {
+	if viewer == nil {
+		return ErrUnauthenticated
+	}
+	if viewer.IsModerator() || (authorID != nil && *authorID == viewer.ID) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// RequireModerator возвращает текущего пользователя, если он модератор
+func RequireModerator(ctx context.Context) (*models.User, error) {
+	viewer, err := RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.IsModerator() {
+		return nil, ErrForbidden
+	}
+	return viewer, nil
+}
+
+// RequireAdmin возвращает текущего пользователя, если он администратор
+func RequireAdmin(ctx context.Context) (*models.User, error) {
+	viewer, err := RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.IsAdmin() {
+		return nil, ErrForbidden
+	}
+	return viewer, nil
+}
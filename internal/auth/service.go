@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultSessionTTL - срок жизни сессии по умолчанию
+const DefaultSessionTTL = 30 * 24 * time.Hour
+
+// Ограничения на пароль. bcrypt учитывает только первые 72 байта
+const (
+	MinPasswordLength = 8
+	MaxPasswordBytes  = 72
+)
+
+// Ошибки аутентификации
+var (
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrInvalidUsername    = fmt.Errorf("%w: username must be 3-32 letters, digits or underscores", ErrInvalidInput)
+	ErrInvalidPassword    = fmt.Errorf("%w: password must be %d-%d bytes long", ErrInvalidInput, MinPasswordLength, MaxPasswordBytes)
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// Хеш для сравнения при входе несуществующего пользователя,
+// чтобы время ответа не выдавало, занято ли имя
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// Service - регистрация, вход и проверка сессий поверх хранилища
+type Service struct {
+	Storage    storage.Storage
+	SessionTTL time.Duration
+}
+
+func NewService(store storage.Storage) *Service {
+	return &Service{Storage: store, SessionTTL: DefaultSessionTTL}
+}
+
+// Register создаёт пользователя и сразу открывает для него сессию
+func (s *Service) Register(ctx context.Context, username, password string) (*models.User, string, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, "", ErrInvalidUsername
+	}
+	if utf8.RuneCountInString(password) < MinPasswordLength || len(password) > MaxPasswordBytes {
+		return nil, "", ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Failed to hash password: %v", err)
+		return nil, "", err
+	}
+
+	user, err := s.Storage.CreateUser(ctx, username, string(hash))
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.openSession(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// Login проверяет пароль и открывает новую сессию
+func (s *Service) Login(ctx context.Context, username, password string) (*models.User, string, error) {
+	user, err := s.Storage.GetUserByUsername(ctx, username)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, "", ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, "", ErrInvalidCredentials
+	}
+
+	token, err := s.openSession(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// Logout закрывает сессию с указанным токеном
+func (s *Service) Logout(ctx context.Context, token string) error {
+	err := s.Storage.DeleteSession(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return ErrUnauthenticated
+	}
+	return err
+}
+
+// Authenticate возвращает владельца действующей сессии.
+// Неизвестный или истёкший токен даёт ErrUnauthenticated
+func (s *Service) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	session, err := s.Storage.GetSession(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.Storage.GetUserByID(ctx, session.UserID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	return user, err
+}
+
+// GrantAdmins назначает роль администратора уже зарегистрированным пользователям
+// с переданными ID. Вызывается оператором при запуске сервера, чтобы назначить
+// первого администратора, который раздаёт роли остальным; имя пользователя для этого
+// не подходит, так как его может занять любой, кто зарегистрируется первым
+func (s *Service) GrantAdmins(ctx context.Context, userIDs []string) error {
+	for _, id := range userIDs {
+		user, err := s.Storage.GetUserByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("admin %s: %w", id, err)
+		}
+		if user.IsAdmin() {
+			continue
+		}
+		log.Printf("Granting admin role to %s (%s)", user.Username, user.ID)
+		if _, err := s.Storage.SetUserRole(ctx, user.ID, models.RoleAdmin); err != nil {
+			return fmt.Errorf("admin %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// openSession генерирует токен и сохраняет сессию с его хешем
+func (s *Service) openSession(ctx context.Context, userID string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	session := models.Session{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.SessionTTL),
+	}
+	if err := s.Storage.CreateSession(ctx, session); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// hashToken - в хранилище попадает только SHA-256 от токена
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
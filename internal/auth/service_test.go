@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister_Validation(t *testing.T) {
+	svc := NewService(storage.NewMemoryStorage())
+
+	_, _, err := svc.Register(context.Background(), "a!", "long enough password")
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, _, err = svc.Register(context.Background(), "alice", "short")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	_, _, err = svc.Register(context.Background(), "alice", "long enough password")
+	assert.NoError(t, err)
+
+	_, _, err = svc.Register(context.Background(), "ALICE", "long enough password")
+	assert.ErrorIs(t, err, storage.ErrUsernameTaken)
+	assert.ErrorIs(t, err, storage.ErrConflict)
+}
+
+func TestLogin(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	svc := NewService(store)
+
+	registered, _, err := svc.Register(context.Background(), "alice", "long enough password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "long enough password", registered.PasswordHash)
+
+	_, _, err = svc.Login(context.Background(), "alice", "wrong password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, _, err = svc.Login(context.Background(), "bob", "long enough password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	user, token, err := svc.Login(context.Background(), "Alice", "long enough password")
+	assert.NoError(t, err)
+	assert.Equal(t, registered.ID, user.ID)
+
+	viewer, err := svc.Authenticate(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, registered.ID, viewer.ID)
+
+	// В хранилище лежит только хеш токена
+	_, err = store.GetSession(context.Background(), token)
+	assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+}
+
+func TestGrantAdmins(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	svc := NewService(store)
+
+	alice, _, err := svc.Register(context.Background(), "alice", "long enough password")
+	assert.NoError(t, err)
+	assert.NoError(t, svc.GrantAdmins(context.Background(), []string{alice.ID}))
+	user, _, err := svc.Login(context.Background(), "alice", "long enough password")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+	assert.NoError(t, svc.GrantAdmins(context.Background(), []string{alice.ID}))
+
+	// Регистрация и вход сами по себе роль не дают
+	bob, _, err := svc.Register(context.Background(), "bob", "long enough password")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, bob.Role)
+	user, _, err = svc.Login(context.Background(), "bob", "long enough password")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	err = svc.GrantAdmins(context.Background(), []string{"00000000-0000-0000-0000-000000000000"})
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+}
+
+func TestAuthenticate_ExpiredSession(t *testing.T) {
+	svc := NewService(storage.NewMemoryStorage())
+	svc.SessionTTL = -time.Minute
+
+	_, token, err := svc.Register(context.Background(), "alice", "long enough password")
+	assert.NoError(t, err)
+
+	_, err = svc.Authenticate(context.Background(), token)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestMiddleware(t *testing.T) {
+	svc := NewService(storage.NewMemoryStorage())
+	_, token, err := svc.Register(context.Background(), "alice", "long enough password")
+	assert.NoError(t, err)
+
+	var viewer *models.User
+	handler := Middleware(svc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		viewer = ViewerFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Nil(t, viewer)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NotNil(t, viewer)
+	assert.Equal(t, "alice", viewer.Username)
+
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,26 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	lines := Lines("a\nb\nc\nd", "a\nc\nx\nd")
+
+	assert.Equal(t, []Line{
+		{Op: Equal, Text: "a"},
+		{Op: Delete, Text: "b"},
+		{Op: Equal, Text: "c"},
+		{Op: Insert, Text: "x"},
+		{Op: Equal, Text: "d"},
+	}, lines)
+}
+
+func TestUnified(t *testing.T) {
+	assert.Equal(t, "+hello\n", Unified("", "hello"))
+	assert.Equal(t, "-old\n+new\n", Unified("old", "new"))
+	assert.Equal(t, " same\n", Unified("same", "same\n"))
+	assert.Equal(t, "", Unified("", ""))
+}
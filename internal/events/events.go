@@ -0,0 +1,77 @@
+// Package events доставляет доменные события подписчикам: хранилище публикует
+// событие в тему, подписки GraphQL получают его на любом экземпляре сервера.
+package events
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Типы событий
+const (
+	PostAdded           = "post_added"
+	PostUpdated         = "post_updated"
+	CommentAdded        = "comment_added"
+	CommentUpdated      = "comment_updated"
+	CommentDeleted      = "comment_deleted"
+	ScoreChanged        = "score_changed"
+	NotificationCreated = "notification_created"
+)
+
+// PostsTopic - тема всех новых постов
+const PostsTopic = "posts"
+
+// CommunityTopic - тема новых постов сообщества
+func CommunityTopic(communityID string) string {
+	return "community:" + communityID
+}
+
+// PostTopic - тема событий поста: изменения самого поста, новые, изменённые
+// и удалённые комментарии
+func PostTopic(postID string) string {
+	return "post:" + postID
+}
+
+// ScoreTopic - тема изменений рейтинга поста или комментария
+func ScoreTopic(targetID string) string {
+	return "score:" + targetID
+}
+
+// UserTopic - тема событий пользователя: новые уведомления
+func UserTopic(userID string) string {
+	return "user:" + userID
+}
+
+// Event - доменное событие: что произошло (Type) с объектом ID. Data - объект в JSON;
+// брокер может опустить его, если событие не помещается в сообщение, тогда
+// подписчик читает объект из хранилища по ID
+type Event struct {
+	Type string          `json:"type"`
+	ID   string          `json:"id"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEvent собирает событие с объектом data
+func NewEvent(eventType, id string, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, ID: id, Data: raw}, nil
+}
+
+// Decode разбирает объект события в v. Возвращает false, если объекта в событии нет
+func (e Event) Decode(v interface{}) (bool, error) {
+	if len(e.Data) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(e.Data, v)
+}
+
+// Broker публикует события и подписывает на них по темам. Доставка - лучшее
+// усилие: подписчик, который не успевает читать, пропускает события.
+// Канал подписки закрывается после отмены ctx
+type Broker interface {
+	Publish(ctx context.Context, topic string, event Event) error
+	Subscribe(ctx context.Context, topic string) (<-chan Event, error)
+}
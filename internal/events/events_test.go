@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testObject struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+func TestEvent_Decode(t *testing.T) {
+	event, err := NewEvent(CommentAdded, "c1", testObject{ID: "c1", Content: "it's a 'quoted' | text"})
+	assert.NoError(t, err)
+
+	var object testObject
+	ok, err := event.Decode(&object)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "it's a 'quoted' | text", object.Content)
+
+	ok, err = Event{Type: CommentAdded, ID: "c1"}.Decode(&object)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	first, err := broker.Subscribe(ctx, PostTopic("p1"))
+	assert.NoError(t, err)
+	second, err := broker.Subscribe(context.Background(), PostTopic("p1"))
+	assert.NoError(t, err)
+	other, err := broker.Subscribe(context.Background(), PostTopic("p2"))
+	assert.NoError(t, err)
+
+	event := Event{Type: PostUpdated, ID: "p1"}
+	assert.NoError(t, broker.Publish(context.Background(), PostTopic("p1"), event))
+	assert.Equal(t, event, <-first)
+	assert.Equal(t, event, <-second)
+	assert.Empty(t, other)
+
+	// Отменённый подписчик удаляется, его канал закрывается
+	cancel()
+	_, open := <-first
+	assert.False(t, open)
+	assert.NoError(t, broker.Publish(context.Background(), PostTopic("p1"), event))
+	assert.Equal(t, event, <-second)
+
+	// Переполненный подписчик пропускает события, не блокируя публикацию
+	for i := 0; i < subscriberBuffer+1; i++ {
+		assert.NoError(t, broker.Publish(context.Background(), PostTopic("p2"), event))
+	}
+	assert.Len(t, other, subscriberBuffer)
+
+	_, err = broker.Subscribe(ctx, PostTopic("p1"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestEncodeMessage(t *testing.T) {
+	event, err := NewEvent(CommentAdded, "c1", testObject{ID: "c1", Content: "short"})
+	assert.NoError(t, err)
+
+	payload, err := encodeMessage(PostTopic("p1"), event)
+	assert.NoError(t, err)
+	var msg message
+	assert.NoError(t, json.Unmarshal([]byte(payload), &msg))
+	assert.Equal(t, message{Topic: "post:p1", Event: event}, msg)
+
+	// Большой объект не помещается в уведомление - остаётся только ID
+	event, err = NewEvent(CommentAdded, "c1", testObject{ID: "c1", Content: strings.Repeat("ж", maxPayload)})
+	assert.NoError(t, err)
+	payload, err = encodeMessage(PostTopic("p1"), event)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(payload), maxPayload)
+	msg = message{}
+	assert.NoError(t, json.Unmarshal([]byte(payload), &msg))
+	assert.Equal(t, Event{Type: CommentAdded, ID: "c1"}, msg.Event)
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// subscriberBuffer - сколько событий копится у подписчика; остальные пропускаются,
+// чтобы медленный подписчик не задерживал публикацию
+const subscriberBuffer = 16
+
+// MemoryBroker раздаёт события подписчикам своего процесса - подходит для одного
+// экземпляра сервера
+type MemoryBroker struct {
+	subscribers map[string]map[chan Event]struct{} // тема → подписчики
+	mu          sync.Mutex
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Subscriber of %s is not ready, event %s skipped", topic, event.Type)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string) (<-chan Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[chan Event]struct{})
+	}
+	b.subscribers[topic][ch] = struct{}{}
+	b.mu.Unlock()
+	log.Printf("Subscribed to %s", topic)
+
+	// Отписка при завершении контекста: удаляем подписчика и закрываем канал
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[topic], ch)
+		if len(b.subscribers[topic]) == 0 {
+			delete(b.subscribers, topic)
+		}
+		close(ch)
+		log.Printf("Unsubscribed from %s", topic)
+	}()
+
+	return ch, nil
+}
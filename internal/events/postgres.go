@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// postgresChannel - канал LISTEN/NOTIFY, через который экземпляры сервера обмениваются событиями
+const postgresChannel = "events"
+
+// maxPayload - предел размера уведомления (8000 байт в PostgreSQL) с запасом
+const maxPayload = 7900
+
+// publishTimeout ограничивает pg_notify, который уже не зависит от запроса клиента
+const publishTimeout = 5 * time.Second
+
+// message - уведомление канала: событие и его тема
+type message struct {
+	Topic string `json:"topic"`
+	Event Event  `json:"event"`
+}
+
+// encodeMessage собирает уведомление; объект события опускается, если не помещается в maxPayload
+func encodeMessage(topic string, event Event) (string, error) {
+	payload, err := json.Marshal(message{Topic: topic, Event: event})
+	if err != nil {
+		return "", err
+	}
+	if len(payload) > maxPayload && event.Data != nil {
+		event.Data = nil
+		if payload, err = json.Marshal(message{Topic: topic, Event: event}); err != nil {
+			return "", err
+		}
+	}
+	return string(payload), nil
+}
+
+// PostgresBroker рассылает события через LISTEN/NOTIFY, поэтому подписчик получает
+// событие, опубликованное любым экземпляром сервера. Каждый экземпляр держит одно
+// соединение LISTEN, которое открывается при первой подписке и живёт до завершения
+// процесса, и раздаёт события своим подписчикам в памяти
+type PostgresBroker struct {
+	DB         *sql.DB
+	DataSource string
+	local      *MemoryBroker
+	listener   *pq.Listener
+	mu         sync.Mutex
+}
+
+func NewPostgresBroker(db *sql.DB, dataSource string) *PostgresBroker {
+	return &PostgresBroker{DB: db, DataSource: dataSource, local: NewMemoryBroker()}
+}
+
+// Publish вызывается после сохранения изменения, поэтому отключение клиента не
+// должно терять событие: уведомление отправляется без отмены ctx запроса, а ошибка
+// отправки только логируется
+func (b *PostgresBroker) Publish(ctx context.Context, topic string, event Event) error {
+	payload, err := encodeMessage(topic, event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+	defer cancel()
+	if _, err := b.DB.ExecContext(ctx, "SELECT pg_notify($1, $2)", postgresChannel, payload); err != nil {
+		log.Printf("Failed to publish %s to %s: %v", event.Type, topic, err)
+	}
+	return nil
+}
+
+func (b *PostgresBroker) Subscribe(ctx context.Context, topic string) (<-chan Event, error) {
+	if err := b.listen(); err != nil {
+		return nil, err
+	}
+	return b.local.Subscribe(ctx, topic)
+}
+
+// listen открывает соединение LISTEN, если оно ещё не открыто
+func (b *PostgresBroker) listen() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.listener != nil {
+		return nil
+	}
+
+	listener := pq.NewListener(b.DataSource, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("Postgres Listener error:", err)
+		}
+	})
+	if err := listener.Listen(postgresChannel); err != nil {
+		log.Printf("Failed to listen on %s: %v", postgresChannel, err)
+		listener.Close()
+		return fmt.Errorf("failed to listen on %s: %w", postgresChannel, err)
+	}
+	log.Printf("Listening for events on %s", postgresChannel)
+
+	b.listener = listener
+	go b.run(listener)
+	return nil
+}
+
+// run читает уведомления соединения и раздаёт события подписчикам процесса
+func (b *PostgresBroker) run(listener *pq.Listener) {
+	for {
+		select {
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// Соединение переустановлено, события за время разрыва потеряны
+				log.Println("Postgres Listener reconnected")
+				continue
+			}
+			var msg message
+			if err := json.Unmarshal([]byte(notification.Extra), &msg); err != nil {
+				log.Printf("Invalid event %q: %v", notification.Extra, err)
+				continue
+			}
+			_ = b.local.Publish(context.Background(), msg.Topic, msg.Event)
+
+		case <-time.After(90 * time.Second):
+			// Проверяем соединение каждые 90 секунд
+			if err := listener.Ping(); err != nil {
+				log.Println("Postgres Listener ping error:", err)
+			}
+		}
+	}
+}
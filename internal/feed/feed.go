@@ -0,0 +1,111 @@
+// Package feed собирает домашнюю ленту пользователя из нескольких выборок постов.
+package feed
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// Feed строит домашнюю ленту поверх storage.Storage. Лента подписанного
+// пользователя объединяет посты отслеживаемых авторов, тегов и сообществ,
+// в которых он состоит; анонимный пользователь и пользователь без подписок
+// видят общую ленту популярного.
+type Feed struct {
+	Storage storage.Storage
+}
+
+func New(store storage.Storage) *Feed {
+	return &Feed{Storage: store}
+}
+
+// Home возвращает страницу домашней ленты viewer (nil - анонимный пользователь).
+// Все источники упорядочены одной сортировкой и листаются одним курсором,
+// поэтому страница - это первые First постов их слияния без повторов.
+func (f *Feed) Home(ctx context.Context, viewer *models.User, opts storage.PostListOptions) (*storage.PostPage, error) {
+	if viewer == nil {
+		return f.Storage.GetAllPosts(ctx, opts)
+	}
+
+	sources, err := f.sources(ctx, viewer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		log.Printf("User %s has no interests, falling back to popular feed", viewer.ID)
+		return f.Storage.GetAllPosts(ctx, opts)
+	}
+
+	limit := storage.PageSize(opts.First)
+	var (
+		posts       []models.Post
+		hasNextPage bool
+	)
+	for _, source := range sources {
+		source.First, source.After, source.Sort, source.TimeRange = limit, opts.After, opts.Sort, opts.TimeRange
+		page, err := f.Storage.GetAllPosts(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, page.Posts...)
+		// Пост за пределами страницы источника не попадёт и в общую страницу
+		hasNextPage = hasNextPage || page.HasNextPage
+	}
+
+	less := opts.SortOrder().Less
+	slices.SortFunc(posts, func(a, b models.Post) int {
+		switch {
+		case less(&a, &b):
+			return -1
+		case less(&b, &a):
+			return 1
+		}
+		return 0
+	})
+	// Один пост может прийти из нескольких источников, копии стоят рядом
+	posts = slices.CompactFunc(posts, func(a, b models.Post) bool { return a.ID == b.ID })
+	if len(posts) > limit {
+		posts, hasNextPage = posts[:limit], true
+	}
+	return &storage.PostPage{Posts: posts, HasNextPage: hasNextPage}, nil
+}
+
+// sources возвращает фильтры выборок, из которых складывается лента пользователя
+func (f *Feed) sources(ctx context.Context, userID string) ([]storage.PostListOptions, error) {
+	interests, err := f.Storage.GetInterests(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	communities, err := f.Storage.GetJoinedCommunities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var authors, tags, communityIDs []string
+	for _, interest := range interests {
+		switch interest.Kind {
+		case models.InterestAuthor:
+			authors = append(authors, interest.Target)
+		case models.InterestTag:
+			tags = append(tags, interest.Target)
+		}
+	}
+	for _, community := range communities {
+		communityIDs = append(communityIDs, community.ID)
+	}
+
+	var sources []storage.PostListOptions
+	if len(authors) > 0 {
+		sources = append(sources, storage.PostListOptions{AuthorIDs: authors})
+	}
+	if len(tags) > 0 {
+		sources = append(sources, storage.PostListOptions{Tags: tags})
+	}
+	if len(communityIDs) > 0 {
+		sources = append(sources, storage.PostListOptions{CommunityIDs: communityIDs})
+	}
+	return sources, nil
+}
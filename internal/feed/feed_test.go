@@ -0,0 +1,90 @@
+package feed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHome_Anonymous(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	author := testUser(t, store, "author")
+	addPost(t, store, author.ID, nil, "p1")
+	addPost(t, store, author.ID, nil, "p2")
+
+	page, err := New(store).Home(context.Background(), nil, storage.PostListOptions{Sort: storage.PostSortNew})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p2", "p1"}, titles(page))
+	assert.False(t, page.HasNextPage)
+}
+
+func TestHome_NoInterests(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	viewer := testUser(t, store, "viewer")
+	author := testUser(t, store, "author")
+	addPost(t, store, author.ID, nil, "p1")
+
+	page, err := New(store).Home(context.Background(), viewer, storage.PostListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p1"}, titles(page))
+}
+
+func TestHome_MergesSources(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	viewer := testUser(t, store, "viewer")
+	alice := testUser(t, store, "alice")
+	bob := testUser(t, store, "bob")
+
+	community, err := store.CreateCommunity(ctx, bob.ID, "golang", "")
+	assert.NoError(t, err)
+	_, err = store.JoinCommunity(ctx, viewer.ID, community.ID)
+	assert.NoError(t, err)
+	_, err = store.FollowInterest(ctx, viewer.ID, models.InterestAuthor, alice.ID)
+	assert.NoError(t, err)
+	_, err = store.FollowInterest(ctx, viewer.ID, models.InterestTag, "go")
+	assert.NoError(t, err)
+
+	addPost(t, store, alice.ID, nil, "alice", "go")           // автор и тег одновременно
+	addPost(t, store, bob.ID, nil, "bob unrelated")           // не попадает в ленту
+	addPost(t, store, bob.ID, nil, "bob tagged", "go")        // тег
+	addPost(t, store, bob.ID, &community.ID, "bob community") // сообщество
+	addPost(t, store, alice.ID, nil, "alice again")           // автор
+
+	feed := New(store)
+	page, err := feed.Home(ctx, viewer, storage.PostListOptions{First: 2, Sort: storage.PostSortNew})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice again", "bob community"}, titles(page))
+	assert.True(t, page.HasNextPage)
+
+	after := storage.PostCursor(&page.Posts[1])
+	page, err = feed.Home(ctx, viewer, storage.PostListOptions{First: 2, Sort: storage.PostSortNew, After: after})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob tagged", "alice"}, titles(page))
+	assert.False(t, page.HasNextPage)
+}
+
+func testUser(t *testing.T, store storage.Storage, username string) *models.User {
+	t.Helper()
+	user, err := store.CreateUser(context.Background(), username, "hash")
+	assert.NoError(t, err)
+	return user
+}
+
+func addPost(t *testing.T, store storage.Storage, authorID string, communityID *string, title string, tags ...string) {
+	t.Helper()
+	_, err := store.AddPost(context.Background(), authorID, communityID, title, "Content", tags, true, models.ModerationVisible)
+	assert.NoError(t, err)
+}
+
+func titles(page *storage.PostPage) []string {
+	var titles []string
+	for _, post := range page.Posts {
+		titles = append(titles, post.Title)
+	}
+	return titles
+}
@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// Пороги байесовского классификатора по умолчанию
+const (
+	DefaultFlagThreshold   = 0.9
+	DefaultRejectThreshold = 0.99
+	DefaultMinTrainingDocs = 10
+)
+
+// Ограничения слов классификатора
+const (
+	maxTokenLength = 32
+	maxTokens      = 200
+)
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// words разбивает текст на слова в нижнем регистре
+func words(text string) []string {
+	return wordPattern.FindAllString(strings.ToLower(text), -1)
+}
+
+// Tokenize возвращает различные слова текста для классификатора: однобуквенные
+// и слишком длинные слова отбрасываются, домены ссылок добавляются как отдельные признаки
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if !seen[token] && len(tokens) < maxTokens {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	for _, link := range links(text) {
+		if host := linkHost(link); host != "" {
+			add("domain:" + host)
+		}
+	}
+	for _, word := range words(text) {
+		if n := utf8.RuneCountInString(word); n > 1 && n <= maxTokenLength {
+			add(word)
+		}
+	}
+	return tokens
+}
+
+// Bayes - наивный байесовский классификатор спама, обученный на решениях
+// модераторов. Пока каждого класса меньше MinTrainingDocs текстов, пропускает всё
+type Bayes struct {
+	Storage         storage.Storage
+	FlagThreshold   float64
+	RejectThreshold float64
+	MinTrainingDocs int
+}
+
+func NewBayes(store storage.Storage) *Bayes {
+	return &Bayes{
+		Storage:         store,
+		FlagThreshold:   DefaultFlagThreshold,
+		RejectThreshold: DefaultRejectThreshold,
+		MinTrainingDocs: DefaultMinTrainingDocs,
+	}
+}
+
+func (f *Bayes) Name() string { return "bayes" }
+
+func (f *Bayes) Check(ctx context.Context, content Content) (Decision, error) {
+	tokens := Tokenize(content.FullText())
+	stats, err := f.Storage.GetClassifierStats(ctx, tokens)
+	if err != nil {
+		return Accept, err
+	}
+	if stats.SpamDocs < f.MinTrainingDocs || stats.HamDocs < f.MinTrainingDocs {
+		return Accept, nil
+	}
+
+	p := spamProbability(stats, tokens)
+	reason := fmt.Sprintf("spam probability %.2f", p)
+	switch {
+	case p >= f.RejectThreshold:
+		return Decision{Verdict: models.FilterReject, Reason: reason}, nil
+	case p >= f.FlagThreshold:
+		return Decision{Verdict: models.FilterFlag, Reason: reason}, nil
+	}
+	return Accept, nil
+}
+
+// spamProbability - вероятность того, что текст со словами tokens - спам.
+// Частоты слов сглажены по Лапласу, слова, которых не было в обучающих
+// текстах, не учитываются
+func spamProbability(stats *storage.ClassifierStats, tokens []string) float64 {
+	spamDocs, hamDocs := float64(stats.SpamDocs), float64(stats.HamDocs)
+	logSpam := math.Log(spamDocs / (spamDocs + hamDocs))
+	logHam := math.Log(hamDocs / (spamDocs + hamDocs))
+	for _, token := range tokens {
+		counts, exists := stats.Tokens[token]
+		if !exists {
+			continue
+		}
+		logSpam += math.Log((float64(counts.Spam) + 1) / (spamDocs + 2))
+		logHam += math.Log((float64(counts.Ham) + 1) / (hamDocs + 2))
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam))
+}
@@ -0,0 +1,164 @@
+// Package filter проверяет новые посты и комментарии перед публикацией:
+// цепочка фильтров принимает контент, отправляет его на проверку модератору
+// или отклоняет с причиной.
+package filter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// ErrRejected - контент отклонён фильтром
+var ErrRejected = errors.New("content rejected")
+
+// RejectedError - контент отклонён фильтром Filter по причине Reason.
+// Совпадает с ErrRejected при проверке через errors.Is
+type RejectedError struct {
+	Filter string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRejected, e.Reason)
+}
+
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+// Content - проверяемый пост или комментарий
+type Content struct {
+	Kind     models.ContentKind
+	AuthorID string
+	Title    string // пусто у комментария
+	Text     string
+}
+
+// FullText возвращает заголовок и текст одной строкой
+func (c Content) FullText() string {
+	if c.Title == "" {
+		return c.Text
+	}
+	return c.Title + "\n" + c.Text
+}
+
+// Decision - решение фильтра. Filter заполняет цепочка
+type Decision struct {
+	Verdict models.FilterVerdict
+	Filter  string
+	Reason  string
+}
+
+// Accept - решение «пропустить»
+var Accept = Decision{Verdict: models.FilterAccept}
+
+// Filter - один фильтр цепочки
+type Filter interface {
+	Name() string
+	Check(ctx context.Context, content Content) (Decision, error)
+}
+
+// Pipeline - цепочка фильтров. Фильтры вызываются по порядку, действует самое
+// строгое решение; первое отклонение останавливает цепочку. Ошибка фильтра
+// не блокирует публикацию. Решения, кроме «пропустить», пишутся в журнал хранилища
+type Pipeline struct {
+	Filters []Filter
+	Storage storage.Storage
+}
+
+func New(store storage.Storage, filters ...Filter) *Pipeline {
+	return &Pipeline{Filters: filters, Storage: store}
+}
+
+// Config - настройки встроенных фильтров; пустые списки и нулевые значения
+// отключают соответствующий фильтр
+type Config struct {
+	BannedWords     []string
+	BlockedDomains  []string
+	MaxLinks        int
+	DuplicateWindow time.Duration
+}
+
+// Default собирает цепочку встроенных фильтров: запрещённые слова, домены,
+// число ссылок, повторы и наивный байесовский классификатор
+func Default(store storage.Storage, config Config) *Pipeline {
+	var filters []Filter
+	if len(config.BannedWords) > 0 {
+		filters = append(filters, NewBannedWords(config.BannedWords))
+	}
+	if len(config.BlockedDomains) > 0 {
+		filters = append(filters, NewDomainBlocklist(config.BlockedDomains))
+	}
+	if config.MaxLinks > 0 {
+		filters = append(filters, &LinkLimit{Max: config.MaxLinks})
+	}
+	if config.DuplicateWindow > 0 {
+		filters = append(filters, NewDuplicate(config.DuplicateWindow))
+	}
+	filters = append(filters, NewBayes(store))
+	return New(store, filters...)
+}
+
+// Check прогоняет контент через цепочку и возвращает итоговое решение
+func (p *Pipeline) Check(ctx context.Context, content Content) Decision {
+	result := Accept
+	for _, f := range p.Filters {
+		decision, err := f.Check(ctx, content)
+		if err != nil {
+			log.Printf("Filter %s failed: %v", f.Name(), err)
+			continue
+		}
+		if decision.Verdict.Severity() <= result.Verdict.Severity() {
+			continue
+		}
+		decision.Filter = f.Name()
+		result = decision
+		if result.Verdict == models.FilterReject {
+			break
+		}
+	}
+	return result
+}
+
+// Record пишет решение в журнал. targetID - ID опубликованного объекта, nil у отклонённого
+func (p *Pipeline) Record(ctx context.Context, content Content, decision Decision, targetID *string) {
+	if decision.Verdict == models.FilterAccept {
+		return
+	}
+	log.Printf("Filter %s: %s %s by %s: %s", decision.Filter, decision.Verdict, content.Kind, content.AuthorID, decision.Reason)
+	_, err := p.Storage.RecordFilterDecision(ctx, models.FilterDecision{
+		Kind:     content.Kind,
+		TargetID: targetID,
+		AuthorID: &content.AuthorID,
+		Content:  content.FullText(),
+		Verdict:  decision.Verdict,
+		Filter:   decision.Filter,
+		Reason:   decision.Reason,
+	})
+	if err != nil {
+		log.Printf("Failed to record filter decision: %v", err)
+	}
+}
+
+// Train обучает классификатор на решении модератора: spam - контент удалён как спам
+func (p *Pipeline) Train(ctx context.Context, content Content, spam bool) error {
+	return p.Storage.TrainClassifier(ctx, spam, Tokenize(content.FullText()))
+}
+
+// Untrain отменяет обучение на решении модератора, которое он потом изменил
+func (p *Pipeline) Untrain(ctx context.Context, content Content, spam bool) error {
+	return p.Storage.UntrainClassifier(ctx, spam, Tokenize(content.FullText()))
+}
+
+// IsSpamReason - называет ли причина удаления контент спамом
+func IsSpamReason(reason string) bool {
+	reason = strings.ToLower(reason)
+	return strings.Contains(reason, "spam") || strings.Contains(reason, "спам")
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// verdictFilter возвращает заданное решение или ошибку
+type verdictFilter struct {
+	name     string
+	decision Decision
+	err      error
+	calls    int
+}
+
+func (f *verdictFilter) Name() string { return f.name }
+
+func (f *verdictFilter) Check(ctx context.Context, content Content) (Decision, error) {
+	f.calls++
+	return f.decision, f.err
+}
+
+func comment(text string) Content {
+	return Content{Kind: models.ContentComment, AuthorID: "u1", Text: text}
+}
+
+func TestPipeline_StrictestDecision(t *testing.T) {
+	broken := &verdictFilter{name: "broken", err: errors.New("boom")}
+	flag := &verdictFilter{name: "flag", decision: Decision{Verdict: models.FilterFlag, Reason: "suspicious"}}
+	reject := &verdictFilter{name: "reject", decision: Decision{Verdict: models.FilterReject, Reason: "spam"}}
+	last := &verdictFilter{name: "last", decision: Accept}
+
+	decision := New(storage.NewMemoryStorage(), broken, flag, last).Check(context.Background(), comment("text"))
+	assert.Equal(t, Decision{Verdict: models.FilterFlag, Filter: "flag", Reason: "suspicious"}, decision)
+
+	// Отклонение останавливает цепочку
+	decision = New(storage.NewMemoryStorage(), flag, reject, last).Check(context.Background(), comment("text"))
+	assert.Equal(t, "reject", decision.Filter)
+	assert.Equal(t, 1, last.calls)
+}
+
+func TestPipeline_Record(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	pipeline := New(store)
+	targetID := "c1"
+
+	pipeline.Record(context.Background(), comment("fine"), Accept, &targetID)
+	pipeline.Record(context.Background(), comment("buy now"), Decision{Verdict: models.FilterReject, Filter: "bayes", Reason: "spam"}, nil)
+
+	decisions, err := store.GetFilterDecisions(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, decisions, 1)
+	assert.Equal(t, models.FilterReject, decisions[0].Verdict)
+	assert.Equal(t, "buy now", decisions[0].Content)
+	assert.Nil(t, decisions[0].TargetID)
+}
+
+func TestBuiltinFilters(t *testing.T) {
+	ctx := context.Background()
+
+	words := NewBannedWords([]string{"Casino"})
+	decision, err := words.Check(ctx, comment("Best CASINO bonus"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.FilterReject, decision.Verdict)
+	decision, _ = words.Check(ctx, comment("casinos are not banned"))
+	assert.Equal(t, models.FilterAccept, decision.Verdict)
+
+	links := &LinkLimit{Max: 2}
+	decision, _ = links.Check(ctx, comment("https://a.com http://b.com www.c.com"))
+	assert.Equal(t, models.FilterFlag, decision.Verdict)
+	decision, _ = links.Check(ctx, comment("https://a.com and https://b.com"))
+	assert.Equal(t, models.FilterAccept, decision.Verdict)
+
+	domains := NewDomainBlocklist([]string{"spam.example"})
+	decision, _ = domains.Check(ctx, comment("see https://shop.SPAM.example/deal"))
+	assert.Equal(t, models.FilterReject, decision.Verdict)
+	decision, _ = domains.Check(ctx, comment("see https://notspam.example"))
+	assert.Equal(t, models.FilterAccept, decision.Verdict)
+}
+
+func TestDuplicate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	duplicate := NewDuplicate(10 * time.Minute)
+	duplicate.now = func() time.Time { return now }
+
+	decision, _ := duplicate.Check(ctx, comment("Hello  world"))
+	assert.Equal(t, models.FilterAccept, decision.Verdict)
+	decision, _ = duplicate.Check(ctx, comment("hello world"))
+	assert.Equal(t, models.FilterReject, decision.Verdict)
+
+	// Тот же текст другого автора - не повтор
+	other := comment("hello world")
+	other.AuthorID = "u2"
+	decision, _ = duplicate.Check(ctx, other)
+	assert.Equal(t, models.FilterAccept, decision.Verdict)
+
+	now = now.Add(11 * time.Minute)
+	decision, _ = duplicate.Check(ctx, comment("hello world"))
+	assert.Equal(t, models.FilterAccept, decision.Verdict)
+}
+
+func TestBayes_TrainedByModerators(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	pipeline := New(store, NewBayes(store))
+
+	// Необученный классификатор пропускает всё
+	assert.Equal(t, Accept, pipeline.Check(ctx, comment("cheap pills buy now")))
+
+	for i := 0; i < DefaultMinTrainingDocs; i++ {
+		n := strconv.Itoa(i)
+		assert.NoError(t, pipeline.Train(ctx, comment("cheap pills buy now offer"+n), true))
+		assert.NoError(t, pipeline.Train(ctx, comment("interesting article about golang generics "+n), false))
+	}
+
+	decision := pipeline.Check(ctx, comment("buy cheap pills now"))
+	assert.Equal(t, models.FilterReject, decision.Verdict)
+	assert.Equal(t, "bayes", decision.Filter)
+	assert.Equal(t, models.FilterAccept, pipeline.Check(ctx, comment("golang generics article")).Verdict)
+
+	assert.True(t, IsSpamReason("Spam links"))
+	assert.True(t, IsSpamReason("спам"))
+	assert.False(t, IsSpamReason("off-topic"))
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("Visit https://Shop.example/x NOW now, a b")
+	assert.Equal(t, []string{"domain:shop.example", "visit", "https", "shop", "example", "now"}, tokens)
+}
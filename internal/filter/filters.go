@@ -0,0 +1,144 @@
+package filter
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// BannedWords отклоняет контент с запрещёнными словами без учёта регистра
+type BannedWords struct {
+	words map[string]bool
+}
+
+func NewBannedWords(words []string) *BannedWords {
+	f := &BannedWords{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		f.words[strings.ToLower(strings.TrimSpace(word))] = true
+	}
+	return f
+}
+
+func (f *BannedWords) Name() string { return "banned_words" }
+
+func (f *BannedWords) Check(ctx context.Context, content Content) (Decision, error) {
+	for _, word := range words(content.FullText()) {
+		if f.words[word] {
+			return Decision{Verdict: models.FilterReject, Reason: fmt.Sprintf("contains banned word %q", word)}, nil
+		}
+	}
+	return Accept, nil
+}
+
+// linkPattern находит ссылки с протоколом или начинающиеся с www.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>()\[\]"']+`)
+
+// links возвращает ссылки текста
+func links(text string) []string {
+	return linkPattern.FindAllString(text, -1)
+}
+
+// linkHost возвращает домен ссылки в нижнем регистре
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// LinkLimit отправляет на проверку контент, в котором больше Max ссылок
+type LinkLimit struct {
+	Max int
+}
+
+func (f *LinkLimit) Name() string { return "link_limit" }
+
+func (f *LinkLimit) Check(ctx context.Context, content Content) (Decision, error) {
+	if n := len(links(content.FullText())); n > f.Max {
+		return Decision{Verdict: models.FilterFlag, Reason: fmt.Sprintf("too many links: %d, at most %d", n, f.Max)}, nil
+	}
+	return Accept, nil
+}
+
+// DomainBlocklist отклоняет контент со ссылками на запрещённые домены и их поддомены
+type DomainBlocklist struct {
+	domains []string
+}
+
+func NewDomainBlocklist(domains []string) *DomainBlocklist {
+	f := &DomainBlocklist{}
+	for _, domain := range domains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			f.domains = append(f.domains, domain)
+		}
+	}
+	return f
+}
+
+func (f *DomainBlocklist) Name() string { return "domain_blocklist" }
+
+func (f *DomainBlocklist) Check(ctx context.Context, content Content) (Decision, error) {
+	for _, link := range links(content.FullText()) {
+		host := linkHost(link)
+		for _, domain := range f.domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return Decision{Verdict: models.FilterReject, Reason: fmt.Sprintf("links to blocked domain %s", domain)}, nil
+			}
+		}
+	}
+	return Accept, nil
+}
+
+// Duplicate отклоняет точный повтор текста того же автора в пределах окна Window.
+// Хеши недавних текстов хранятся в памяти процесса
+type Duplicate struct {
+	Window    time.Duration
+	seen      map[[sha256.Size]byte]time.Time // хеш автора и текста → время последней проверки
+	lastPrune time.Time
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+func NewDuplicate(window time.Duration) *Duplicate {
+	return &Duplicate{Window: window, seen: make(map[[sha256.Size]byte]time.Time), now: time.Now}
+}
+
+func (f *Duplicate) Name() string { return "duplicate" }
+
+func (f *Duplicate) Check(ctx context.Context, content Content) (Decision, error) {
+	// Пробелы и регистр не делают текст новым
+	normalized := strings.ToLower(strings.Join(strings.Fields(content.FullText()), " "))
+	key := sha256.Sum256([]byte(string(content.Kind) + "\x00" + content.AuthorID + "\x00" + normalized))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if now.Sub(f.lastPrune) >= f.Window {
+		f.lastPrune = now
+		for k, at := range f.seen {
+			if now.Sub(at) >= f.Window {
+				delete(f.seen, k)
+			}
+		}
+	}
+
+	last, exists := f.seen[key]
+	f.seen[key] = now
+	if exists && now.Sub(last) < f.Window {
+		return Decision{Verdict: models.FilterReject, Reason: "duplicate of a recent " + string(content.Kind)}, nil
+	}
+	return Accept, nil
+}
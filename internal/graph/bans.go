@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// communityIDByName находит ID сообщества по имени
+func (r *Resolver) communityIDByName(ctx context.Context, name string) (string, error) {
+	community, err := r.Storage.GetCommunityByName(ctx, name)
+	if err != nil {
+		log.Printf("Failed to fetch community %s: %v", name, err)
+		return "", err
+	}
+	return community.ID, nil
+}
+
+// banDuration переводит срок бана в днях в длительность; nil означает бессрочный бан
+func banDuration(days *int) (time.Duration, error) {
+	if days == nil {
+		return 0, nil
+	}
+	if *days <= 0 {
+		return 0, storage.ErrInvalidBan
+	}
+	return time.Duration(*days) * 24 * time.Hour, nil
+}
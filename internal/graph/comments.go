@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// modifiableComment загружает комментарий и проверяет, что текущий
+// пользователь - его автор или модератор
+func (r *Resolver) modifiableComment(ctx context.Context, id string) (*models.Comment, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.Storage.GetCommentByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to fetch comment: %v", err)
+		return nil, err
+	}
+	if err := auth.CanModify(viewer, comment.AuthorID); err != nil {
+		log.Printf("User %s may not modify comment %s", viewer.ID, id)
+		return nil, err
+	}
+	return comment, nil
+}
@@ -0,0 +1,41 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// setMembership находит сообщество по имени и меняет членство текущего пользователя
+func (r *Resolver) setMembership(ctx context.Context, name string,
+	change func(ctx context.Context, userID, communityID string) (*models.Community, error),
+) (*Community, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	community, err := r.Storage.GetCommunityByName(ctx, name)
+	if err != nil {
+		log.Printf("Failed to fetch community %s: %v", name, err)
+		return nil, err
+	}
+	community, err = change(ctx, viewer.ID, community.ID)
+	if err != nil {
+		log.Printf("Failed to change membership in %s: %v", name, err)
+		return nil, err
+	}
+	return toGraphCommunity(community), nil
+}
+
+// communityByID загружает сообщество по ID
+func (r *Resolver) communityByID(ctx context.Context, id string) (*Community, error) {
+	community, err := r.Storage.GetCommunityByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to fetch community %s: %v", id, err)
+		return nil, err
+	}
+	return toGraphCommunity(community), nil
+}
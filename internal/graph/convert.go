@@ -0,0 +1,391 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/MosinFAM/graphql-posts/internal/diff"
+	"github.com/MosinFAM/graphql-posts/internal/markdown"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// Преобразование моделей хранилища в типы GraphQL
+
+// toGraphPost преобразует пост; у скрытого модерацией, как и у комментария,
+// скрываются заголовок, текст, теги и автор
+func toGraphPost(post *models.Post) *Post {
+	p := toModeratorPost(post)
+	if !post.ModStatus.Listed() {
+		p.Title = models.RemovedPlaceholder
+		p.Content = models.RemovedPlaceholder
+		p.ContentHTML = removedHTML
+		p.Tags = []string{}
+		p.AuthorID = nil
+	}
+	return p
+}
+
+// toModeratorPost преобразует пост без скрытия текста - для модераторов
+func toModeratorPost(post *models.Post) *Post {
+	return &Post{
+		ID:            post.ID,
+		AuthorID:      post.AuthorID,
+		CommunityID:   post.CommunityID,
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentHTML:   renderedHTML(post.ContentHTML, post.Content),
+		Tags:          post.Tags,
+		AllowComments: post.AllowComments,
+		CreatedAt:     post.CreatedAt,
+		UpdatedAt:     post.UpdatedAt,
+		EditedAt:      post.EditedAt,
+		Score:         post.Score(),
+		Upvotes:       post.Upvotes,
+		Downvotes:     post.Downvotes,
+
+		ModerationStatus: toGraphModerationStatus(post.ModStatus),
+		RemovalReason:    post.RemovalReason,
+	}
+}
+
+// toGraphComment преобразует комментарий; у удалённого и скрытого модерацией
+// скрываются текст и автор
+func toGraphComment(comment *models.Comment) *Comment {
+	c := toModeratorComment(comment)
+	if !c.Deleted && !comment.ModStatus.Listed() {
+		c.Content = models.RemovedPlaceholder
+		c.ContentHTML = removedHTML
+		c.AuthorID = nil
+	}
+	return c
+}
+
+// toModeratorComment преобразует комментарий для модераторов: текст скрывается
+// только у удалённого автором
+func toModeratorComment(comment *models.Comment) *Comment {
+	c := &Comment{
+		ID:          comment.ID,
+		PostID:      comment.PostID,
+		ParentID:    comment.ParentID,
+		AuthorID:    comment.AuthorID,
+		Content:     comment.Content,
+		ContentHTML: renderedHTML(comment.ContentHTML, comment.Content),
+		CreatedAt:   comment.CreatedAt,
+		EditedAt:    comment.EditedAt,
+		Depth:       comment.Depth,
+		Deleted:     comment.Deleted(),
+		DeletedAt:   comment.DeletedAt,
+		Score:       comment.Score(),
+		Upvotes:     comment.Upvotes,
+		Downvotes:   comment.Downvotes,
+
+		ModerationStatus: toGraphModerationStatus(comment.ModStatus),
+		RemovalReason:    comment.RemovalReason,
+		Locked:           comment.Locked,
+	}
+	if c.Deleted {
+		c.Content = models.DeletedPlaceholder
+		c.ContentHTML = deletedHTML
+		c.AuthorID = nil
+	}
+	return c
+}
+
+// HTML заглушек удалённого и скрытого модерацией текста
+var (
+	deletedHTML = markdown.Render(models.DeletedPlaceholder)
+	removedHTML = markdown.Render(models.RemovedPlaceholder)
+)
+
+// toGraphModerationStatus преобразует статус модерации; пустой статус означает,
+// что модератор объект не проверял
+func toGraphModerationStatus(status models.ModerationStatus) ModerationStatus {
+	if status == "" {
+		return ModerationStatusVisible
+	}
+	return ModerationStatus(strings.ToUpper(string(status)))
+}
+
+// renderedHTML возвращает сохранённый HTML текста. У записей, созданных
+// до появления HTML в хранилище, он отрисовывается при чтении
+func renderedHTML(cached, content string) string {
+	if cached == "" && content != "" {
+		return markdown.Render(content)
+	}
+	return cached
+}
+
+func toGraphCommunity(community *models.Community) *Community {
+	return &Community{
+		ID:          community.ID,
+		Name:        community.Name,
+		Description: community.Description,
+		CreatorID:   community.CreatorID,
+		CreatedAt:   community.CreatedAt,
+		MemberCount: community.MemberCount,
+	}
+}
+
+// toGraphInterest преобразует подписку: цель попадает в поле своего вида
+func toGraphInterest(interest *models.Interest) *Interest {
+	i := &Interest{CreatedAt: interest.CreatedAt}
+	switch interest.Kind {
+	case models.InterestAuthor:
+		i.Kind, i.AuthorID = InterestKindAuthor, &interest.Target
+	case models.InterestTag:
+		i.Kind, i.Tag = InterestKindTag, &interest.Target
+	}
+	return i
+}
+
+// toGraphUser преобразует пользователя, nil остаётся nil
+func toGraphUser(user *models.User) *User {
+	if user == nil {
+		return nil
+	}
+	return &User{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      Role(strings.ToUpper(string(user.Role))),
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// toModelRole переводит роль GraphQL в роль хранилища
+func toModelRole(role Role) models.Role {
+	return models.Role(strings.ToLower(string(role)))
+}
+
+// voteDirections - соответствие направлений голоса GraphQL значениям хранилища
+var voteDirections = map[VoteDirection]models.VoteDirection{
+	VoteDirectionUp:   models.VoteUp,
+	VoteDirectionDown: models.VoteDown,
+	VoteDirectionNone: models.VoteNone,
+}
+
+func toModelVote(direction VoteDirection) models.VoteDirection {
+	return voteDirections[direction]
+}
+
+func toGraphVote(direction models.VoteDirection) VoteDirection {
+	for graphDirection, modelDirection := range voteDirections {
+		if modelDirection == direction {
+			return graphDirection
+		}
+	}
+	return VoteDirectionNone
+}
+
+// toGraphVotable преобразует объект голосования в пост или комментарий
+func toGraphVotable(target *storage.Target) Votable {
+	if target.Post != nil {
+		return toGraphPost(target.Post)
+	}
+	return toGraphComment(target.Comment)
+}
+
+// toModeratorVotable преобразует пост или комментарий без скрытия текста - для модераторов
+func toModeratorVotable(target *storage.Target) Votable {
+	if target.Post != nil {
+		return toModeratorPost(target.Post)
+	}
+	return toModeratorComment(target.Comment)
+}
+
+func toGraphModQueue(items []*storage.ModQueueItem) []*ModQueueItem {
+	result := make([]*ModQueueItem, 0, len(items))
+	for _, item := range items {
+		var target ModQueueTarget
+		switch {
+		case item.Appeal != nil:
+			target = toGraphBanAppeal(item.Appeal)
+		case item.Post != nil:
+			target = toModeratorPost(item.Post)
+		default:
+			target = toModeratorComment(item.Comment)
+		}
+		result = append(result, &ModQueueItem{
+			Target:         target,
+			ReportCount:    item.ReportCount,
+			Reasons:        append([]string{}, item.Reasons...),
+			LastReportedAt: item.LastReportedAt,
+		})
+	}
+	return result
+}
+
+// toGraphBan преобразует бан; сообщество и пользователи загружаются резолверами по ID
+func toGraphBan(ban *models.Ban) *Ban {
+	return &Ban{
+		ID:          ban.ID,
+		CommunityID: ban.CommunityID,
+		UserID:      ban.UserID,
+		ModeratorID: ban.ModeratorID,
+		Reason:      ban.Reason,
+		Muted:       ban.Muted,
+		CreatedAt:   ban.CreatedAt,
+		ExpiresAt:   ban.ExpiresAt,
+	}
+}
+
+func toGraphBanAppeal(appeal *models.BanAppeal) *BanAppeal {
+	return &BanAppeal{
+		ID:          appeal.ID,
+		CommunityID: appeal.CommunityID,
+		UserID:      appeal.UserID,
+		Message:     appeal.Message,
+		CreatedAt:   appeal.CreatedAt,
+		ResolvedAt:  appeal.ResolvedAt,
+		Accepted:    appeal.Accepted,
+	}
+}
+
+// toGraphFilterDecision преобразует запись журнала фильтра; автор загружается резолвером по ID
+func toGraphFilterDecision(decision *models.FilterDecision) *FilterDecision {
+	return &FilterDecision{
+		ID:        decision.ID,
+		Kind:      ContentKind(strings.ToUpper(string(decision.Kind))),
+		TargetID:  decision.TargetID,
+		AuthorID:  decision.AuthorID,
+		Content:   decision.Content,
+		Verdict:   FilterVerdict(strings.ToUpper(string(decision.Verdict))),
+		Filter:    decision.Filter,
+		Reason:    decision.Reason,
+		CreatedAt: decision.CreatedAt,
+	}
+}
+
+// toGraphRevisions преобразует историю правок и считает разницу каждой версии с предыдущей.
+// withTitle - отдавать ли заголовок (у комментариев его нет)
+func toGraphRevisions(revisions []*models.Revision, withTitle bool) []*Revision {
+	result := make([]*Revision, 0, len(revisions))
+	previous := ""
+	for _, revision := range revisions {
+		r := &Revision{
+			Version:   revision.Version,
+			EditorID:  revision.EditorID,
+			CreatedAt: revision.CreatedAt,
+			Content:   revision.Content,
+			Diff:      diff.Unified(previous, revision.Content),
+		}
+		if withTitle {
+			title := revision.Title
+			r.Title = &title
+		}
+		result = append(result, r)
+		previous = revision.Content
+	}
+	return result
+}
+
+func toPostConnection(page *storage.PostPage) *PostConnection {
+	conn := &PostConnection{
+		Edges:    make([]*PostEdge, 0, len(page.Posts)),
+		PageInfo: &PageInfo{HasNextPage: page.HasNextPage},
+	}
+	for i := range page.Posts {
+		cursor := storage.PostCursor(&page.Posts[i])
+		conn.Edges = append(conn.Edges, &PostEdge{Cursor: cursor, Node: toGraphPost(&page.Posts[i])})
+		conn.PageInfo.EndCursor = &cursor
+	}
+	return conn
+}
+
+func toSearchConnection(page *storage.SearchPage) *SearchConnection {
+	conn := &SearchConnection{
+		Edges:    make([]*SearchEdge, 0, len(page.Results)),
+		PageInfo: &PageInfo{HasNextPage: page.HasNextPage},
+	}
+	for i := range page.Results {
+		result := &page.Results[i]
+		edge := &SearchEdge{Cursor: storage.SearchCursor(result), Rank: result.Rank, Snippet: result.Snippet}
+		if result.Post != nil {
+			edge.Node = toGraphPost(result.Post)
+		} else {
+			edge.Node = toGraphComment(result.Comment)
+		}
+		conn.Edges = append(conn.Edges, edge)
+		conn.PageInfo.EndCursor = &edge.Cursor
+	}
+	return conn
+}
+
+// toGraphNotification преобразует уведомление; пост, комментарий и автор
+// загружаются резолверами по ID
+func toGraphNotification(notification *models.Notification) *Notification {
+	return &Notification{
+		ID:        notification.ID,
+		Kind:      NotificationKind(strings.ToUpper(string(notification.Kind))),
+		ActorID:   notification.ActorID,
+		PostID:    notification.PostID,
+		CommentID: notification.CommentID,
+		CreatedAt: notification.CreatedAt,
+		Read:      notification.Read(),
+		ReadAt:    notification.ReadAt,
+	}
+}
+
+func toNotificationConnection(page *storage.NotificationPage) *NotificationConnection {
+	conn := &NotificationConnection{
+		Edges:    make([]*NotificationEdge, 0, len(page.Notifications)),
+		PageInfo: &PageInfo{HasNextPage: page.HasNextPage},
+	}
+	for _, notification := range page.Notifications {
+		cursor := storage.NotificationCursor(notification)
+		conn.Edges = append(conn.Edges, &NotificationEdge{Cursor: cursor, Node: toGraphNotification(notification)})
+		conn.PageInfo.EndCursor = &cursor
+	}
+	return conn
+}
+
+func toCommentConnection(page *storage.CommentPage) *CommentConnection {
+	conn := &CommentConnection{
+		Edges:    make([]*CommentEdge, 0, len(page.Comments)),
+		PageInfo: &PageInfo{HasNextPage: page.HasNextPage},
+	}
+	for _, comment := range page.Comments {
+		cursor := storage.CommentCursor(comment)
+		conn.Edges = append(conn.Edges, &CommentEdge{Cursor: cursor, Node: toGraphComment(comment)})
+		conn.PageInfo.EndCursor = &cursor
+	}
+	return conn
+}
+
+// postListOptions собирает параметры пагинации, сортировки и периода списка постов
+// из аргументов запроса
+func postListOptions(first *int, after *string, sort *PostSort, timeRange *TimeRange) storage.PostListOptions {
+	opts := storage.PostListOptions{}
+	if first != nil {
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	if sort != nil {
+		opts.Sort = storage.PostSort(*sort)
+	}
+	if timeRange != nil {
+		opts.TimeRange = storage.TimeRange(*timeRange)
+	}
+	return opts
+}
+
+// commentListOptions собирает параметры курсорной пагинации из аргументов запроса
+func commentListOptions(first *int, after *string, sort *CommentSort) storage.CommentListOptions {
+	opts := storage.CommentListOptions{Sort: toStorageCommentSort(sort)}
+	if first != nil {
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	return opts
+}
+
+// toStorageCommentSort преобразует сортировку комментариев, nil - сортировка по умолчанию
+func toStorageCommentSort(sort *CommentSort) storage.CommentSort {
+	if sort == nil {
+		return ""
+	}
+	return storage.CommentSort(*sort)
+}
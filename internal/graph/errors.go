@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/filter"
+	"github.com/MosinFAM/graphql-posts/internal/ratelimit"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Стабильные коды ошибок, которые клиент получает в extensions.code
+const (
+	CodeNotFound         = "NOT_FOUND"
+	CodeCommentsDisabled = "COMMENTS_DISABLED"
+	CodeCommentDeleted   = "COMMENT_DELETED"
+	CodeCommentLocked    = "COMMENT_LOCKED"
+	CodeBanned           = "BANNED"
+	CodeRateLimited      = "RATE_LIMITED"
+	CodeContentRejected  = "CONTENT_REJECTED"
+	CodeContentTooLong   = "CONTENT_TOO_LONG"
+	CodeInvalidID        = "INVALID_ID"
+	CodeInvalidParent    = "INVALID_PARENT"
+	CodeConflict         = "CONFLICT"
+	CodeInvalidCursor    = "INVALID_CURSOR"
+	CodeUnauthenticated  = "UNAUTHENTICATED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeBadCredentials   = "INVALID_CREDENTIALS"
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeInternal         = "INTERNAL"
+)
+
+// errorCodes - соответствие доменных ошибок кодам GraphQL
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{storage.ErrNotFound, CodeNotFound},
+	{storage.ErrCommentsDisabled, CodeCommentsDisabled},
+	{storage.ErrCommentDeleted, CodeCommentDeleted},
+	{storage.ErrCommentLocked, CodeCommentLocked},
+	{storage.ErrBanned, CodeBanned},
+	{storage.ErrAppealsMuted, CodeForbidden},
+	{storage.ErrContentTooLong, CodeContentTooLong},
+	{storage.ErrInvalidID, CodeInvalidID},
+	{storage.ErrInvalidParent, CodeInvalidParent},
+	{storage.ErrConflict, CodeConflict},
+	{storage.ErrInvalidCursor, CodeInvalidCursor},
+	{storage.ErrInvalidVote, CodeBadUserInput},
+	{storage.ErrInvalidCommunity, CodeBadUserInput},
+	{storage.ErrInvalidTag, CodeBadUserInput},
+	{storage.ErrTooManyTags, CodeBadUserInput},
+	{storage.ErrInvalidInterest, CodeBadUserInput},
+	{storage.ErrInvalidSearch, CodeBadUserInput},
+	{storage.ErrEmptyReason, CodeBadUserInput},
+	{storage.ErrInvalidModeration, CodeBadUserInput},
+	{storage.ErrInvalidRole, CodeBadUserInput},
+	{storage.ErrInvalidBan, CodeBadUserInput},
+	{ratelimit.ErrRateLimited, CodeRateLimited},
+	{filter.ErrRejected, CodeContentRejected},
+	{auth.ErrUnauthenticated, CodeUnauthenticated},
+	{auth.ErrForbidden, CodeForbidden},
+	{auth.ErrInvalidCredentials, CodeBadCredentials},
+	{auth.ErrInvalidInput, CodeBadUserInput},
+	{storage.ErrInternal, CodeInternal},
+}
+
+// ErrorPresenter добавляет к ошибкам доменного уровня код в extensions.code,
+// чтобы клиенту не приходилось сравнивать текст сообщений. К ошибке бана
+// добавляются причина и время окончания бана (null у бессрочного), к ошибке
+// лимита - retryAfter: через сколько секунд можно повторить запрос, к ошибке
+// фильтра контента - reason.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			if gqlErr.Extensions == nil {
+				gqlErr.Extensions = make(map[string]interface{})
+			}
+			gqlErr.Extensions["code"] = ec.code
+			break
+		}
+	}
+
+	var banned *storage.BannedError
+	if errors.As(err, &banned) {
+		gqlErr.Extensions["reason"] = banned.Reason
+		gqlErr.Extensions["expiresAt"] = banned.ExpiresAt
+	}
+	var limited *ratelimit.LimitedError
+	if errors.As(err, &limited) {
+		gqlErr.Extensions["retryAfter"] = limited.RetryAfterSeconds()
+	}
+	var rejected *filter.RejectedError
+	if errors.As(err, &rejected) {
+		gqlErr.Extensions["reason"] = rejected.Reason
+	}
+
+	return gqlErr
+}
@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/filter"
+	"github.com/MosinFAM/graphql-posts/internal/ratelimit"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPresenter_DomainErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code string
+	}{
+		{storage.ErrPostNotFound, CodeNotFound},
+		{storage.ErrCommentNotFound, CodeNotFound},
+		{storage.ErrCommentsDisabled, CodeCommentsDisabled},
+		{storage.ErrContentTooLong, CodeContentTooLong},
+		{fmt.Errorf("%w: %q", storage.ErrInvalidID, "abc"), CodeInvalidID},
+		{storage.ErrConflict, CodeConflict},
+		{storage.ErrUsernameTaken, CodeConflict},
+		{auth.ErrUnauthenticated, CodeUnauthenticated},
+		{auth.ErrInvalidCredentials, CodeBadCredentials},
+		{auth.ErrInvalidPassword, CodeBadUserInput},
+		{fmt.Errorf("%w: no words to search", storage.ErrInvalidSearch), CodeBadUserInput},
+	}
+
+	for _, c := range cases {
+		gqlErr := ErrorPresenter(context.Background(), c.err)
+		assert.Equal(t, c.code, gqlErr.Extensions["code"], c.err.Error())
+		assert.Equal(t, c.err.Error(), gqlErr.Message)
+	}
+}
+
+func TestErrorPresenter_UnknownError(t *testing.T) {
+	gqlErr := ErrorPresenter(context.Background(), errors.New("boom"))
+
+	assert.Equal(t, "boom", gqlErr.Message)
+	assert.NotContains(t, gqlErr.Extensions, "code")
+}
+
+func TestErrorPresenter_Banned(t *testing.T) {
+	expiresAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	gqlErr := ErrorPresenter(context.Background(), &storage.BannedError{CommunityID: "c1", Reason: "spam", ExpiresAt: &expiresAt})
+
+	assert.Equal(t, CodeBanned, gqlErr.Extensions["code"])
+	assert.Equal(t, "spam", gqlErr.Extensions["reason"])
+	assert.Equal(t, &expiresAt, gqlErr.Extensions["expiresAt"])
+}
+
+func TestErrorPresenter_RateLimited(t *testing.T) {
+	gqlErr := ErrorPresenter(context.Background(), &ratelimit.LimitedError{Operation: "addComment", RetryAfter: 1500 * time.Millisecond})
+
+	assert.Equal(t, CodeRateLimited, gqlErr.Extensions["code"])
+	assert.Equal(t, 2, gqlErr.Extensions["retryAfter"])
+}
+
+func TestErrorPresenter_ContentRejected(t *testing.T) {
+	gqlErr := ErrorPresenter(context.Background(), &filter.RejectedError{Filter: "banned_words", Reason: "contains banned word \"casino\""})
+
+	assert.Equal(t, CodeContentRejected, gqlErr.Extensions["code"])
+	assert.Equal(t, "contains banned word \"casino\"", gqlErr.Extensions["reason"])
+}
@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+var (
+	// errNoBroker - сервер запущен без брокера событий
+	errNoBroker = errors.New("subscriptions are not available")
+	// errHidden - объект события скрыт модерацией и не рассылается
+	errHidden = errors.New("hidden by moderation")
+)
+
+// subscribe подписывается на тему брокера событий до отмены ctx
+func (r *Resolver) subscribe(ctx context.Context, topic string) (<-chan events.Event, error) {
+	if r.Events == nil {
+		return nil, errNoBroker
+	}
+	return r.Events.Subscribe(ctx, topic)
+}
+
+// eventComment возвращает комментарий из события или читает его из хранилища,
+// если брокер опустил объект
+func (r *Resolver) eventComment(ctx context.Context, event events.Event) (*models.Comment, error) {
+	var comment models.Comment
+	ok, err := event.Decode(&comment)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.Storage.GetCommentByID(ctx, event.ID)
+	}
+	return &comment, nil
+}
+
+// eventPost возвращает пост из события или читает его из хранилища
+func (r *Resolver) eventPost(ctx context.Context, event events.Event) (*models.Post, error) {
+	var post models.Post
+	ok, err := event.Decode(&post)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.Storage.GetPostByID(ctx, event.ID)
+	}
+	return &post, nil
+}
+
+// eventGraphPost возвращает пост из события в виде для клиента
+func (r *Resolver) eventGraphPost(ctx context.Context, event events.Event) (*Post, error) {
+	post, err := r.eventPost(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphPost(post), nil
+}
+
+// eventTarget возвращает пост или комментарий из события или читает его из хранилища
+func (r *Resolver) eventTarget(ctx context.Context, event events.Event) (*storage.Target, error) {
+	var target storage.Target
+	ok, err := event.Decode(&target)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &target, nil
+	}
+	post, err := r.Storage.GetPostByID(ctx, event.ID)
+	if err == nil {
+		return &storage.Target{Post: post}, nil
+	}
+	if !errors.Is(err, storage.ErrPostNotFound) {
+		return nil, err
+	}
+	comment, err := r.Storage.GetCommentByID(ctx, event.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Target{Comment: comment}, nil
+}
+
+// forward подписывается на тему и отправляет клиенту события типа eventType,
+// преобразованные convert. События, которые не удалось преобразовать или которые
+// convert скрыл ошибкой errHidden, пропускаются
+func forward[T any](ctx context.Context, r *Resolver, topic, eventType string,
+	convert func(ctx context.Context, event events.Event) (T, error)) (<-chan T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	received, err := r.subscribe(ctx, topic)
+	if err != nil {
+		cancel()
+		log.Printf("Failed to subscribe to %s: %v", topic, err)
+		return nil, err
+	}
+
+	ch := make(chan T, 1)
+	go func() {
+		defer cancel()
+		defer close(ch)
+		for event := range received {
+			if event.Type != eventType {
+				continue
+			}
+			value, err := convert(ctx, event)
+			if errors.Is(err, errHidden) {
+				continue
+			}
+			if err != nil {
+				log.Printf("Failed to deliver %s event %s: %v", event.Type, event.ID, err)
+				continue
+			}
+			select {
+			case ch <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
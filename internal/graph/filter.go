@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/filter"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// checkContent прогоняет новый пост или комментарий через фильтр контента.
+// Отклонённый контент записывается в журнал и возвращает *filter.RejectedError
+func (r *Resolver) checkContent(ctx context.Context, content filter.Content) (filter.Decision, error) {
+	if r.Filter == nil {
+		return filter.Accept, nil
+	}
+	decision := r.Filter.Check(ctx, content)
+	if decision.Verdict == models.FilterReject {
+		r.Filter.Record(ctx, content, decision, nil)
+		return decision, &filter.RejectedError{Filter: decision.Filter, Reason: decision.Reason}
+	}
+	return decision, nil
+}
+
+// initialStatus - статус нового поста или комментария по решению фильтра: отмеченный
+// фильтром объект сохраняется скрытым до проверки модератором
+func initialStatus(decision filter.Decision) models.ModerationStatus {
+	if decision.Verdict == models.FilterFlag {
+		return models.ModerationFlagged
+	}
+	return models.ModerationVisible
+}
+
+// recordDecision записывает решение фильтра об опубликованном объекте
+func (r *Resolver) recordDecision(ctx context.Context, content filter.Content, decision filter.Decision, targetID string) {
+	if r.Filter == nil {
+		return
+	}
+	r.Filter.Record(ctx, content, decision, &targetID)
+}
+
+// trainingSample - каким обучающим примером служит решение модератора: spam - удаление
+// с причиной «спам», иначе одобрение; ok = false - решение классификатор не обучает
+func trainingSample(status models.ModerationStatus, reason *string) (spam bool, ok bool) {
+	switch {
+	case status == models.ModerationRemoved && reason != nil && filter.IsSpamReason(*reason):
+		return true, true
+	case status == models.ModerationApproved:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// trainFilter обучает классификатор спама на решении модератора: удаление
+// с причиной «спам» - пример спама, одобрение - пример обычного контента.
+// Повторное решение не обучает классификатор, а пример отменённого решения вычитается
+func (r *Resolver) trainFilter(ctx context.Context, result *storage.ModerationResult) {
+	if r.Filter == nil {
+		return
+	}
+
+	content := filter.Content{Kind: models.ContentComment}
+	var status models.ModerationStatus
+	var reason *string
+	if result.Post != nil {
+		content = filter.Content{Kind: models.ContentPost, Title: result.Post.Title, Text: result.Post.Content}
+		status, reason = result.Post.ModStatus, result.Post.RemovalReason
+	} else {
+		content.Text = result.Comment.Content
+		status, reason = result.Comment.ModStatus, result.Comment.RemovalReason
+	}
+
+	prevSpam, prevOK := trainingSample(result.PrevStatus, result.PrevReason)
+	spam, ok := trainingSample(status, reason)
+	if prevOK == ok && prevSpam == spam {
+		return
+	}
+	if prevOK {
+		if err := r.Filter.Untrain(ctx, content, prevSpam); err != nil {
+			log.Printf("Failed to untrain spam classifier: %v", err)
+		}
+	}
+	if ok {
+		if err := r.Filter.Train(ctx, content, spam); err != nil {
+			log.Printf("Failed to train spam classifier: %v", err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// follow подписывает текущего пользователя на автора или тег
+func (r *Resolver) follow(ctx context.Context, kind models.InterestKind, target string) (*Interest, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	interest, err := r.Storage.FollowInterest(ctx, viewer.ID, kind, target)
+	if err != nil {
+		log.Printf("Failed to follow %s %s: %v", kind, target, err)
+		return nil, err
+	}
+	return toGraphInterest(interest), nil
+}
+
+// unfollow отменяет подписку текущего пользователя на автора или тег
+func (r *Resolver) unfollow(ctx context.Context, kind models.InterestKind, target string) (bool, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.Storage.UnfollowInterest(ctx, viewer.ID, kind, target); err != nil {
+		log.Printf("Failed to unfollow %s %s: %v", kind, target, err)
+		return false, err
+	}
+	return true, nil
+}
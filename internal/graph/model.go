@@ -2,26 +2,824 @@
 
 package graph
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Объект очереди модерации: пост или комментарий с жалобами либо апелляция на бан.
+type ModQueueTarget interface {
+	IsModQueueTarget()
+}
+
+type SearchResult interface {
+	IsSearchResult()
+}
+
+// Пост или комментарий, за который можно голосовать.
+type Votable interface {
+	IsVotable()
+	GetID() string
+	// Рейтинг: upvotes - downvotes.
+	GetScore() int
+	GetUpvotes() int
+	GetDownvotes() int
+	// Голос текущего пользователя, null - для анонимного запроса.
+	GetViewerVote() *VoteDirection
+}
+
+// Результат регистрации или входа. token передаётся в заголовке
+// "Authorization: Bearer <token>" или в поле Authorization при connection_init.
+type AuthPayload struct {
+	Token string `json:"token"`
+	User  *User  `json:"user"`
+}
+
+// Бан пользователя в сообществе: забаненный пользователь не может публиковать
+// в сообществе посты и комментарии.
+type Ban struct {
+	ID        string     `json:"id"`
+	Community *Community `json:"community"`
+	// Забаненный пользователь, null - если аккаунт удалён.
+	User *User `json:"user,omitempty"`
+	// Выдавший бан модератор, null - если аккаунт удалён.
+	Moderator *User  `json:"moderator,omitempty"`
+	Reason    string `json:"reason"`
+	// Бан нельзя обжаловать.
+	Muted     bool      `json:"muted"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Время окончания бана, null - у бессрочного бана.
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	CommunityID string     `json:"-"`
+	ModeratorID *string    `json:"-"`
+	UserID      string     `json:"-"`
+}
+
+// Апелляция пользователя на бан в сообществе.
+type BanAppeal struct {
+	ID        string     `json:"id"`
+	Community *Community `json:"community"`
+	User      *User      `json:"user,omitempty"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"createdAt"`
+	// Время решения модератора, null - у открытой апелляции.
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// Бан снят по апелляции.
+	Accepted    bool   `json:"accepted"`
+	CommunityID string `json:"-"`
+	UserID      string `json:"-"`
+}
+
+func (BanAppeal) IsModQueueTarget() {}
+
 type Comment struct {
-	ID        string  `json:"id"`
-	PostID    string  `json:"postId"`
-	ParentID  *string `json:"parentId,omitempty"`
-	Content   string  `json:"content"`
-	CreatedAt string  `json:"createdAt"`
+	ID       string  `json:"id"`
+	PostID   string  `json:"postId"`
+	ParentID *string `json:"parentId,omitempty"`
+	// Автор комментария, null - у анонимных и удалённых комментариев.
+	Author *User `json:"author,omitempty"`
+	// Текст комментария в Markdown. У удалённого комментария - "[deleted]".
+	Content string `json:"content"`
+	// Текст комментария, отрисованный в HTML.
+	ContentHTML string    `json:"contentHtml"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Время последней правки, null - если комментарий не правился.
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	Depth    int        `json:"depth"`
+	// Комментарий удалён. Удалённый комментарий с ответами остаётся в дереве заглушкой.
+	Deleted   bool       `json:"deleted"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// История правок в порядке версий. Доступна автору комментария и модераторам.
+	Revisions        []*Revision      `json:"revisions"`
+	Score            int              `json:"score"`
+	Upvotes          int              `json:"upvotes"`
+	Downvotes        int              `json:"downvotes"`
+	ViewerVote       *VoteDirection   `json:"viewerVote,omitempty"`
+	ModerationStatus ModerationStatus `json:"moderationStatus"`
+	// Причина удаления модератором.
+	RemovalReason *string `json:"removalReason,omitempty"`
+	// На комментарий и ответы в его поддереве нельзя отвечать.
+	Locked   bool               `json:"locked"`
+	Replies  *CommentConnection `json:"replies"`
+	AuthorID *string            `json:"-"`
+}
+
+func (Comment) IsModQueueTarget() {}
+
+func (Comment) IsVotable()         {}
+func (this Comment) GetID() string { return this.ID }
+
+// Рейтинг: upvotes - downvotes.
+func (this Comment) GetScore() int     { return this.Score }
+func (this Comment) GetUpvotes() int   { return this.Upvotes }
+func (this Comment) GetDownvotes() int { return this.Downvotes }
+
+// Голос текущего пользователя, null - для анонимного запроса.
+func (this Comment) GetViewerVote() *VoteDirection { return this.ViewerVote }
+
+func (Comment) IsSearchResult() {}
+
+type CommentConnection struct {
+	Edges    []*CommentEdge `json:"edges"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
+
+// Узел дерева комментариев. Дерево отдаётся плоским списком в порядке обхода в глубину.
+type CommentTreeNode struct {
+	Comment        *Comment `json:"comment"`
+	Depth          int      `json:"depth"`
+	HasMoreReplies bool     `json:"hasMoreReplies"`
+}
+
+// Сообщество - раздел сайта со своими постами (аналог сабреддита).
+type Community struct {
+	ID string `json:"id"`
+	// Уникальное без учёта регистра имя: 3-21 латинская буква, цифра или подчёркивание.
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Создатель сообщества, null - если аккаунт удалён.
+	Creator     *User     `json:"creator,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	MemberCount int       `json:"memberCount"`
+	// Состоит ли текущий пользователь в сообществе, null - для анонимного запроса.
+	ViewerIsMember *bool           `json:"viewerIsMember,omitempty"`
+	Posts          *PostConnection `json:"posts"`
+	CreatorID      *string         `json:"-"`
+}
+
+// Запись журнала фильтра контента: решение о новом посте или комментарии,
+// который фильтр отправил на проверку или отклонил.
+type FilterDecision struct {
+	ID   string      `json:"id"`
+	Kind ContentKind `json:"kind"`
+	// ID опубликованного поста или комментария, null - у отклонённого контента.
+	TargetID *string `json:"targetId,omitempty"`
+	// Автор, null - если аккаунт удалён.
+	Author *User `json:"author,omitempty"`
+	// Проверенный текст, у поста - заголовок и текст.
+	Content string        `json:"content"`
+	Verdict FilterVerdict `json:"verdict"`
+	// Фильтр, вынесший решение.
+	Filter    string    `json:"filter"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+	AuthorID  *string   `json:"-"`
+}
+
+// Подписка пользователя на автора или тег. Посты подписок и сообществ,
+// в которых состоит пользователь, попадают в его домашнюю ленту.
+type Interest struct {
+	Kind InterestKind `json:"kind"`
+	// Автор для подписки AUTHOR, null - у подписки на тег или удалённого автора.
+	Author *User `json:"author,omitempty"`
+	// Тег для подписки TAG, null - у подписки на автора.
+	Tag       *string   `json:"tag,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	AuthorID  *string   `json:"-"`
+}
+
+// Пост или комментарий, ожидающий решения модератора, или открытая апелляция на бан.
+type ModQueueItem struct {
+	Target ModQueueTarget `json:"target"`
+	// Число открытых жалоб, у апелляции - 0.
+	ReportCount int `json:"reportCount"`
+	// Различные причины открытых жалоб, у апелляции - причина бана.
+	Reasons []string `json:"reasons"`
+	// Время последней жалобы, у скрытого без жалоб объекта - время его создания,
+	// у апелляции - время подачи.
+	LastReportedAt time.Time `json:"lastReportedAt"`
 }
 
 type Mutation struct {
 }
 
+// Уведомление о комментарии, который отвечает пользователю или упоминает его.
+type Notification struct {
+	ID   string           `json:"id"`
+	Kind NotificationKind `json:"kind"`
+	// Автор комментария, null - если аккаунт удалён.
+	Actor     *User      `json:"actor,omitempty"`
+	Post      *Post      `json:"post"`
+	Comment   *Comment   `json:"comment"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+	ActorID   *string    `json:"-"`
+	CommentID string     `json:"-"`
+	PostID    string     `json:"-"`
+}
+
+type NotificationConnection struct {
+	Edges    []*NotificationEdge `json:"edges"`
+	PageInfo *PageInfo           `json:"pageInfo"`
+}
+
+type NotificationEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *Notification `json:"node"`
+}
+
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor,omitempty"`
+}
+
 type Post struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	Content       string `json:"content"`
-	AllowComments bool   `json:"allowComments"`
+	ID string `json:"id"`
+	// Автор поста, null - у анонимных постов, созданных до появления аккаунтов.
+	Author *User `json:"author,omitempty"`
+	// Сообщество поста, null - у постов вне сообществ.
+	Community *Community `json:"community,omitempty"`
+	Title     string     `json:"title"`
+	// Исходный текст поста в Markdown.
+	Content string `json:"content"`
+	// Текст поста, отрисованный в HTML. Поддерживаемое подмножество Markdown описано
+	// в README, весь остальной текст экранируется.
+	ContentHTML string `json:"contentHtml"`
+	// Теги поста в нижнем регистре, не больше пяти.
+	Tags          []string  `json:"tags"`
+	AllowComments bool      `json:"allowComments"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	// Время последней правки текста автором, null - если пост не правился.
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	// История правок в порядке версий. Доступна автору поста и модераторам.
+	Revisions        []*Revision      `json:"revisions"`
+	Score            int              `json:"score"`
+	Upvotes          int              `json:"upvotes"`
+	Downvotes        int              `json:"downvotes"`
+	ViewerVote       *VoteDirection   `json:"viewerVote,omitempty"`
+	ModerationStatus ModerationStatus `json:"moderationStatus"`
+	// Причина удаления модератором.
+	RemovalReason *string `json:"removalReason,omitempty"`
+	AuthorID      *string `json:"-"`
+	CommunityID   *string `json:"-"`
+}
+
+func (Post) IsModQueueTarget() {}
+
+func (Post) IsVotable()         {}
+func (this Post) GetID() string { return this.ID }
+
+// Рейтинг: upvotes - downvotes.
+func (this Post) GetScore() int     { return this.Score }
+func (this Post) GetUpvotes() int   { return this.Upvotes }
+func (this Post) GetDownvotes() int { return this.Downvotes }
+
+// Голос текущего пользователя, null - для анонимного запроса.
+func (this Post) GetViewerVote() *VoteDirection { return this.ViewerVote }
+
+func (Post) IsSearchResult() {}
+
+type PostConnection struct {
+	Edges    []*PostEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
 }
 
 type Query struct {
 }
 
+// Версия текста поста или комментария.
+type Revision struct {
+	Version int `json:"version"`
+	// Кто внёс правку: автор или модератор.
+	Editor    *User     `json:"editor,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Заголовок версии, null - у комментариев.
+	Title   *string `json:"title,omitempty"`
+	Content string  `json:"content"`
+	// Построчная разница с предыдущей версией: "+" - добавленная строка,
+	// "-" - удалённая, " " - неизменная. У первой версии все строки добавлены.
+	Diff     string  `json:"diff"`
+	EditorID *string `json:"-"`
+}
+
+type SearchConnection struct {
+	Edges    []*SearchEdge `json:"edges"`
+	PageInfo *PageInfo     `json:"pageInfo"`
+}
+
+type SearchEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   SearchResult `json:"node"`
+	// Релевантность результата, выдача упорядочена по её убыванию.
+	Rank float64 `json:"rank"`
+	// Фрагмент текста, экранированный для HTML, слова запроса обёрнуты в <mark>.
+	Snippet string `json:"snippet"`
+}
+
 type Subscription struct {
 }
+
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Порядок комментариев. В дереве применяется к каждой группе ответов на один комментарий.
+type CommentSort string
+
+const (
+	// По нижней границе интервала Уилсона для доли положительных голосов.
+	CommentSortBest CommentSort = "BEST"
+	// По рейтингу upvotes - downvotes.
+	CommentSortTop CommentSort = "TOP"
+	CommentSortNew CommentSort = "NEW"
+	CommentSortOld CommentSort = "OLD"
+	// Много голосов, поделённых примерно поровну.
+	CommentSortControversial CommentSort = "CONTROVERSIAL"
+)
+
+var AllCommentSort = []CommentSort{
+	CommentSortBest,
+	CommentSortTop,
+	CommentSortNew,
+	CommentSortOld,
+	CommentSortControversial,
+}
+
+func (e CommentSort) IsValid() bool {
+	switch e {
+	case CommentSortBest, CommentSortTop, CommentSortNew, CommentSortOld, CommentSortControversial:
+		return true
+	}
+	return false
+}
+
+func (e CommentSort) String() string {
+	return string(e)
+}
+
+func (e *CommentSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CommentSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CommentSort", str)
+	}
+	return nil
+}
+
+func (e CommentSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ContentKind string
+
+const (
+	ContentKindPost    ContentKind = "POST"
+	ContentKindComment ContentKind = "COMMENT"
+)
+
+var AllContentKind = []ContentKind{
+	ContentKindPost,
+	ContentKindComment,
+}
+
+func (e ContentKind) IsValid() bool {
+	switch e {
+	case ContentKindPost, ContentKindComment:
+		return true
+	}
+	return false
+}
+
+func (e ContentKind) String() string {
+	return string(e)
+}
+
+func (e *ContentKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ContentKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ContentKind", str)
+	}
+	return nil
+}
+
+func (e ContentKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type FilterVerdict string
+
+const (
+	FilterVerdictAccept FilterVerdict = "ACCEPT"
+	// Контент опубликован скрытым до проверки модератором.
+	FilterVerdictFlag   FilterVerdict = "FLAG"
+	FilterVerdictReject FilterVerdict = "REJECT"
+)
+
+var AllFilterVerdict = []FilterVerdict{
+	FilterVerdictAccept,
+	FilterVerdictFlag,
+	FilterVerdictReject,
+}
+
+func (e FilterVerdict) IsValid() bool {
+	switch e {
+	case FilterVerdictAccept, FilterVerdictFlag, FilterVerdictReject:
+		return true
+	}
+	return false
+}
+
+func (e FilterVerdict) String() string {
+	return string(e)
+}
+
+func (e *FilterVerdict) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FilterVerdict(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FilterVerdict", str)
+	}
+	return nil
+}
+
+func (e FilterVerdict) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type InterestKind string
+
+const (
+	InterestKindAuthor InterestKind = "AUTHOR"
+	InterestKindTag    InterestKind = "TAG"
+)
+
+var AllInterestKind = []InterestKind{
+	InterestKindAuthor,
+	InterestKindTag,
+}
+
+func (e InterestKind) IsValid() bool {
+	switch e {
+	case InterestKindAuthor, InterestKindTag:
+		return true
+	}
+	return false
+}
+
+func (e InterestKind) String() string {
+	return string(e)
+}
+
+func (e *InterestKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = InterestKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid InterestKind", str)
+	}
+	return nil
+}
+
+func (e InterestKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Состояние модерации поста или комментария. Текст (у поста - и заголовок) скрытого
+// (FLAGGED) и удалённого модератором (REMOVED) объекта заменяется на "[removed]",
+// автор и теги не показываются. Такие объекты не попадают в списки постов и комментариев
+// и в поиск.
+type ModerationStatus string
+
+const (
+	// Модератор объект не проверял.
+	ModerationStatusVisible ModerationStatus = "VISIBLE"
+	// Скрыт до проверки модератором после нескольких жалоб.
+	ModerationStatusFlagged  ModerationStatus = "FLAGGED"
+	ModerationStatusApproved ModerationStatus = "APPROVED"
+	ModerationStatusRemoved  ModerationStatus = "REMOVED"
+)
+
+var AllModerationStatus = []ModerationStatus{
+	ModerationStatusVisible,
+	ModerationStatusFlagged,
+	ModerationStatusApproved,
+	ModerationStatusRemoved,
+}
+
+func (e ModerationStatus) IsValid() bool {
+	switch e {
+	case ModerationStatusVisible, ModerationStatusFlagged, ModerationStatusApproved, ModerationStatusRemoved:
+		return true
+	}
+	return false
+}
+
+func (e ModerationStatus) String() string {
+	return string(e)
+}
+
+func (e *ModerationStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ModerationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ModerationStatus", str)
+	}
+	return nil
+}
+
+func (e ModerationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type NotificationKind string
+
+const (
+	// Ответ на пост получателя.
+	NotificationKindPostReply NotificationKind = "POST_REPLY"
+	// Ответ на комментарий получателя.
+	NotificationKindCommentReply NotificationKind = "COMMENT_REPLY"
+	// Упоминание @username получателя в комментарии.
+	NotificationKindMention NotificationKind = "MENTION"
+)
+
+var AllNotificationKind = []NotificationKind{
+	NotificationKindPostReply,
+	NotificationKindCommentReply,
+	NotificationKindMention,
+}
+
+func (e NotificationKind) IsValid() bool {
+	switch e {
+	case NotificationKindPostReply, NotificationKindCommentReply, NotificationKindMention:
+		return true
+	}
+	return false
+}
+
+func (e NotificationKind) String() string {
+	return string(e)
+}
+
+func (e *NotificationKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationKind", str)
+	}
+	return nil
+}
+
+func (e NotificationKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type PostSort string
+
+const (
+	PostSortNew           PostSort = "NEW"
+	PostSortTop           PostSort = "TOP"
+	PostSortHot           PostSort = "HOT"
+	PostSortControversial PostSort = "CONTROVERSIAL"
+)
+
+var AllPostSort = []PostSort{
+	PostSortNew,
+	PostSortTop,
+	PostSortHot,
+	PostSortControversial,
+}
+
+func (e PostSort) IsValid() bool {
+	switch e {
+	case PostSortNew, PostSortTop, PostSortHot, PostSortControversial:
+		return true
+	}
+	return false
+}
+
+func (e PostSort) String() string {
+	return string(e)
+}
+
+func (e *PostSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PostSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PostSort", str)
+	}
+	return nil
+}
+
+func (e PostSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleUser      Role = "USER"
+	RoleModerator Role = "MODERATOR"
+	RoleAdmin     Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleModerator,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SearchType string
+
+const (
+	SearchTypePost    SearchType = "POST"
+	SearchTypeComment SearchType = "COMMENT"
+)
+
+var AllSearchType = []SearchType{
+	SearchTypePost,
+	SearchTypeComment,
+}
+
+func (e SearchType) IsValid() bool {
+	switch e {
+	case SearchTypePost, SearchTypeComment:
+		return true
+	}
+	return false
+}
+
+func (e SearchType) String() string {
+	return string(e)
+}
+
+func (e *SearchType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SearchType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SearchType", str)
+	}
+	return nil
+}
+
+func (e SearchType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TimeRange string
+
+const (
+	TimeRangeDay   TimeRange = "DAY"
+	TimeRangeWeek  TimeRange = "WEEK"
+	TimeRangeMonth TimeRange = "MONTH"
+	TimeRangeYear  TimeRange = "YEAR"
+	TimeRangeAll   TimeRange = "ALL"
+)
+
+var AllTimeRange = []TimeRange{
+	TimeRangeDay,
+	TimeRangeWeek,
+	TimeRangeMonth,
+	TimeRangeYear,
+	TimeRangeAll,
+}
+
+func (e TimeRange) IsValid() bool {
+	switch e {
+	case TimeRangeDay, TimeRangeWeek, TimeRangeMonth, TimeRangeYear, TimeRangeAll:
+		return true
+	}
+	return false
+}
+
+func (e TimeRange) String() string {
+	return string(e)
+}
+
+func (e *TimeRange) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TimeRange(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TimeRange", str)
+	}
+	return nil
+}
+
+func (e TimeRange) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type VoteDirection string
+
+const (
+	VoteDirectionUp   VoteDirection = "UP"
+	VoteDirectionDown VoteDirection = "DOWN"
+	// Голоса нет. В мутации vote отзывает поставленный голос.
+	VoteDirectionNone VoteDirection = "NONE"
+)
+
+var AllVoteDirection = []VoteDirection{
+	VoteDirectionUp,
+	VoteDirectionDown,
+	VoteDirectionNone,
+}
+
+func (e VoteDirection) IsValid() bool {
+	switch e {
+	case VoteDirectionUp, VoteDirectionDown, VoteDirectionNone:
+		return true
+	}
+	return false
+}
+
+func (e VoteDirection) String() string {
+	return string(e)
+}
+
+func (e *VoteDirection) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = VoteDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid VoteDirection", str)
+	}
+	return nil
+}
+
+func (e VoteDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,26 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// moderate меняет статус модерации поста или комментария от имени модератора
+func (r *Resolver) moderate(ctx context.Context, targetID string, status models.ModerationStatus, reason *string) (Votable, error) {
+	moderator, err := auth.RequireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Moderator %s sets %s to %s", moderator.ID, targetID, status)
+	result, err := r.Storage.Moderate(ctx, targetID, status, reason)
+	if err != nil {
+		log.Printf("Failed to moderate %s: %v", targetID, err)
+		return nil, err
+	}
+	r.trainFilter(ctx, result)
+	return toModeratorVotable(&result.Target), nil
+}
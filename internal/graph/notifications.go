@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// mentionPattern находит упоминания @username; имя устроено так же, как при регистрации
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]{3,32})\b`)
+
+// maxMentions ограничивает число пользователей, уведомляемых об упоминании в одном комментарии
+const maxMentions = 10
+
+// mentions возвращает различные имена, упомянутые в тексте, без учёта регистра
+func mentions(content string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(match[1])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// notifyComment уведомляет автора родительского комментария или поста об ответе
+// и упомянутых пользователей. Каждый получатель получает одно уведомление, ответ
+// важнее упоминания; автор комментария себя не уведомляет. Ошибки только
+// логируются: комментарий уже сохранён
+func (r *Resolver) notifyComment(ctx context.Context, post *models.Post, comment *models.Comment) {
+	recipients := map[string]models.NotificationKind{}
+	var order []string
+	add := func(userID *string, kind models.NotificationKind) {
+		if userID == nil || (comment.AuthorID != nil && *userID == *comment.AuthorID) {
+			return
+		}
+		if _, ok := recipients[*userID]; ok {
+			return
+		}
+		recipients[*userID] = kind
+		order = append(order, *userID)
+	}
+
+	if comment.ParentID != nil {
+		parent, err := r.Storage.GetCommentByID(ctx, *comment.ParentID)
+		if err != nil {
+			log.Printf("Failed to fetch parent comment %s: %v", *comment.ParentID, err)
+		} else if !parent.Deleted() {
+			add(parent.AuthorID, models.NotificationCommentReply)
+		}
+	} else {
+		add(post.AuthorID, models.NotificationPostReply)
+	}
+
+	for _, name := range mentions(comment.Content) {
+		user, err := r.Storage.GetUserByUsername(ctx, name)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to fetch mentioned user %s: %v", name, err)
+			continue
+		}
+		add(&user.ID, models.NotificationMention)
+	}
+
+	for _, userID := range order {
+		_, err := r.Storage.CreateNotification(ctx, models.Notification{
+			UserID:    userID,
+			Kind:      recipients[userID],
+			ActorID:   comment.AuthorID,
+			PostID:    comment.PostID,
+			CommentID: comment.ID,
+		})
+		if err != nil {
+			log.Printf("Failed to notify user %s about comment %s: %v", userID, comment.ID, err)
+		}
+	}
+}
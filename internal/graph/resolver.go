@@ -6,20 +6,106 @@ import (
 	"context"
 	"errors"
 	"log"
-	"sync"
 
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/feed"
+	"github.com/MosinFAM/graphql-posts/internal/filter"
+	"github.com/MosinFAM/graphql-posts/internal/models"
 	"github.com/MosinFAM/graphql-posts/internal/storage"
 )
 
 type Resolver struct {
-	Storage       storage.Storage
-	mu            sync.Mutex
-	subscriptions map[string][]chan *Comment
+	Storage storage.Storage
+	Auth    *auth.Service
+	Feed    *feed.Feed
+	Filter  *filter.Pipeline // nil отключает фильтр контента
+	Events  events.Broker
 }
 
-func (r *mutationResolver) AddPost(ctx context.Context, title string, content string, allowComments bool) (*Post, error) {
+func (r *mutationResolver) Register(ctx context.Context, username string, password string) (*AuthPayload, error) {
+	log.Printf("Registering user: %s", username)
+	user, token, err := r.Auth.Register(ctx, username, password)
+	if err != nil {
+		log.Printf("Failed to register user: %v", err)
+		return nil, err
+	}
+
+	return &AuthPayload{Token: token, User: toGraphUser(user)}, nil
+}
+
+func (r *mutationResolver) Login(ctx context.Context, username string, password string) (*AuthPayload, error) {
+	log.Printf("Logging in user: %s", username)
+	user, token, err := r.Auth.Login(ctx, username, password)
+	if err != nil {
+		log.Printf("Failed to log in: %v", err)
+		return nil, err
+	}
+
+	return &AuthPayload{Token: token, User: toGraphUser(user)}, nil
+}
+
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	token := auth.TokenFromContext(ctx)
+	if token == "" {
+		return false, auth.ErrUnauthenticated
+	}
+	if err := r.Auth.Logout(ctx, token); err != nil {
+		log.Printf("Failed to log out: %v", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) SetRole(ctx context.Context, userID string, role Role) (*User, error) {
+	admin, err := auth.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userID == admin.ID {
+		return nil, auth.ErrForbidden
+	}
+
+	log.Printf("Admin %s sets role of %s to %s", admin.ID, userID, role)
+	user, err := r.Storage.SetUserRole(ctx, userID, toModelRole(role))
+	if err != nil {
+		log.Printf("Failed to set role of %s: %v", userID, err)
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+func (r *queryResolver) Viewer(ctx context.Context) (*User, error) {
+	return toGraphUser(auth.ViewerFromContext(ctx)), nil
+}
+
+func (r *mutationResolver) AddPost(ctx context.Context, title string, content string, allowComments bool, community *string, tags []string) (*Post, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var communityID *string
+	if community != nil {
+		modelCommunity, err := r.Storage.GetCommunityByName(ctx, *community)
+		if err != nil {
+			log.Printf("Failed to fetch community %s: %v", *community, err)
+			return nil, err
+		}
+		communityID = &modelCommunity.ID
+	}
+
+	filtered := filter.Content{Kind: models.ContentPost, AuthorID: viewer.ID, Title: title, Text: content}
+	decision, err := r.checkContent(ctx, filtered)
+	if err != nil {
+		log.Printf("Post rejected by filter: %v", err)
+		return nil, err
+	}
+
 	log.Printf("Adding post: title=%s", title)
-	modelPost, err := r.Storage.AddPost(title, content, allowComments)
+	modelPost, err := r.Storage.AddPost(ctx, viewer.ID, communityID, title, content, tags, allowComments,
+		initialStatus(decision))
 	if err != nil {
 		log.Printf("Failed to create post: %v", err)
 		return nil, err
@@ -28,60 +114,86 @@ func (r *mutationResolver) AddPost(ctx context.Context, title string, content st
 		log.Println("Post ID is empty, creation failed")
 		return nil, errors.New("failed to create post")
 	}
+	r.recordDecision(ctx, filtered, decision, modelPost.ID)
 
-	post := &Post{
-		ID:            modelPost.ID,
-		Title:         modelPost.Title,
-		Content:       modelPost.Content,
-		AllowComments: modelPost.AllowComments,
-	}
+	post := toGraphPost(&modelPost)
 
 	log.Printf("Post created successfully: ID=%s", post.ID)
 	return post, nil
 }
 
-func (r *queryResolver) Posts(ctx context.Context) ([]*Post, error) {
-	log.Println("Fetching all posts")
-	modelPosts, err := r.Storage.GetAllPosts()
+func (r *queryResolver) Posts(ctx context.Context, first *int, after *string, sort *PostSort, timeRange *TimeRange) (*PostConnection, error) {
+	log.Println("Fetching posts")
+	page, err := r.Storage.GetAllPosts(ctx, postListOptions(first, after, sort, timeRange))
 	if err != nil {
 		log.Printf("Failed to fetch posts: %v", err)
 		return nil, err
 	}
 
-	posts := make([]*Post, 0, len(modelPosts))
+	return toPostConnection(page), nil
+}
 
-	for _, modelPost := range modelPosts {
-		post := &Post{
-			ID:            modelPost.ID,
-			Title:         modelPost.Title,
-			AllowComments: modelPost.AllowComments,
-		}
-		posts = append(posts, post)
+func (r *queryResolver) HomeFeed(ctx context.Context, first *int, after *string, sort *PostSort) (*PostConnection, error) {
+	viewer := auth.ViewerFromContext(ctx)
+	log.Println("Fetching home feed")
+	page, err := r.Feed.Home(ctx, viewer, postListOptions(first, after, sort, nil))
+	if err != nil {
+		log.Printf("Failed to fetch home feed: %v", err)
+		return nil, err
 	}
 
-	return posts, nil
+	return toPostConnection(page), nil
 }
 
 func (r *queryResolver) Post(ctx context.Context, id string) (*Post, error) {
 	log.Printf("Fetching post with ID: %s", id)
-	modelPost, err := r.Storage.GetPostByID(id)
+	modelPost, err := r.Storage.GetPostByID(ctx, id)
 	if err != nil {
 		log.Printf("Failed to fetch post: %v", err)
 		return nil, err
 	}
 
-	post := &Post{
-		ID:            modelPost.ID,
-		Title:         modelPost.Title,
-		Content:       modelPost.Content,
-		AllowComments: modelPost.AllowComments,
+	return toGraphPost(modelPost), nil
+}
+
+func (r *queryResolver) Search(ctx context.Context, query string, typeArg *SearchType, community *string, first *int, after *string) (*SearchConnection, error) {
+	log.Printf("Searching: %s", query)
+	opts := storage.SearchOptions{Query: query}
+	if typeArg != nil {
+		opts.Type = storage.SearchType(*typeArg)
 	}
-	return post, nil
+	if community != nil {
+		modelCommunity, err := r.Storage.GetCommunityByName(ctx, *community)
+		if err != nil {
+			log.Printf("Failed to fetch community %s: %v", *community, err)
+			return nil, err
+		}
+		opts.CommunityID = modelCommunity.ID
+	}
+	if first != nil {
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+
+	page, err := r.Storage.Search(ctx, opts)
+	if err != nil {
+		log.Printf("Failed to search: %v", err)
+		return nil, err
+	}
+
+	return toSearchConnection(page), nil
 }
 
 func (r *mutationResolver) AddComment(ctx context.Context, postID string, parentID *string, content string) (*Comment, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Printf("Adding comment to post ID: %s", postID)
-	post, err := r.Storage.GetPostByID(postID)
+	post, err := r.Storage.GetPostByID(ctx, postID)
 	if err != nil {
 		log.Printf("Failed to fetch post: %v", err)
 		return nil, err
@@ -89,137 +201,813 @@ func (r *mutationResolver) AddComment(ctx context.Context, postID string, parent
 
 	if !post.AllowComments {
 		log.Println("Comments are disabled for this post")
-		return nil, errors.New("comments are disabled for this post")
+		return nil, storage.ErrCommentsDisabled
 	}
 
-	modelComment, err := r.Storage.AddComment(postID, parentID, content)
+	filtered := filter.Content{Kind: models.ContentComment, AuthorID: viewer.ID, Text: content}
+	decision, err := r.checkContent(ctx, filtered)
 	if err != nil {
-		log.Printf("Failed to add comment: %v", err)
+		log.Printf("Comment rejected by filter: %v", err)
 		return nil, err
 	}
 
-	comment := &Comment{
-		ID:        modelComment.ID,
-		PostID:    modelComment.PostID,
-		ParentID:  modelComment.ParentID,
-		Content:   modelComment.Content,
-		CreatedAt: modelComment.CreatedAt.String(),
+	modelComment, err := r.Storage.AddComment(ctx, viewer.ID, postID, parentID, content, initialStatus(decision))
+	if err != nil {
+		log.Printf("Failed to add comment: %v", err)
+		return nil, err
 	}
+	r.recordDecision(ctx, filtered, decision, modelComment.ID)
+
+	comment := toGraphComment(modelComment)
 
 	log.Printf("Comment added successfully: ID=%s", comment.ID)
-	// Отправка комментария в подписки
-	go func() {
-		r.mu.Lock()
-		defer r.mu.Unlock()
-		if subscribers, ok := r.subscriptions[postID]; ok {
-			for _, ch := range subscribers {
-				ch <- comment
-			}
-		}
-	}()
+	// О скрытом до проверки комментарии не уведомляем
+	if modelComment.ModStatus.Listed() {
+		r.notifyComment(ctx, post, modelComment)
+	}
 
 	return comment, nil
 }
 
-func (r *queryResolver) Comments(ctx context.Context, postID string, limit, offset int) ([]*Comment, error) {
+func (r *mutationResolver) UpdatePost(ctx context.Context, id string, title *string, content *string, allowComments *bool) (*Post, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Updating post ID: %s", id)
+	post, err := r.Storage.GetPostByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to fetch post: %v", err)
+		return nil, err
+	}
+	if err := auth.CanModify(viewer, post.AuthorID); err != nil {
+		log.Printf("User %s may not modify post %s", viewer.ID, id)
+		return nil, err
+	}
+
+	update := storage.PostUpdate{Title: title, Content: content, AllowComments: allowComments}
+	modelPost, err := r.Storage.UpdatePost(ctx, viewer.ID, id, update)
+	if err != nil {
+		log.Printf("Failed to update post: %v", err)
+		return nil, err
+	}
+
+	return toGraphPost(modelPost), nil
+}
+
+func (r *mutationResolver) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*Post, error) {
+	return r.UpdatePost(ctx, postID, nil, nil, &enabled)
+}
+
+func (r *mutationResolver) EditComment(ctx context.Context, id string, content string) (*Comment, error) {
+	if _, err := r.modifiableComment(ctx, id); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Editing comment ID: %s", id)
+	viewer := auth.ViewerFromContext(ctx)
+	modelComment, err := r.Storage.UpdateComment(ctx, viewer.ID, id, content)
+	if err != nil {
+		log.Printf("Failed to edit comment: %v", err)
+		return nil, err
+	}
+
+	return toGraphComment(modelComment), nil
+}
+
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (*Comment, error) {
+	if _, err := r.modifiableComment(ctx, id); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Deleting comment ID: %s", id)
+	modelComment, err := r.Storage.DeleteComment(ctx, id)
+	if err != nil {
+		log.Printf("Failed to delete comment: %v", err)
+		return nil, err
+	}
+
+	return toGraphComment(modelComment), nil
+}
+
+func (r *queryResolver) Comments(ctx context.Context, postID string, limit int, offset int) ([]*Comment, error) {
 	log.Printf("Fetching comments for post ID: %s", postID)
-	modelComments, err := r.Storage.GetCommentsByPostID(postID, limit, offset)
+	page, err := r.Storage.GetCommentsByPostID(ctx, postID, storage.CommentListOptions{First: limit, Offset: offset})
 	if err != nil {
 		log.Printf("Failed to fetch comments: %v", err)
 		return nil, err
 	}
 
-	comments := make([]*Comment, 0, len(modelComments))
+	comments := make([]*Comment, 0, len(page.Comments))
+	for _, modelComment := range page.Comments {
+		comments = append(comments, toGraphComment(modelComment))
+	}
+
+	return comments, nil
+}
+
+func (r *queryResolver) CommentsConnection(ctx context.Context, postID string, first *int, after *string, sort *CommentSort) (*CommentConnection, error) {
+	log.Printf("Fetching comments page for post ID: %s", postID)
+	page, err := r.Storage.GetCommentsByPostID(ctx, postID, commentListOptions(first, after, sort))
+	if err != nil {
+		log.Printf("Failed to fetch comments: %v", err)
+		return nil, err
+	}
+
+	return toCommentConnection(page), nil
+}
+
+func (r *queryResolver) CommentTree(ctx context.Context, postID string, maxDepth *int, maxChildren *int, sort *CommentSort) ([]*CommentTreeNode, error) {
+	log.Printf("Fetching comment tree for post ID: %s", postID)
+	depth := -1 // глубина по умолчанию выбирается хранилищем
+	if maxDepth != nil {
+		depth = *maxDepth
+	}
+	children := 0
+	if maxChildren != nil {
+		children = *maxChildren
+	}
+
+	modelNodes, err := r.Storage.GetCommentTree(ctx, postID, depth, children, toStorageCommentSort(sort))
+	if err != nil {
+		log.Printf("Failed to fetch comment tree: %v", err)
+		return nil, err
+	}
+
+	nodes := make([]*CommentTreeNode, 0, len(modelNodes))
+	for _, node := range modelNodes {
+		nodes = append(nodes, &CommentTreeNode{
+			Comment:        toGraphComment(node.Comment),
+			Depth:          node.Depth,
+			HasMoreReplies: node.HasMoreReplies,
+		})
+	}
+	return nodes, nil
+}
 
+func (r *queryResolver) Thread(ctx context.Context, commentID string) ([]*Comment, error) {
+	log.Printf("Fetching thread of comment ID: %s", commentID)
+	modelComments, err := r.Storage.GetThread(ctx, commentID)
+	if err != nil {
+		log.Printf("Failed to fetch thread: %v", err)
+		return nil, err
+	}
+
+	comments := make([]*Comment, 0, len(modelComments))
 	for _, modelComment := range modelComments {
-		comment := &Comment{
-			ID:        modelComment.ID,
-			PostID:    modelComment.PostID,
-			ParentID:  modelComment.ParentID,
-			Content:   modelComment.Content,
-			CreatedAt: modelComment.CreatedAt.String(),
+		comments = append(comments, toGraphComment(modelComment))
+	}
+	return comments, nil
+}
+
+func (r *commentResolver) Replies(ctx context.Context, obj *Comment, first *int, after *string, sort *CommentSort) (*CommentConnection, error) {
+	page, err := r.Storage.GetReplies(ctx, obj.ID, commentListOptions(first, after, sort))
+	if err != nil {
+		log.Printf("Failed to fetch replies: %v", err)
+		return nil, err
+	}
+
+	return toCommentConnection(page), nil
+}
+
+func (r *postResolver) Author(ctx context.Context, obj *Post) (*User, error) {
+	return r.userByID(ctx, obj.AuthorID)
+}
+
+func (r *commentResolver) Author(ctx context.Context, obj *Comment) (*User, error) {
+	return r.userByID(ctx, obj.AuthorID)
+}
+
+func (r *postResolver) Revisions(ctx context.Context, obj *Post) ([]*Revision, error) {
+	if err := auth.CanModify(auth.ViewerFromContext(ctx), obj.AuthorID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := r.Storage.GetPostRevisions(ctx, obj.ID)
+	if err != nil {
+		log.Printf("Failed to fetch post revisions: %v", err)
+		return nil, err
+	}
+
+	return toGraphRevisions(revisions, true), nil
+}
+
+func (r *commentResolver) Revisions(ctx context.Context, obj *Comment) ([]*Revision, error) {
+	if err := auth.CanModify(auth.ViewerFromContext(ctx), obj.AuthorID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := r.Storage.GetCommentRevisions(ctx, obj.ID)
+	if err != nil {
+		log.Printf("Failed to fetch comment revisions: %v", err)
+		return nil, err
+	}
+
+	return toGraphRevisions(revisions, false), nil
+}
+
+func (r *revisionResolver) Editor(ctx context.Context, obj *Revision) (*User, error) {
+	return r.userByID(ctx, obj.EditorID)
+}
+
+func (r *mutationResolver) Vote(ctx context.Context, targetID string, direction VoteDirection) (Votable, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Voting %s for %s", direction, targetID)
+	target, err := r.Storage.Vote(ctx, viewer.ID, targetID, toModelVote(direction))
+	if err != nil {
+		log.Printf("Failed to vote: %v", err)
+		return nil, err
+	}
+	return toGraphVotable(target), nil
+}
+
+func (r *postResolver) ViewerVote(ctx context.Context, obj *Post) (*VoteDirection, error) {
+	return r.viewerVote(ctx, obj.ID)
+}
+
+func (r *commentResolver) ViewerVote(ctx context.Context, obj *Comment) (*VoteDirection, error) {
+	return r.viewerVote(ctx, obj.ID)
+}
+
+func (r *queryResolver) Community(ctx context.Context, name string) (*Community, error) {
+	log.Printf("Fetching community %s", name)
+	community, err := r.Storage.GetCommunityByName(ctx, name)
+	if err != nil {
+		log.Printf("Failed to fetch community: %v", err)
+		return nil, err
+	}
+	return toGraphCommunity(community), nil
+}
+
+func (r *mutationResolver) CreateCommunity(ctx context.Context, name string, description string) (*Community, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Creating community %s", name)
+	community, err := r.Storage.CreateCommunity(ctx, viewer.ID, name, description)
+	if err != nil {
+		log.Printf("Failed to create community: %v", err)
+		return nil, err
+	}
+	return toGraphCommunity(community), nil
+}
+
+func (r *mutationResolver) JoinCommunity(ctx context.Context, name string) (*Community, error) {
+	return r.setMembership(ctx, name, r.Storage.JoinCommunity)
+}
+
+func (r *mutationResolver) LeaveCommunity(ctx context.Context, name string) (*Community, error) {
+	return r.setMembership(ctx, name, r.Storage.LeaveCommunity)
+}
+
+func (r *communityResolver) Creator(ctx context.Context, obj *Community) (*User, error) {
+	return r.userByID(ctx, obj.CreatorID)
+}
+
+func (r *communityResolver) ViewerIsMember(ctx context.Context, obj *Community) (*bool, error) {
+	viewer := auth.ViewerFromContext(ctx)
+	if viewer == nil {
+		return nil, nil
+	}
+	member, err := r.Storage.IsMember(ctx, viewer.ID, obj.ID)
+	if err != nil {
+		log.Printf("Failed to check membership: %v", err)
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *communityResolver) Posts(ctx context.Context, obj *Community, first *int, after *string, sort *PostSort, timeRange *TimeRange) (*PostConnection, error) {
+	log.Printf("Fetching posts of community %s", obj.Name)
+	opts := postListOptions(first, after, sort, timeRange)
+	opts.CommunityIDs = []string{obj.ID}
+	page, err := r.Storage.GetAllPosts(ctx, opts)
+	if err != nil {
+		log.Printf("Failed to fetch posts: %v", err)
+		return nil, err
+	}
+	return toPostConnection(page), nil
+}
+
+func (r *postResolver) Community(ctx context.Context, obj *Post) (*Community, error) {
+	if obj.CommunityID == nil {
+		return nil, nil
+	}
+	return r.communityByID(ctx, *obj.CommunityID)
+}
+
+func (r *queryResolver) Interests(ctx context.Context) ([]*Interest, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	interests, err := r.Storage.GetInterests(ctx, viewer.ID)
+	if err != nil {
+		log.Printf("Failed to fetch interests: %v", err)
+		return nil, err
+	}
+
+	result := make([]*Interest, 0, len(interests))
+	for _, interest := range interests {
+		result = append(result, toGraphInterest(interest))
+	}
+	return result, nil
+}
+
+func (r *mutationResolver) FollowAuthor(ctx context.Context, userID string) (*Interest, error) {
+	return r.follow(ctx, models.InterestAuthor, userID)
+}
+
+func (r *mutationResolver) UnfollowAuthor(ctx context.Context, userID string) (bool, error) {
+	return r.unfollow(ctx, models.InterestAuthor, userID)
+}
+
+func (r *mutationResolver) FollowTag(ctx context.Context, tag string) (*Interest, error) {
+	return r.follow(ctx, models.InterestTag, tag)
+}
+
+func (r *mutationResolver) UnfollowTag(ctx context.Context, tag string) (bool, error) {
+	return r.unfollow(ctx, models.InterestTag, tag)
+}
+
+func (r *interestResolver) Author(ctx context.Context, obj *Interest) (*User, error) {
+	return r.userByID(ctx, obj.AuthorID)
+}
+
+func (r *queryResolver) Notifications(ctx context.Context, unreadOnly *bool, first *int, after *string) (*NotificationConnection, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := storage.NotificationListOptions{}
+	if unreadOnly != nil {
+		opts.UnreadOnly = *unreadOnly
+	}
+	if first != nil {
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+
+	log.Printf("Fetching notifications of user %s", viewer.ID)
+	page, err := r.Storage.GetNotifications(ctx, viewer.ID, opts)
+	if err != nil {
+		log.Printf("Failed to fetch notifications: %v", err)
+		return nil, err
+	}
+	return toNotificationConnection(page), nil
+}
+
+func (r *queryResolver) UnreadNotificationCount(ctx context.Context) (int, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, err := r.Storage.CountUnreadNotifications(ctx, viewer.ID)
+	if err != nil {
+		log.Printf("Failed to count unread notifications: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []string) (int, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return 0, err
+	}
+	marked, err := r.Storage.MarkNotificationsRead(ctx, viewer.ID, ids)
+	if err != nil {
+		log.Printf("Failed to mark notifications read: %v", err)
+		return 0, err
+	}
+	return marked, nil
+}
+
+func (r *mutationResolver) Report(ctx context.Context, targetID string, reason string) (bool, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Reporting %s", targetID)
+	if err := r.Storage.Report(ctx, viewer.ID, targetID, reason); err != nil {
+		log.Printf("Failed to report %s: %v", targetID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) Approve(ctx context.Context, targetID string) (Votable, error) {
+	return r.moderate(ctx, targetID, models.ModerationApproved, nil)
+}
+
+func (r *mutationResolver) Remove(ctx context.Context, targetID string, reason string) (Votable, error) {
+	return r.moderate(ctx, targetID, models.ModerationRemoved, &reason)
+}
+
+func (r *mutationResolver) LockComment(ctx context.Context, id string, locked bool) (*Comment, error) {
+	if _, err := auth.RequireModerator(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Setting lock of comment %s to %t", id, locked)
+	comment, err := r.Storage.LockComment(ctx, id, locked)
+	if err != nil {
+		log.Printf("Failed to lock comment: %v", err)
+		return nil, err
+	}
+	return toModeratorComment(comment), nil
+}
+
+func (r *queryResolver) ModQueue(ctx context.Context, community *string, first *int) ([]*ModQueueItem, error) {
+	if _, err := auth.RequireModerator(ctx); err != nil {
+		return nil, err
+	}
+
+	opts := storage.ModQueueOptions{}
+	if community != nil {
+		modelCommunity, err := r.Storage.GetCommunityByName(ctx, *community)
+		if err != nil {
+			log.Printf("Failed to fetch community %s: %v", *community, err)
+			return nil, err
 		}
+		opts.CommunityID = modelCommunity.ID
+	}
+	if first != nil {
+		opts.First = *first
+	}
+
+	log.Println("Fetching moderation queue")
+	items, err := r.Storage.GetModQueue(ctx, opts)
+	if err != nil {
+		log.Printf("Failed to fetch moderation queue: %v", err)
+		return nil, err
+	}
+	return toGraphModQueue(items), nil
+}
 
-		comments = append(comments, comment)
+func (r *queryResolver) Bans(ctx context.Context, community string) ([]*Ban, error) {
+	if _, err := auth.RequireModerator(ctx); err != nil {
+		return nil, err
+	}
+	communityID, err := r.communityIDByName(ctx, community)
+	if err != nil {
+		return nil, err
 	}
 
-	return comments, nil
+	log.Printf("Fetching bans of community %s", community)
+	bans, err := r.Storage.GetBans(ctx, communityID)
+	if err != nil {
+		log.Printf("Failed to fetch bans: %v", err)
+		return nil, err
+	}
+	result := make([]*Ban, 0, len(bans))
+	for _, ban := range bans {
+		result = append(result, toGraphBan(ban))
+	}
+	return result, nil
+}
+
+func (r *mutationResolver) BanUser(ctx context.Context, community string, userID string, reason string, days *int,
+	muted bool) (*Ban, error) {
+	moderator, err := auth.RequireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := banDuration(days)
+	if err != nil {
+		return nil, err
+	}
+	communityID, err := r.communityIDByName(ctx, community)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Moderator %s bans user %s in community %s", moderator.ID, userID, community)
+	ban, err := r.Storage.BanUser(ctx, storage.BanOptions{
+		CommunityID: communityID,
+		UserID:      userID,
+		ModeratorID: moderator.ID,
+		Reason:      reason,
+		Duration:    duration,
+		Muted:       muted,
+	})
+	if err != nil {
+		log.Printf("Failed to ban user %s: %v", userID, err)
+		return nil, err
+	}
+	return toGraphBan(ban), nil
+}
+
+func (r *mutationResolver) UnbanUser(ctx context.Context, community string, userID string) (bool, error) {
+	moderator, err := auth.RequireModerator(ctx)
+	if err != nil {
+		return false, err
+	}
+	communityID, err := r.communityIDByName(ctx, community)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Moderator %s unbans user %s in community %s", moderator.ID, userID, community)
+	if err := r.Storage.UnbanUser(ctx, communityID, userID); err != nil {
+		log.Printf("Failed to unban user %s: %v", userID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) AppealBan(ctx context.Context, community string, message string) (*BanAppeal, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	communityID, err := r.communityIDByName(ctx, community)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %s appeals ban in community %s", viewer.ID, community)
+	appeal, err := r.Storage.AppealBan(ctx, communityID, viewer.ID, message)
+	if err != nil {
+		log.Printf("Failed to appeal ban: %v", err)
+		return nil, err
+	}
+	return toGraphBanAppeal(appeal), nil
+}
+
+func (r *mutationResolver) ResolveAppeal(ctx context.Context, appealID string, accepted bool) (*BanAppeal, error) {
+	moderator, err := auth.RequireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Moderator %s resolves ban appeal %s, accepted: %t", moderator.ID, appealID, accepted)
+	appeal, err := r.Storage.ResolveAppeal(ctx, appealID, accepted)
+	if err != nil {
+		log.Printf("Failed to resolve ban appeal %s: %v", appealID, err)
+		return nil, err
+	}
+	return toGraphBanAppeal(appeal), nil
+}
+
+func (r *queryResolver) FilterDecisions(ctx context.Context, first *int) ([]*FilterDecision, error) {
+	if _, err := auth.RequireModerator(ctx); err != nil {
+		return nil, err
+	}
+
+	limit := 0
+	if first != nil {
+		limit = *first
+	}
+	log.Println("Fetching filter decisions")
+	decisions, err := r.Storage.GetFilterDecisions(ctx, limit)
+	if err != nil {
+		log.Printf("Failed to fetch filter decisions: %v", err)
+		return nil, err
+	}
+	result := make([]*FilterDecision, 0, len(decisions))
+	for _, decision := range decisions {
+		result = append(result, toGraphFilterDecision(decision))
+	}
+	return result, nil
+}
+
+func (r *filterDecisionResolver) Author(ctx context.Context, obj *FilterDecision) (*User, error) {
+	return r.userByID(ctx, obj.AuthorID)
+}
+
+func (r *banResolver) Community(ctx context.Context, obj *Ban) (*Community, error) {
+	return r.communityByID(ctx, obj.CommunityID)
+}
+
+func (r *banResolver) User(ctx context.Context, obj *Ban) (*User, error) {
+	return r.userByID(ctx, &obj.UserID)
+}
+
+func (r *banResolver) Moderator(ctx context.Context, obj *Ban) (*User, error) {
+	return r.userByID(ctx, obj.ModeratorID)
+}
+
+func (r *banAppealResolver) Community(ctx context.Context, obj *BanAppeal) (*Community, error) {
+	return r.communityByID(ctx, obj.CommunityID)
+}
+
+func (r *banAppealResolver) User(ctx context.Context, obj *BanAppeal) (*User, error) {
+	return r.userByID(ctx, &obj.UserID)
+}
+
+func (r *notificationResolver) Actor(ctx context.Context, obj *Notification) (*User, error) {
+	return r.userByID(ctx, obj.ActorID)
+}
+
+func (r *notificationResolver) Post(ctx context.Context, obj *Notification) (*Post, error) {
+	post, err := r.Storage.GetPostByID(ctx, obj.PostID)
+	if err != nil {
+		log.Printf("Failed to fetch post %s: %v", obj.PostID, err)
+		return nil, err
+	}
+	return toGraphPost(post), nil
+}
+
+func (r *notificationResolver) Comment(ctx context.Context, obj *Notification) (*Comment, error) {
+	comment, err := r.Storage.GetCommentByID(ctx, obj.CommentID)
+	if err != nil {
+		log.Printf("Failed to fetch comment %s: %v", obj.CommentID, err)
+		return nil, err
+	}
+	return toGraphComment(comment), nil
+}
+
+func (r *subscriptionResolver) NotificationReceived(ctx context.Context) (<-chan *Notification, error) {
+	viewer, err := auth.RequireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Subscribing to notifications of user %s", viewer.ID)
+	return forward(ctx, r.Resolver, events.UserTopic(viewer.ID), events.NotificationCreated,
+		func(ctx context.Context, event events.Event) (*Notification, error) {
+			var notification models.Notification
+			ok, err := event.Decode(&notification)
+			if err == nil && !ok {
+				err = errors.New("notification is missing from event")
+			}
+			if err != nil {
+				return nil, err
+			}
+			return toGraphNotification(&notification), nil
+		})
 }
 
 func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *Comment, error) {
 	log.Printf("Subscribing to comments for post ID: %s", postID)
-	modelCh, err := r.Storage.SubscribeToComments(postID)
+	// События поста включают его изменения: подписка завершается, когда комментарии запрещают
+	ctx, cancel := context.WithCancel(ctx)
+	received, err := r.subscribe(ctx, events.PostTopic(postID))
 	if err != nil {
+		cancel()
 		log.Printf("Failed to subscribe: %v", err)
 		return nil, err
 	}
 
 	ch := make(chan *Comment, 1)
-
-	// Горутина для преобразования значений
 	go func() {
+		defer cancel()
 		defer close(ch)
-		for {
-			select {
-			case <-ctx.Done():
-				log.Println("Subscription cancelled")
-				return // Контекст отменён — просто выходим из горутины
-			case comment, ok := <-modelCh:
-				if !ok {
-					log.Println("Subscription channel closed")
-					return // Если modelCh закрыт, выходим из горутины
+		for event := range received {
+			switch event.Type {
+			case events.PostUpdated:
+				post, err := r.eventPost(ctx, event)
+				if err != nil {
+					log.Printf("Failed to load updated post %s: %v", postID, err)
+					continue
+				}
+				if !post.AllowComments {
+					log.Printf("Comments disabled for post %s, completing subscription", postID)
+					return
 				}
-				convertedComment := &Comment{
-					ID:        comment.ID,
-					PostID:    comment.PostID,
-					ParentID:  comment.ParentID,
-					Content:   comment.Content,
-					CreatedAt: comment.CreatedAt.String(),
+			case events.CommentAdded:
+				comment, err := r.eventComment(ctx, event)
+				if err != nil {
+					log.Printf("Failed to load comment %s: %v", event.ID, err)
+					continue
+				}
+				// Скрытый до проверки модератором комментарий не рассылаем
+				if !comment.ModStatus.Listed() {
+					continue
 				}
 				select {
-				case ch <- convertedComment:
+				case ch <- toGraphComment(comment):
 				case <-ctx.Done():
-					return // Контекст отменён, выходим
+					return
 				}
 			}
 		}
+		log.Println("Subscription cancelled")
 	}()
 
-	r.mu.Lock()
-	if r.subscriptions == nil {
-		r.subscriptions = make(map[string][]chan *Comment)
-	}
-	r.subscriptions[postID] = append(r.subscriptions[postID], ch)
-	r.mu.Unlock()
+	return ch, nil
+}
 
-	// Отписка при завершении контекста
-	go func() {
-		<-ctx.Done()
-		r.mu.Lock()
-		defer r.mu.Unlock()
-
-		// Удаляем подписчика
-		for i, sub := range r.subscriptions[postID] {
-			if sub == ch {
-				r.subscriptions[postID] = append(r.subscriptions[postID][:i], r.subscriptions[postID][i+1:]...)
-				break
+func (r *subscriptionResolver) CommentUpdated(ctx context.Context, postID string) (<-chan *Comment, error) {
+	log.Printf("Subscribing to comment updates for post ID: %s", postID)
+	return forward(ctx, r.Resolver, events.PostTopic(postID), events.CommentUpdated,
+		func(ctx context.Context, event events.Event) (*Comment, error) {
+			comment, err := r.eventComment(ctx, event)
+			if err != nil {
+				return nil, err
 			}
+			return toGraphComment(comment), nil
+		})
+}
+
+func (r *subscriptionResolver) CommentDeleted(ctx context.Context, postID string) (<-chan string, error) {
+	log.Printf("Subscribing to comment deletions for post ID: %s", postID)
+	return forward(ctx, r.Resolver, events.PostTopic(postID), events.CommentDeleted,
+		func(ctx context.Context, event events.Event) (string, error) {
+			return event.ID, nil
+		})
+}
+
+func (r *subscriptionResolver) PostAdded(ctx context.Context, community *string) (<-chan *Post, error) {
+	topic := events.PostsTopic
+	if community != nil {
+		communityID, err := r.communityIDByName(ctx, *community)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		topic = events.CommunityTopic(communityID)
+	}
 
-	return ch, nil
+	log.Printf("Subscribing to new posts on %s", topic)
+	return forward(ctx, r.Resolver, topic, events.PostAdded,
+		func(ctx context.Context, event events.Event) (*Post, error) {
+			post, err := r.eventPost(ctx, event)
+			if err != nil {
+				return nil, err
+			}
+			// Скрытый до проверки модератором пост не рассылаем
+			if !post.ModStatus.Listed() {
+				return nil, errHidden
+			}
+			return toGraphPost(post), nil
+		})
+}
+
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, id string) (<-chan *Post, error) {
+	log.Printf("Subscribing to updates of post ID: %s", id)
+	return forward(ctx, r.Resolver, events.PostTopic(id), events.PostUpdated, r.eventGraphPost)
 }
 
+func (r *subscriptionResolver) ScoreChanged(ctx context.Context, targetID string) (<-chan Votable, error) {
+	log.Printf("Subscribing to score of %s", targetID)
+	return forward(ctx, r.Resolver, events.ScoreTopic(targetID), events.ScoreChanged,
+		func(ctx context.Context, event events.Event) (Votable, error) {
+			target, err := r.eventTarget(ctx, event)
+			if err != nil {
+				return nil, err
+			}
+			return toGraphVotable(target), nil
+		})
+}
+
+// Community returns CommunityResolver implementation.
+func (r *Resolver) Community() CommunityResolver { return &communityResolver{r} }
+
+// Ban returns BanResolver implementation.
+func (r *Resolver) Ban() BanResolver { return &banResolver{r} }
+
+// BanAppeal returns BanAppealResolver implementation.
+func (r *Resolver) BanAppeal() BanAppealResolver { return &banAppealResolver{r} }
+
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
+// FilterDecision returns FilterDecisionResolver implementation.
+func (r *Resolver) FilterDecision() FilterDecisionResolver { return &filterDecisionResolver{r} }
+
+// Interest returns InterestResolver implementation.
+func (r *Resolver) Interest() InterestResolver { return &interestResolver{r} }
+
+// Notification returns NotificationResolver implementation.
+func (r *Resolver) Notification() NotificationResolver { return &notificationResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Revision returns RevisionResolver implementation.
+func (r *Resolver) Revision() RevisionResolver { return &revisionResolver{r} }
+
 // Query returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+type banResolver struct{ *Resolver }
+type banAppealResolver struct{ *Resolver }
+type commentResolver struct{ *Resolver }
+type communityResolver struct{ *Resolver }
+type filterDecisionResolver struct{ *Resolver }
+type interestResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
+type revisionResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
 
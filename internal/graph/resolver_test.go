@@ -3,22 +3,38 @@ package graph
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/feed"
+	"github.com/MosinFAM/graphql-posts/internal/filter"
 	"github.com/MosinFAM/graphql-posts/internal/models"
 	"github.com/MosinFAM/graphql-posts/internal/storage"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+var testViewer = &models.User{ID: "u1", Username: "alice", Role: models.RoleUser}
+
+// viewerContext возвращает контекст запроса от имени testViewer
+func viewerContext() context.Context {
+	return auth.WithViewer(context.Background(), testViewer, "token")
+}
+
 func TestAddPost(t *testing.T) {
 	mockStorage := new(storage.MockStorage)
 	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
 
 	expectedPost := models.Post{ID: "1", Title: "Test Post", Content: "Test Content", AllowComments: true}
-	mockStorage.On("AddPost", "Test Post", "Test Content", true).Return(expectedPost, nil)
+	mockStorage.On("AddPost", testViewer.ID, (*string)(nil), "Test Post", "Test Content", []string(nil), true, models.ModerationVisible).Return(expectedPost, nil)
 
-	post, err := resolver.AddPost(context.Background(), "Test Post", "Test Content", true)
+	post, err := resolver.AddPost(viewerContext(), "Test Post", "Test Content", true, nil, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, post)
 	assert.Equal(t, "Test Post", post.Title)
@@ -30,9 +46,9 @@ func TestAddPost_Failure(t *testing.T) {
 	mockStorage := new(storage.MockStorage)
 	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
 
-	mockStorage.On("AddPost", "Test Post", "Test Content", true).Return(models.Post{}, errors.New("failed to create post"))
+	mockStorage.On("AddPost", testViewer.ID, (*string)(nil), "Test Post", "Test Content", []string(nil), true, models.ModerationVisible).Return(models.Post{}, errors.New("failed to create post"))
 
-	post, err := resolver.AddPost(context.Background(), "Test Post", "Test Content", true)
+	post, err := resolver.AddPost(viewerContext(), "Test Post", "Test Content", true, nil, nil)
 	assert.Error(t, err)
 	assert.Nil(t, post)
 
@@ -47,13 +63,17 @@ func TestPosts(t *testing.T) {
 		{ID: "1", Title: "Test Post 1"},
 		{ID: "2", Title: "Test Post 2"},
 	}
-	mockStorage.On("GetAllPosts").Return(expectedPosts, nil)
+	opts := storage.PostListOptions{First: 2, Sort: storage.PostSortTop, TimeRange: storage.TimeRangeWeek}
+	mockStorage.On("GetAllPosts", opts).Return(&storage.PostPage{Posts: expectedPosts, HasNextPage: true}, nil)
 
-	posts, err := resolver.Posts(context.Background())
+	first, sort, timeRange := 2, PostSortTop, TimeRangeWeek
+	posts, err := resolver.Posts(context.Background(), &first, nil, &sort, &timeRange)
 	assert.NoError(t, err)
-	assert.Len(t, posts, 2)
-	assert.Equal(t, "Test Post 1", posts[0].Title)
-	assert.Equal(t, "Test Post 2", posts[1].Title)
+	assert.Len(t, posts.Edges, 2)
+	assert.Equal(t, "Test Post 1", posts.Edges[0].Node.Title)
+	assert.Equal(t, "Test Post 2", posts.Edges[1].Node.Title)
+	assert.True(t, posts.PageInfo.HasNextPage)
+	assert.Equal(t, posts.Edges[1].Cursor, *posts.PageInfo.EndCursor)
 
 	mockStorage.AssertExpectations(t)
 }
@@ -73,15 +93,37 @@ func TestPost(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestPost_Timestamps(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage}}
+
+	createdAt := time.Date(2026, 10, 17, 14, 0, 0, 0, time.UTC)
+	editedAt := createdAt.Add(time.Hour)
+	expectedPost := &models.Post{ID: "1", Title: "Test Post", CreatedAt: createdAt, UpdatedAt: editedAt, EditedAt: &editedAt}
+	mockStorage.On("GetPostByID", "1").Return(expectedPost, nil)
+
+	post, err := resolver.Post(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, createdAt, post.CreatedAt)
+	assert.Equal(t, editedAt, post.UpdatedAt)
+	assert.Equal(t, &editedAt, post.EditedAt)
+
+	var value strings.Builder
+	graphql.MarshalTime(post.CreatedAt).MarshalGQL(&value)
+	assert.Equal(t, `"2026-10-17T14:00:00Z"`, value.String())
+
+	mockStorage.AssertExpectations(t)
+}
+
 func TestAddComment(t *testing.T) {
 	mockStorage := new(storage.MockStorage)
 	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
 
 	expectedComment := &models.Comment{ID: "1", PostID: "1", Content: "Test Comment"}
 	mockStorage.On("GetPostByID", "1").Return(&models.Post{ID: "1", AllowComments: true}, nil)
-	mockStorage.On("AddComment", "1", (*string)(nil), "Test Comment").Return(expectedComment, nil)
+	mockStorage.On("AddComment", testViewer.ID, "1", (*string)(nil), "Test Comment", models.ModerationVisible).Return(expectedComment, nil)
 
-	comment, err := resolver.AddComment(context.Background(), "1", nil, "Test Comment")
+	comment, err := resolver.AddComment(viewerContext(), "1", nil, "Test Comment")
 	assert.NoError(t, err)
 	assert.NotNil(t, comment)
 	assert.Equal(t, "Test Comment", comment.Content)
@@ -97,7 +139,8 @@ func TestComments(t *testing.T) {
 		{ID: "1", PostID: "1", Content: "Test Comment 1"},
 		{ID: "2", PostID: "1", Content: "Test Comment 2"},
 	}
-	mockStorage.On("GetCommentsByPostID", "1", 10, 0).Return(expectedComments, nil)
+	mockStorage.On("GetCommentsByPostID", "1", storage.CommentListOptions{First: 10, Offset: 0}).
+		Return(&storage.CommentPage{Comments: expectedComments}, nil)
 
 	comments, err := resolver.Comments(context.Background(), "1", 10, 0)
 	assert.NoError(t, err)
@@ -107,22 +150,929 @@ func TestComments(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+// publish публикует событие с объектом data в брокер
+func publish(t *testing.T, broker events.Broker, topic, eventType, id string, data interface{}) {
+	t.Helper()
+	event, err := events.NewEvent(eventType, id, data)
+	assert.NoError(t, err)
+	assert.NoError(t, broker.Publish(context.Background(), topic, event))
+}
+
 func TestCommentAdded(t *testing.T) {
 	mockStorage := new(storage.MockStorage)
-	resolver := &subscriptionResolver{&Resolver{Storage: mockStorage}}
+	broker := events.NewMemoryBroker()
+	resolver := &subscriptionResolver{&Resolver{Storage: mockStorage, Events: broker}}
 
-	commentCh := make(chan *models.Comment, 1)
-	mockStorage.On("SubscribeToComments", "1").Return(commentCh, nil)
-
-	subCh, err := resolver.CommentAdded(context.Background(), "1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subCh, err := resolver.CommentAdded(ctx, "1")
 	assert.NoError(t, err)
 	assert.NotNil(t, subCh)
 
-	expectedComment := &models.Comment{ID: "1", PostID: "1", Content: "New Comment"}
-	commentCh <- expectedComment
-
+	publish(t, broker, events.PostTopic("1"), events.CommentAdded, "c1",
+		&models.Comment{ID: "c1", PostID: "1", Content: "New Comment"})
 	receivedComment := <-subCh
 	assert.Equal(t, "New Comment", receivedComment.Content)
 
+	// Комментарий, не поместившийся в событие, читается из хранилища
+	mockStorage.On("GetCommentByID", "c2").Return(&models.Comment{ID: "c2", PostID: "1", Content: "Long Comment"}, nil)
+	assert.NoError(t, broker.Publish(context.Background(), events.PostTopic("1"), events.Event{Type: events.CommentAdded, ID: "c2"}))
+	receivedComment = <-subCh
+	assert.Equal(t, "Long Comment", receivedComment.Content)
+
+	// Скрытый до проверки комментарий подписчики не получают
+	publish(t, broker, events.PostTopic("1"), events.CommentAdded, "c3",
+		&models.Comment{ID: "c3", PostID: "1", Content: "Spam", ModStatus: models.ModerationFlagged})
+	publish(t, broker, events.PostTopic("1"), events.CommentAdded, "c4",
+		&models.Comment{ID: "c4", PostID: "1", Content: "Next Comment"})
+	receivedComment = <-subCh
+	assert.Equal(t, "c4", receivedComment.ID)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCommentAdded_NoBroker(t *testing.T) {
+	resolver := &subscriptionResolver{&Resolver{Storage: new(storage.MockStorage)}}
+
+	_, err := resolver.CommentAdded(context.Background(), "1")
+	assert.ErrorIs(t, err, errNoBroker)
+}
+
+func TestPosts_CanceledContext(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	posts, err := resolver.Posts(ctx, nil, nil, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, posts)
+
+	mockStorage.AssertNotCalled(t, "GetAllPosts")
+}
+
+func TestCommentsConnection(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage}}
+
+	expectedComments := []*models.Comment{
+		{ID: "1", PostID: "1", Content: "Test Comment 1", CreatedAt: time.Unix(100, 0)},
+		{ID: "2", PostID: "1", Content: "Test Comment 2", CreatedAt: time.Unix(200, 0)},
+	}
+	after := storage.EncodeCursor(storage.Cursor{CreatedAt: time.Unix(50, 0), ID: "0"})
+	first := 2
+	mockStorage.On("GetCommentsByPostID", "1", storage.CommentListOptions{First: 2, After: after}).
+		Return(&storage.CommentPage{Comments: expectedComments, HasNextPage: true}, nil)
+
+	conn, err := resolver.CommentsConnection(context.Background(), "1", &first, &after, nil)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 2)
+	assert.Equal(t, "Test Comment 2", conn.Edges[1].Node.Content)
+	assert.True(t, conn.PageInfo.HasNextPage)
+	assert.Equal(t, conn.Edges[1].Cursor, *conn.PageInfo.EndCursor)
+
+	cursor, err := storage.DecodeCursor(*conn.PageInfo.EndCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", cursor.ID)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCommentTree(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage}}
+
+	parentID := "1"
+	expectedNodes := []*models.CommentNode{
+		{Comment: &models.Comment{ID: "1", PostID: "1", Content: "Root"}, Depth: 0},
+		{Comment: &models.Comment{ID: "2", PostID: "1", ParentID: &parentID, Content: "Reply"}, Depth: 1, HasMoreReplies: true},
+	}
+	mockStorage.On("GetCommentTree", "1", -1, 5, storage.CommentSortBest).Return(expectedNodes, nil)
+
+	maxChildren := 5
+	sort := CommentSortBest
+	nodes, err := resolver.CommentTree(context.Background(), "1", nil, &maxChildren, &sort)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "Reply", nodes[1].Comment.Content)
+	assert.Equal(t, 1, nodes[1].Depth)
+	assert.True(t, nodes[1].HasMoreReplies)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestReplies(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &commentResolver{&Resolver{Storage: mockStorage}}
+
+	parentID := "1"
+	expectedReplies := []*models.Comment{{ID: "2", PostID: "1", ParentID: &parentID, Content: "Reply"}}
+	mockStorage.On("GetReplies", "1", storage.CommentListOptions{}).
+		Return(&storage.CommentPage{Comments: expectedReplies}, nil)
+
+	conn, err := resolver.Replies(context.Background(), &Comment{ID: "1"}, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 1)
+	assert.Equal(t, "Reply", conn.Edges[0].Node.Content)
+	assert.False(t, conn.PageInfo.HasNextPage)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestThread(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage}}
+
+	parentID := "1"
+	expectedComments := []*models.Comment{
+		{ID: "1", PostID: "1", Content: "Root"},
+		{ID: "2", PostID: "1", ParentID: &parentID, Content: "Reply", Depth: 1},
+	}
+	mockStorage.On("GetThread", "1").Return(expectedComments, nil)
+
+	comments, err := resolver.Thread(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.Equal(t, 1, comments[1].Depth)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestAddPost_Unauthenticated(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	post, err := resolver.AddPost(context.Background(), "Test Post", "Test Content", true, nil, nil)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	assert.Nil(t, post)
+
+	mockStorage.AssertNotCalled(t, "AddPost")
+}
+
+func TestRegisterAndViewer(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	resolver := &Resolver{Storage: store, Auth: auth.NewService(store)}
+
+	payload, err := resolver.Mutation().Register(context.Background(), "alice", "correct horse")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, payload.Token)
+	assert.Equal(t, "alice", payload.User.Username)
+	assert.Equal(t, RoleUser, payload.User.Role)
+
+	viewer, err := resolver.Auth.Authenticate(context.Background(), payload.Token)
+	assert.NoError(t, err)
+	ctx := auth.WithViewer(context.Background(), viewer, payload.Token)
+
+	me, err := resolver.Query().Viewer(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, payload.User.ID, me.ID)
+
+	post, err := resolver.Mutation().AddPost(ctx, "Title", "Content", true, nil, nil)
+	assert.NoError(t, err)
+	author, err := resolver.Post().Author(ctx, post)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", author.Username)
+
+	ok, err := resolver.Mutation().Logout(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = resolver.Auth.Authenticate(context.Background(), payload.Token)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+}
+
+func TestCommentAuthor_Anonymous(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &commentResolver{&Resolver{Storage: mockStorage}}
+
+	author, err := resolver.Author(context.Background(), &Comment{ID: "1"})
+	assert.NoError(t, err)
+	assert.Nil(t, author)
+
+	mockStorage.AssertNotCalled(t, "GetUserByID")
+}
+
+func TestUpdatePost_Forbidden(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	otherAuthor := "u2"
+	mockStorage.On("GetPostByID", "1").Return(&models.Post{ID: "1", AuthorID: &otherAuthor}, nil)
+
+	title := "New title"
+	post, err := resolver.UpdatePost(viewerContext(), "1", &title, nil, nil)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	assert.Nil(t, post)
+
+	mockStorage.AssertNotCalled(t, "UpdatePost")
+}
+
+func TestSetCommentsEnabled_Moderator(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	otherAuthor := "u2"
+	disabled := false
+	mockStorage.On("GetPostByID", "1").Return(&models.Post{ID: "1", AuthorID: &otherAuthor, AllowComments: true}, nil)
+	mockStorage.On("UpdatePost", "m1", "1", storage.PostUpdate{AllowComments: &disabled}).
+		Return(&models.Post{ID: "1", AuthorID: &otherAuthor}, nil)
+
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	ctx := auth.WithViewer(context.Background(), moderator, "token")
+	post, err := resolver.SetCommentsEnabled(ctx, "1", false)
+	assert.NoError(t, err)
+	assert.False(t, post.AllowComments)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCommentAdded_CompletesWhenCommentsDisabled(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	resolver := &subscriptionResolver{&Resolver{Storage: new(storage.MockStorage), Events: broker}}
+
+	subCh, err := resolver.CommentAdded(context.Background(), "1")
+	assert.NoError(t, err)
+
+	publish(t, broker, events.PostTopic("1"), events.PostUpdated, "1", &models.Post{ID: "1", AllowComments: false})
+
+	select {
+	case _, ok := <-subCh:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not completed")
+	}
+}
+
+func TestEditComment_Forbidden(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	otherAuthor := "u2"
+	mockStorage.On("GetCommentByID", "1").Return(&models.Comment{ID: "1", AuthorID: &otherAuthor}, nil)
+
+	comment, err := resolver.EditComment(viewerContext(), "1", "Edited")
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	assert.Nil(t, comment)
+
+	mockStorage.AssertNotCalled(t, "UpdateComment")
+}
+
+func TestDeleteComment_Tombstone(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	deletedAt := time.Unix(100, 0)
+	mockStorage.On("GetCommentByID", "1").Return(&models.Comment{ID: "1", AuthorID: &testViewer.ID, Content: "Text"}, nil)
+	mockStorage.On("DeleteComment", "1").
+		Return(&models.Comment{ID: "1", AuthorID: &testViewer.ID, Content: "Text", DeletedAt: &deletedAt}, nil)
+
+	comment, err := resolver.DeleteComment(viewerContext(), "1")
+	assert.NoError(t, err)
+	assert.True(t, comment.Deleted)
+	assert.Equal(t, models.DeletedPlaceholder, comment.Content)
+	assert.Nil(t, comment.AuthorID)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestPostRevisions_Diff(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &postResolver{&Resolver{Storage: mockStorage}}
+
+	mockStorage.On("GetPostRevisions", "1").Return([]*models.Revision{
+		{TargetID: "1", Version: 1, EditorID: &testViewer.ID, Title: "Title", Content: "old"},
+		{TargetID: "1", Version: 2, EditorID: &testViewer.ID, Title: "Title", Content: "new"},
+	}, nil)
+
+	revisions, err := resolver.Revisions(viewerContext(), &Post{ID: "1", AuthorID: &testViewer.ID})
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "+old\n", revisions[0].Diff)
+	assert.Equal(t, "-old\n+new\n", revisions[1].Diff)
+	assert.Equal(t, "Title", *revisions[1].Title)
+}
+
+func TestCommentRevisions_Forbidden(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &commentResolver{&Resolver{Storage: mockStorage}}
+
+	otherAuthor := "u2"
+	revisions, err := resolver.Revisions(viewerContext(), &Comment{ID: "1", AuthorID: &otherAuthor})
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	assert.Nil(t, revisions)
+
+	mockStorage.AssertNotCalled(t, "GetCommentRevisions")
+}
+
+func TestVote_Comment(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	mockStorage.On("Vote", testViewer.ID, "1", models.VoteDown).
+		Return(&storage.Target{Comment: &models.Comment{ID: "1", Upvotes: 2, Downvotes: 3}}, nil)
+
+	target, err := resolver.Vote(viewerContext(), "1", VoteDirectionDown)
+	assert.NoError(t, err)
+	comment, ok := target.(*Comment)
+	assert.True(t, ok)
+	assert.Equal(t, -1, comment.Score)
+	assert.Equal(t, 3, comment.Downvotes)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestVote_Unauthenticated(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	target, err := resolver.Vote(context.Background(), "1", VoteDirectionUp)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	assert.Nil(t, target)
+
+	mockStorage.AssertNotCalled(t, "Vote")
+}
+
+func TestPostViewerVote(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &postResolver{&Resolver{Storage: mockStorage}}
+
+	mockStorage.On("GetVote", testViewer.ID, "1").Return(models.VoteUp, nil)
+
+	vote, err := resolver.ViewerVote(viewerContext(), &Post{ID: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, VoteDirectionUp, *vote)
+
+	// Анонимному запросу голос не возвращается
+	vote, err = resolver.ViewerVote(context.Background(), &Post{ID: "1"})
+	assert.NoError(t, err)
+	assert.Nil(t, vote)
+
+	mockStorage.AssertNumberOfCalls(t, "GetVote", 1)
+}
+
+func TestAddPost_Community(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	communityID := "c1"
+	mockStorage.On("GetCommunityByName", "golang").Return(&models.Community{ID: communityID, Name: "golang"}, nil)
+	mockStorage.On("AddPost", testViewer.ID, &communityID, "Title", "Content", []string(nil), true, models.ModerationVisible).
+		Return(models.Post{ID: "1", CommunityID: &communityID, Title: "Title"}, nil)
+
+	name := "golang"
+	post, err := resolver.AddPost(viewerContext(), "Title", "Content", true, &name, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, communityID, *post.CommunityID)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCommunity_PostsAndMembership(t *testing.T) {
+	resolver := &Resolver{Storage: storage.NewMemoryStorage()}
+	ctx := context.Background()
+
+	creator, err := resolver.Storage.CreateUser(ctx, "alice", "hash")
+	assert.NoError(t, err)
+	member, err := resolver.Storage.CreateUser(ctx, "bob", "hash")
+	assert.NoError(t, err)
+	creatorCtx := auth.WithViewer(ctx, creator, "token")
+	memberCtx := auth.WithViewer(ctx, member, "token")
+
+	community, err := resolver.Mutation().CreateCommunity(creatorCtx, "golang", "Go programming")
+	assert.NoError(t, err)
+	name := "GoLang"
+	_, err = resolver.Mutation().AddPost(creatorCtx, "Title", "Content", true, &name, nil)
+	assert.NoError(t, err)
+
+	joined, err := resolver.Mutation().JoinCommunity(memberCtx, "golang")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, joined.MemberCount)
+	isMember, err := resolver.Community().ViewerIsMember(memberCtx, joined)
+	assert.NoError(t, err)
+	assert.True(t, *isMember)
+
+	conn, err := resolver.Community().Posts(ctx, community, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 1)
+	postCommunity, err := resolver.Post().Community(ctx, conn.Edges[0].Node)
+	assert.NoError(t, err)
+	assert.Equal(t, "golang", postCommunity.Name)
+
+	left, err := resolver.Mutation().LeaveCommunity(memberCtx, "golang")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, left.MemberCount)
+
+	isMember, err = resolver.Community().ViewerIsMember(ctx, left)
+	assert.NoError(t, err)
+	assert.Nil(t, isMember)
+}
+
+func TestHomeFeed_Anonymous(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage, Feed: feed.New(mockStorage)}}
+
+	opts := storage.PostListOptions{Sort: storage.PostSortHot}
+	mockStorage.On("GetAllPosts", opts).Return(&storage.PostPage{Posts: []models.Post{{ID: "1", Title: "Popular"}}}, nil)
+
+	sort := PostSortHot
+	conn, err := resolver.HomeFeed(context.Background(), nil, nil, &sort)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 1)
+	assert.Equal(t, "Popular", conn.Edges[0].Node.Title)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "GetInterests", testViewer.ID)
+}
+
+func TestHomeFeed_Interests(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	resolver := &Resolver{Storage: store, Feed: feed.New(store)}
+	ctx := context.Background()
+
+	viewer, err := store.CreateUser(ctx, "alice", "hash")
+	assert.NoError(t, err)
+	author, err := store.CreateUser(ctx, "bob", "hash")
+	assert.NoError(t, err)
+	viewerCtx := auth.WithViewer(ctx, viewer, "token")
+	authorCtx := auth.WithViewer(ctx, author, "token")
+
+	_, err = resolver.Mutation().AddPost(authorCtx, "Tagged", "Content", true, nil, []string{"GraphQL"})
+	assert.NoError(t, err)
+	_, err = resolver.Mutation().AddPost(authorCtx, "Untagged", "Content", true, nil, nil)
+	assert.NoError(t, err)
+
+	interest, err := resolver.Mutation().FollowTag(viewerCtx, "graphql")
+	assert.NoError(t, err)
+	assert.Equal(t, InterestKindTag, interest.Kind)
+	assert.Equal(t, "graphql", *interest.Tag)
+
+	sort := PostSortNew
+	conn, err := resolver.Query().HomeFeed(viewerCtx, nil, nil, &sort)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 1)
+	assert.Equal(t, "Tagged", conn.Edges[0].Node.Title)
+	assert.Equal(t, []string{"graphql"}, conn.Edges[0].Node.Tags)
+
+	followed, err := resolver.Mutation().FollowAuthor(viewerCtx, author.ID)
+	assert.NoError(t, err)
+	followedAuthor, err := resolver.Interest().Author(ctx, followed)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", followedAuthor.Username)
+
+	conn, err = resolver.Query().HomeFeed(viewerCtx, nil, nil, &sort)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 2)
+
+	interests, err := resolver.Query().Interests(viewerCtx)
+	assert.NoError(t, err)
+	assert.Len(t, interests, 2)
+
+	ok, err := resolver.Mutation().UnfollowTag(viewerCtx, "GraphQL")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	interests, err = resolver.Query().Interests(viewerCtx)
+	assert.NoError(t, err)
+	assert.Len(t, interests, 1)
+	assert.Equal(t, InterestKindAuthor, interests[0].Kind)
+}
+
+func TestInterests_Unauthenticated(t *testing.T) {
+	resolver := &Resolver{Storage: new(storage.MockStorage)}
+
+	_, err := resolver.Query().Interests(context.Background())
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	_, err = resolver.Mutation().FollowTag(context.Background(), "go")
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+}
+
+func TestSearch(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &queryResolver{&Resolver{Storage: mockStorage}}
+
+	mockStorage.On("GetCommunityByName", "golang").Return(&models.Community{ID: "c1", Name: "golang"}, nil)
+	opts := storage.SearchOptions{Query: "generics", Type: storage.SearchComments, CommunityID: "c1", First: 10}
+	mockStorage.On("Search", opts).Return(&storage.SearchPage{
+		Results: []storage.SearchResult{{
+			Comment: &models.Comment{ID: "1", PostID: "p1", Content: "generics are here"},
+			Rank:    0.5,
+			Snippet: "<mark>generics</mark> are here",
+		}},
+		HasNextPage: true,
+	}, nil)
+
+	searchType, community, first := SearchTypeComment, "golang", 10
+	conn, err := resolver.Search(context.Background(), "generics", &searchType, &community, &first, nil)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 1)
+	assert.Equal(t, "<mark>generics</mark> are here", conn.Edges[0].Snippet)
+	assert.Equal(t, 0.5, conn.Edges[0].Rank)
+	comment, ok := conn.Edges[0].Node.(*Comment)
+	assert.True(t, ok)
+	assert.Equal(t, "1", comment.ID)
+	assert.True(t, conn.PageInfo.HasNextPage)
+	assert.Equal(t, conn.Edges[0].Cursor, *conn.PageInfo.EndCursor)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestContentHTML(t *testing.T) {
+	deletedAt := time.Now()
+	comment := toGraphComment(&models.Comment{ID: "1", Content: "*secret*", ContentHTML: "<p><em>secret</em></p>\n", DeletedAt: &deletedAt})
+	assert.Equal(t, "<p>[deleted]</p>\n", comment.ContentHTML)
+
+	// Запись без сохранённого HTML отрисовывается при чтении
+	post := toGraphPost(&models.Post{ID: "1", Content: "[go](https://go.dev)"})
+	assert.Equal(t, "<p><a href=\"https://go.dev\" rel=\"nofollow noopener ugc\">go</a></p>\n", post.ContentHTML)
+}
+
+func TestAddComment_Notifications(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &mutationResolver{&Resolver{Storage: mockStorage}}
+
+	postAuthor, parentAuthor, bob := "u2", "u3", "u4"
+	parentID := "c1"
+	content := "@Bob @carol @ghost @alice см. выше, email@example.com"
+	mockStorage.On("GetPostByID", "p1").Return(&models.Post{ID: "p1", AuthorID: &postAuthor, AllowComments: true}, nil)
+	mockStorage.On("AddComment", testViewer.ID, "p1", &parentID, content, models.ModerationVisible).
+		Return(&models.Comment{ID: "c2", PostID: "p1", ParentID: &parentID, AuthorID: &testViewer.ID, Content: content}, nil)
+	mockStorage.On("GetCommentByID", parentID).Return(&models.Comment{ID: parentID, PostID: "p1", AuthorID: &parentAuthor}, nil)
+	mockStorage.On("GetUserByUsername", "bob").Return(&models.User{ID: bob, Username: "bob"}, nil)
+	// carol - автор родительского комментария: ответ важнее упоминания
+	mockStorage.On("GetUserByUsername", "carol").Return(&models.User{ID: parentAuthor, Username: "carol"}, nil)
+	mockStorage.On("GetUserByUsername", "ghost").Return((*models.User)(nil), storage.ErrUserNotFound)
+	mockStorage.On("GetUserByUsername", "alice").Return(testViewer, nil)
+	for userID, kind := range map[string]models.NotificationKind{
+		parentAuthor: models.NotificationCommentReply,
+		bob:          models.NotificationMention,
+	} {
+		mockStorage.On("CreateNotification", models.Notification{
+			UserID: userID, Kind: kind, ActorID: &testViewer.ID, PostID: "p1", CommentID: "c2",
+		}).Return(&models.Notification{ID: "n-" + userID}, nil).Once()
+	}
+
+	_, err := resolver.AddComment(viewerContext(), "p1", &parentID, content)
+	assert.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNumberOfCalls(t, "CreateNotification", 2)
+}
+
+func TestNotifications(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage}
+
+	readAt := time.Now()
+	actorID := "u2"
+	notifications := []*models.Notification{
+		{ID: "n2", UserID: testViewer.ID, Kind: models.NotificationMention, ActorID: &actorID, PostID: "p1", CommentID: "c2"},
+		{ID: "n1", UserID: testViewer.ID, Kind: models.NotificationPostReply, PostID: "p1", CommentID: "c1", ReadAt: &readAt},
+	}
+	mockStorage.On("GetNotifications", testViewer.ID, storage.NotificationListOptions{First: 2}).
+		Return(&storage.NotificationPage{Notifications: notifications, HasNextPage: true}, nil)
+	mockStorage.On("CountUnreadNotifications", testViewer.ID).Return(1, nil)
+	mockStorage.On("MarkNotificationsRead", testViewer.ID, []string{"n2"}).Return(1, nil)
+	mockStorage.On("GetUserByID", actorID).Return(&models.User{ID: actorID, Username: "bob"}, nil)
+
+	first := 2
+	conn, err := resolver.Query().Notifications(viewerContext(), nil, &first, nil)
+	assert.NoError(t, err)
+	assert.Len(t, conn.Edges, 2)
+	assert.True(t, conn.PageInfo.HasNextPage)
+	assert.Equal(t, NotificationKindMention, conn.Edges[0].Node.Kind)
+	assert.False(t, conn.Edges[0].Node.Read)
+	assert.True(t, conn.Edges[1].Node.Read)
+
+	actor, err := resolver.Notification().Actor(viewerContext(), conn.Edges[0].Node)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", actor.Username)
+
+	count, err := resolver.Query().UnreadNotificationCount(viewerContext())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	marked, err := resolver.Mutation().MarkNotificationsRead(viewerContext(), []string{"n2"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	_, err = resolver.Query().UnreadNotificationCount(context.Background())
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	_, err = resolver.Subscription().NotificationReceived(context.Background())
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestNotificationReceived(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	resolver := &subscriptionResolver{&Resolver{Storage: new(storage.MockStorage), Events: broker}}
+
+	ctx, cancel := context.WithCancel(viewerContext())
+	defer cancel()
+	ch, err := resolver.NotificationReceived(ctx)
+	assert.NoError(t, err)
+
+	publish(t, broker, events.UserTopic(testViewer.ID), events.NotificationCreated, "n1",
+		&models.Notification{ID: "n1", Kind: models.NotificationCommentReply, PostID: "p1", CommentID: "c1"})
+	select {
+	case n := <-ch:
+		assert.Equal(t, "n1", n.ID)
+		assert.Equal(t, NotificationKindCommentReply, n.Kind)
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage}
+	admin := &models.User{ID: "a1", Username: "admin", Role: models.RoleAdmin}
+	adminCtx := auth.WithViewer(context.Background(), admin, "token")
+
+	mockStorage.On("SetUserRole", "u2", models.RoleModerator).
+		Return(&models.User{ID: "u2", Username: "bob", Role: models.RoleModerator}, nil)
+	user, err := resolver.Mutation().SetRole(adminCtx, "u2", RoleModerator)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleModerator, user.Role)
+
+	// Роли назначает только администратор, и не себе
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	_, err = resolver.Mutation().SetRole(auth.WithViewer(context.Background(), moderator, "token"), "u2", RoleAdmin)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = resolver.Mutation().SetRole(adminCtx, admin.ID, RoleUser)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestModeration_RequiresModerator(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage}
+
+	_, err := resolver.Query().ModQueue(viewerContext(), nil, nil)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = resolver.Mutation().Remove(viewerContext(), "c1", "spam")
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = resolver.Mutation().LockComment(context.Background(), "c1", true)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+	// Пожаловаться может любой пользователь
+	mockStorage.On("Report", testViewer.ID, "c1", "spam").Return(nil)
+	reported, err := resolver.Mutation().Report(viewerContext(), "c1", "spam")
+	assert.NoError(t, err)
+	assert.True(t, reported)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRemove_HidesContent(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage}
+
+	authorID := "u2"
+	reason := "spam"
+	removed := &models.Comment{ID: "c1", PostID: "p1", AuthorID: &authorID, Content: "Buy now",
+		ModStatus: models.ModerationRemoved, RemovalReason: &reason}
+	mockStorage.On("Moderate", "c1", models.ModerationRemoved, &reason).
+		Return(&storage.ModerationResult{Target: storage.Target{Comment: removed}, PrevStatus: models.ModerationFlagged}, nil)
+	mockStorage.On("GetModQueue", storage.ModQueueOptions{First: 5}).Return([]*storage.ModQueueItem{
+		{Target: storage.Target{Comment: removed}, ReportCount: 2, Reasons: []string{"spam"}},
+	}, nil)
+
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	ctx := auth.WithViewer(context.Background(), moderator, "token")
+
+	// Модератор видит текст удалённого комментария
+	target, err := resolver.Mutation().Remove(ctx, "c1", reason)
+	assert.NoError(t, err)
+	comment := target.(*Comment)
+	assert.Equal(t, "Buy now", comment.Content)
+	assert.Equal(t, ModerationStatusRemoved, comment.ModerationStatus)
+	assert.Equal(t, &reason, comment.RemovalReason)
+
+	first := 5
+	queue, err := resolver.Query().ModQueue(ctx, nil, &first)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, 2, queue[0].ReportCount)
+	assert.Equal(t, "Buy now", queue[0].Target.(*Comment).Content)
+
+	// Остальные - заглушку без автора
+	public := toGraphComment(removed)
+	assert.Equal(t, models.RemovedPlaceholder, public.Content)
+	assert.Nil(t, public.AuthorID)
+	assert.Equal(t, ModerationStatusVisible, toGraphPost(&models.Post{ID: "p1"}).ModerationStatus)
+
+	// У скрытого поста скрываются заголовок, текст, теги и автор
+	flaggedPost := toGraphPost(&models.Post{ID: "p1", AuthorID: &authorID, Title: "Cheap pills", Content: "Buy now",
+		Tags: []string{"pills"}, ModStatus: models.ModerationFlagged})
+	assert.Equal(t, models.RemovedPlaceholder, flaggedPost.Title)
+	assert.Equal(t, models.RemovedPlaceholder, flaggedPost.Content)
+	assert.Empty(t, flaggedPost.Tags)
+	assert.Nil(t, flaggedPost.AuthorID)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestBanUser_RequiresModerator(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage}
+
+	_, err := resolver.Mutation().BanUser(viewerContext(), "golang", "u2", "spam", nil, false)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = resolver.Query().Bans(viewerContext(), "golang")
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	ctx := auth.WithViewer(context.Background(), moderator, "token")
+
+	days := 0
+	_, err = resolver.Mutation().BanUser(ctx, "golang", "u2", "spam", &days, false)
+	assert.ErrorIs(t, err, storage.ErrInvalidBan)
+
+	days = 7
+	opts := storage.BanOptions{CommunityID: "c1", UserID: "u2", ModeratorID: moderator.ID, Reason: "spam",
+		Duration: 7 * 24 * time.Hour}
+	expiresAt := time.Now().Add(opts.Duration)
+	mockStorage.On("GetCommunityByName", "golang").Return(&models.Community{ID: "c1", Name: "golang"}, nil)
+	mockStorage.On("BanUser", opts).Return(&models.Ban{ID: "b1", CommunityID: "c1", UserID: "u2",
+		ModeratorID: &moderator.ID, Reason: "spam", ExpiresAt: &expiresAt}, nil)
+
+	ban, err := resolver.Mutation().BanUser(ctx, "golang", "u2", "spam", &days, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", ban.CommunityID)
+	assert.Equal(t, &expiresAt, ban.ExpiresAt)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestAppealBan_InModQueue(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage}
+
+	appeal := &models.BanAppeal{ID: "a1", CommunityID: "c1", UserID: testViewer.ID, Message: "Sorry"}
+	mockStorage.On("GetCommunityByName", "golang").Return(&models.Community{ID: "c1", Name: "golang"}, nil)
+	mockStorage.On("AppealBan", "c1", testViewer.ID, "Sorry").Return(appeal, nil)
+	mockStorage.On("GetModQueue", storage.ModQueueOptions{}).Return([]*storage.ModQueueItem{
+		{Appeal: appeal, Reasons: []string{"spam"}},
+	}, nil)
+
+	created, err := resolver.Mutation().AppealBan(viewerContext(), "golang", "Sorry")
+	assert.NoError(t, err)
+	assert.Equal(t, "a1", created.ID)
+
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	queue, err := resolver.Query().ModQueue(auth.WithViewer(context.Background(), moderator, "token"), nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, "Sorry", queue[0].Target.(*BanAppeal).Message)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestAddComment_RejectedByFilter(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage, Filter: filter.New(mockStorage, filter.NewBannedWords([]string{"casino"}))}
+
+	mockStorage.On("GetPostByID", "1").Return(&models.Post{ID: "1", AllowComments: true}, nil)
+	mockStorage.On("RecordFilterDecision", mock.MatchedBy(func(decision models.FilterDecision) bool {
+		return decision.Verdict == models.FilterReject && decision.Filter == "banned_words" && decision.TargetID == nil
+	})).Return(&models.FilterDecision{ID: "d1"}, nil)
+
+	// Отклонённый комментарий не попадает в хранилище
+	_, err := resolver.Mutation().AddComment(viewerContext(), "1", nil, "Best casino bonus")
+	assert.ErrorIs(t, err, filter.ErrRejected)
+	var rejected *filter.RejectedError
+	assert.ErrorAs(t, err, &rejected)
+	assert.Equal(t, "banned_words", rejected.Filter)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "AddComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddPost_FlaggedByFilter(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage, Filter: filter.New(mockStorage, &filter.LinkLimit{Max: 1})}
+
+	text := "https://a.example https://b.example"
+	flagged := models.Post{ID: "p1", Title: "Links", Content: text, AllowComments: true, ModStatus: models.ModerationFlagged}
+	mockStorage.On("AddPost", testViewer.ID, (*string)(nil), "Links", text, []string(nil), true, models.ModerationFlagged).Return(flagged, nil)
+	mockStorage.On("RecordFilterDecision", mock.MatchedBy(func(decision models.FilterDecision) bool {
+		return decision.Verdict == models.FilterFlag && decision.TargetID != nil && *decision.TargetID == "p1"
+	})).Return(&models.FilterDecision{ID: "d1"}, nil)
+
+	// Отмеченный фильтром пост сразу сохраняется скрытым до проверки
+	created, err := resolver.Mutation().AddPost(viewerContext(), "Links", text, true, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationStatusFlagged, created.ModerationStatus)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "Moderate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRemove_TrainsFilter(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	resolver := &Resolver{Storage: mockStorage, Filter: filter.New(mockStorage)}
+
+	reason := "Spam links"
+	removed := &models.Comment{ID: "c1", PostID: "p1", Content: "Buy cheap pills",
+		ModStatus: models.ModerationRemoved, RemovalReason: &reason}
+	mockStorage.On("Moderate", "c1", models.ModerationRemoved, &reason).
+		Return(&storage.ModerationResult{Target: storage.Target{Comment: removed}, PrevStatus: models.ModerationVisible}, nil)
+	mockStorage.On("TrainClassifier", true, []string{"buy", "cheap", "pills"}).Return(nil)
+
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	_, err := resolver.Mutation().Remove(auth.WithViewer(context.Background(), moderator, "token"), "c1", reason)
+	assert.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestModerate_RetrainsFilterOnReversal(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	resolver := &Resolver{Storage: store, Filter: filter.New(store)}
+	author, err := store.CreateUser(context.Background(), "author", "hash")
+	assert.NoError(t, err)
+	post, err := store.AddPost(context.Background(), author.ID, nil, "Post", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	comment, err := store.AddComment(context.Background(), author.ID, post.ID, nil, "Buy cheap pills", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	moderator := &models.User{ID: "m1", Username: "mod", Role: models.RoleModerator}
+	ctx := auth.WithViewer(context.Background(), moderator, "token")
+	tokens := []string{"buy", "cheap", "pills"}
+	assertStats := func(spamDocs, hamDocs int) {
+		t.Helper()
+		stats, err := store.GetClassifierStats(context.Background(), tokens)
+		assert.NoError(t, err)
+		assert.Equal(t, spamDocs, stats.SpamDocs)
+		assert.Equal(t, hamDocs, stats.HamDocs)
+		assert.Equal(t, storage.TokenCounts{Spam: spamDocs, Ham: hamDocs}, stats.Tokens["pills"])
+	}
+
+	_, err = resolver.Mutation().Remove(ctx, comment.ID, "spam")
+	assert.NoError(t, err)
+	assertStats(1, 0)
+
+	// Повторное решение не обучает классификатор ещё раз
+	_, err = resolver.Mutation().Remove(ctx, comment.ID, "Spam again")
+	assert.NoError(t, err)
+	assertStats(1, 0)
+
+	// Отменённое удаление вычитает пример спама и добавляет пример обычного контента
+	_, err = resolver.Mutation().Approve(ctx, comment.ID)
+	assert.NoError(t, err)
+	assertStats(0, 1)
+	_, err = resolver.Mutation().Approve(ctx, comment.ID)
+	assert.NoError(t, err)
+	assertStats(0, 1)
+
+	// Удаление не как спам только отменяет одобрение
+	_, err = resolver.Mutation().Remove(ctx, comment.ID, "off-topic")
+	assert.NoError(t, err)
+	assertStats(0, 0)
+}
+
+func TestThreadSubscriptions(t *testing.T) {
+	mockStorage := new(storage.MockStorage)
+	broker := events.NewMemoryBroker()
+	resolver := &Resolver{Storage: mockStorage, Events: broker}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	name := "golang"
+	mockStorage.On("GetCommunityByName", name).Return(&models.Community{ID: "g1", Name: name}, nil)
+	added, err := resolver.Subscription().PostAdded(ctx, &name)
+	assert.NoError(t, err)
+	postUpdated, err := resolver.Subscription().PostUpdated(ctx, "p1")
+	assert.NoError(t, err)
+	commentUpdated, err := resolver.Subscription().CommentUpdated(ctx, "p1")
+	assert.NoError(t, err)
+	commentDeleted, err := resolver.Subscription().CommentDeleted(ctx, "p1")
+	assert.NoError(t, err)
+	scoreChanged, err := resolver.Subscription().ScoreChanged(ctx, "c1")
+	assert.NoError(t, err)
+
+	post := &models.Post{ID: "p1", Title: "Post", AllowComments: true}
+	publish(t, broker, events.PostsTopic, events.PostAdded, "p0", &models.Post{ID: "p0"})
+	publish(t, broker, events.CommunityTopic("g1"), events.PostAdded, "p1", post)
+	publish(t, broker, events.PostTopic("p1"), events.CommentUpdated, "c1",
+		&models.Comment{ID: "c1", PostID: "p1", Content: "Edited", Locked: true})
+	publish(t, broker, events.PostTopic("p1"), events.CommentDeleted, "c2", &models.Comment{ID: "c2", PostID: "p1"})
+	publish(t, broker, events.PostTopic("p1"), events.PostUpdated, "p1", &models.Post{ID: "p1", AllowComments: false})
+	publish(t, broker, events.ScoreTopic("c1"), events.ScoreChanged, "c1",
+		&storage.Target{Comment: &models.Comment{ID: "c1", PostID: "p1", Upvotes: 3, Downvotes: 1}})
+
+	// Каждая подписка получает только свои события
+	assert.Equal(t, "p1", (<-added).ID)
+	updated := <-commentUpdated
+	assert.Equal(t, "Edited", updated.Content)
+	assert.True(t, updated.Locked)
+	assert.Equal(t, "c2", <-commentDeleted)
+	assert.False(t, (<-postUpdated).AllowComments)
+	assert.Equal(t, 2, (<-scoreChanged).(*Comment).Score)
+	assert.Empty(t, added)
+	assert.Empty(t, commentUpdated)
+
 	mockStorage.AssertExpectations(t)
 }
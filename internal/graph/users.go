@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/storage"
+)
+
+// userByID загружает автора контента. Анонимный или удалённый автор даёт nil
+func (r *Resolver) userByID(ctx context.Context, id *string) (*User, error) {
+	if id == nil {
+		return nil, nil
+	}
+	user, err := r.Storage.GetUserByID(ctx, *id)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Failed to fetch user %s: %v", *id, err)
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
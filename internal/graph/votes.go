@@ -0,0 +1,23 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+)
+
+// viewerVote возвращает голос текущего пользователя за объект, nil - для анонимного запроса
+func (r *Resolver) viewerVote(ctx context.Context, targetID string) (*VoteDirection, error) {
+	viewer := auth.ViewerFromContext(ctx)
+	if viewer == nil {
+		return nil, nil
+	}
+	direction, err := r.Storage.GetVote(ctx, viewer.ID, targetID)
+	if err != nil {
+		log.Printf("Failed to fetch vote of %s for %s: %v", viewer.ID, targetID, err)
+		return nil, err
+	}
+	vote := toGraphVote(direction)
+	return &vote, nil
+}
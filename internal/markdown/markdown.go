@@ -0,0 +1,246 @@
+// Package markdown преобразует текст постов и комментариев в безопасный HTML.
+//
+// Поддерживается подмножество Markdown:
+//
+//   - абзацы, разделённые пустой строкой; перевод строки внутри абзаца - <br>;
+//   - блоки кода между строками ``` (после открывающих кавычек можно указать язык);
+//   - цитаты - строки, начинающиеся с ">", цитаты могут быть вложенными;
+//   - `код`, **жирный**, *курсив* или _курсив_, ~~зачёркнутый~~ текст;
+//   - ссылки [текст](адрес) со схемами http, https и mailto;
+//   - спойлеры >!скрытый текст!<.
+//
+// Остальная разметка выводится как текст. Весь текст экранируется, а HTML
+// порождают только перечисленные конструкции, поэтому результат можно вставлять
+// в страницу без дополнительной очистки.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxQuoteDepth ограничивает вложенность цитат, более глубокие уровни выводятся текстом
+const maxQuoteDepth = 8
+
+// languagePattern - допустимое имя языка блока кода
+var languagePattern = regexp.MustCompile(`^[A-Za-z0-9_+-]{1,32}$`)
+
+// allowedSchemes - схемы адресов, которые становятся ссылками
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Render преобразует Markdown в HTML
+func Render(source string) string {
+	var sb strings.Builder
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	renderBlocks(&sb, lines, 0)
+	return sb.String()
+}
+
+// renderBlocks выводит блоки: код, цитаты и абзацы
+func renderBlocks(sb *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case strings.HasPrefix(line, "```"):
+			// Незакрытый блок кода продолжается до конца текста
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(lines[end], "```") {
+				end++
+			}
+			sb.WriteString("<pre><code")
+			if lang := strings.TrimSpace(line[3:]); languagePattern.MatchString(lang) {
+				sb.WriteString(` class="language-` + lang + `"`)
+			}
+			sb.WriteString(">")
+			sb.WriteString(html.EscapeString(strings.Join(lines[i+1:end], "\n")))
+			sb.WriteString("</code></pre>\n")
+			i = end + 1
+
+		case isQuote(line) && depth < maxQuoteDepth:
+			var quoted []string
+			for ; i < len(lines) && isQuote(lines[i]); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(lines[i], ">"), " "))
+			}
+			sb.WriteString("<blockquote>\n")
+			renderBlocks(sb, quoted, depth+1)
+			sb.WriteString("</blockquote>\n")
+
+		default:
+			// Абзац заканчивается пустой строкой, блоком кода или цитатой
+			var paragraph []string
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if strings.TrimSpace(l) == "" || strings.HasPrefix(l, "```") ||
+					(len(paragraph) > 0 && isQuote(l) && depth < maxQuoteDepth) {
+					break
+				}
+				paragraph = append(paragraph, strings.TrimSpace(l))
+			}
+			sb.WriteString("<p>")
+			for j, l := range paragraph {
+				if j > 0 {
+					sb.WriteString("<br>\n")
+				}
+				renderInline(sb, l, false)
+			}
+			sb.WriteString("</p>\n")
+		}
+	}
+}
+
+// isQuote - строка цитаты; ">!" в начале строки открывает спойлер, а не цитату
+func isQuote(line string) bool {
+	return strings.HasPrefix(line, ">") && !strings.HasPrefix(line, ">!")
+}
+
+// inlineSpans - парные разделители и теги, в которые они превращаются
+var inlineSpans = []struct {
+	open, close string
+	tagOpen     string
+	tagClose    string
+}{
+	{">!", "!<", `<span class="spoiler">`, "</span>"},
+	{"**", "**", "<strong>", "</strong>"},
+	{"~~", "~~", "<del>", "</del>"},
+	{"*", "*", "<em>", "</em>"},
+	{"_", "_", "<em>", "</em>"},
+}
+
+// renderInline выводит строку с оформлением текста. Внутри ссылки
+// другие ссылки не распознаются
+func renderInline(sb *strings.Builder, s string, inLink bool) {
+	var text strings.Builder
+	flush := func() {
+		sb.WriteString(html.EscapeString(text.String()))
+		text.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+
+		// Экранированный знак препинания выводится как есть
+		if rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_~[]()>!<", rune(rest[1])) {
+			text.WriteByte(rest[1])
+			i += 2
+			continue
+		}
+
+		if rest[0] == '`' {
+			if code, n, ok := codeSpan(rest); ok {
+				flush()
+				sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n
+				continue
+			}
+		}
+
+		if rest[0] == '[' && !inLink {
+			if label, href, n, ok := link(rest); ok {
+				flush()
+				sb.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener ugc">`)
+				renderInline(sb, label, true)
+				sb.WriteString("</a>")
+				i += n
+				continue
+			}
+		}
+
+		matched := false
+		for _, span := range inlineSpans {
+			if !strings.HasPrefix(rest, span.open) {
+				continue
+			}
+			inner, n, ok := delimited(s, i, span.open, span.close)
+			if !ok {
+				continue
+			}
+			flush()
+			sb.WriteString(span.tagOpen)
+			renderInline(sb, inner, inLink)
+			sb.WriteString(span.tagClose)
+			i += n
+			matched = true
+			break
+		}
+		if matched {
+			continue
+		}
+
+		text.WriteByte(rest[0])
+		i++
+	}
+	flush()
+}
+
+// codeSpan разбирает `код` в начале строки: содержимое, длину и успех
+func codeSpan(s string) (string, int, bool) {
+	ticks := len(s) - len(strings.TrimLeft(s, "`"))
+	closing := strings.Index(s[ticks:], s[:ticks])
+	if closing <= 0 {
+		return "", 0, false
+	}
+	return strings.TrimSpace(s[ticks : ticks+closing]), ticks + closing + ticks, true
+}
+
+// link разбирает [текст](адрес) в начале строки. Адрес с недопустимой схемой
+// ссылкой не становится
+func link(s string) (label, href string, n int, ok bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel <= 1 {
+		return "", "", 0, false
+	}
+	closeHref := strings.IndexByte(s[closeLabel+2:], ')')
+	if closeHref < 0 {
+		return "", "", 0, false
+	}
+	href, ok = safeURL(s[closeLabel+2 : closeLabel+2+closeHref])
+	if !ok {
+		return "", "", 0, false
+	}
+	return s[1:closeLabel], href, closeLabel + 2 + closeHref + 1, true
+}
+
+// safeURL проверяет адрес ссылки: допускаются только абсолютные адреса
+// с разрешённой схемой
+func safeURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	if u.Scheme != "mailto" && u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// delimited разбирает span, открытый разделителем open на позиции i строки s:
+// содержимое до ближайшего close и длину всей конструкции. Содержимое не может быть
+// пустым или начинаться и заканчиваться пробелом; "_" работает только на границах слов
+func delimited(s string, i int, open, close string) (string, int, bool) {
+	start := i + len(open)
+	end := strings.Index(s[start:], close)
+	if end <= 0 {
+		return "", 0, false
+	}
+	inner := s[start : start+end]
+	if strings.TrimSpace(inner) != inner {
+		return "", 0, false
+	}
+	if open == "_" {
+		after := start + end + len(close)
+		if (i > 0 && isWordByte(s[i-1])) || (after < len(s) && isWordByte(s[after])) {
+			return "", 0, false
+		}
+	}
+	return inner, len(open) + end + len(close), true
+}
+
+// isWordByte - байт входит в слово: буква, цифра или часть многобайтового символа
+func isWordByte(b byte) bool {
+	return b >= 0x80 || b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
@@ -0,0 +1,58 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender_Blocks(t *testing.T) {
+	source := "First line\nsecond line\n\n> quoted\n>> nested\n\n```go\nfmt.Println(\"<hi>\")\n```\nafter"
+
+	assert.Equal(t, "<p>First line<br>\nsecond line</p>\n"+
+		"<blockquote>\n<p>quoted</p>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</blockquote>\n"+
+		"<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>\n"+
+		"<p>after</p>\n", Render(source))
+}
+
+func TestRender_Inline(t *testing.T) {
+	cases := []struct {
+		source string
+		html   string
+	}{
+		{"**bold** and *italic* and _also_", "<p><strong>bold</strong> and <em>italic</em> and <em>also</em></p>\n"},
+		{"~~gone~~ `a < b` snake_case_name", "<p><del>gone</del> <code>a &lt; b</code> snake_case_name</p>\n"},
+		{">!spoiler!< text", "<p><span class=\"spoiler\">spoiler</span> text</p>\n"},
+		{"[**docs**](https://go.dev/doc?a=1&b=2)",
+			"<p><a href=\"https://go.dev/doc?a=1&amp;b=2\" rel=\"nofollow noopener ugc\"><strong>docs</strong></a></p>\n"},
+		{`\*not italic\*`, "<p>*not italic*</p>\n"},
+		{"2 * 3 * 4", "<p>2 * 3 * 4</p>\n"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.html, Render(c.source), c.source)
+	}
+}
+
+func TestRender_Sanitizes(t *testing.T) {
+	cases := []struct {
+		source string
+		html   string
+	}{
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"[click](javascript:alert(1))", "<p>[click](javascript:alert(1))</p>\n"},
+		{"[x](https://a.b/\"onmouseover=\"alert(1))", "<p><a href=\"https://a.b/%22onmouseover=%22alert%281\" rel=\"nofollow noopener ugc\">x</a>)</p>\n"},
+		{"[x](//evil.example)", "<p>[x](//evil.example)</p>\n"},
+		{"```\"><img src=x onerror=alert(1)>\n<b>\n```", "<pre><code>&lt;b&gt;</code></pre>\n"},
+		{"```go onclick=alert(1)\ncode\n```", "<pre><code>code</code></pre>\n"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.html, Render(c.source), c.source)
+	}
+}
+
+func TestRender_Empty(t *testing.T) {
+	assert.Equal(t, "", Render(""))
+	assert.Equal(t, "", Render("\n\n"))
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Модель бана пользователя в сообществе
+type Ban struct {
+	ID          string     `json:"id"`
+	CommunityID string     `json:"communityId"`
+	UserID      string     `json:"userId"`
+	ModeratorID *string    `json:"moderatorId"` // ID модератора (nil, если аккаунт удалён)
+	Reason      string     `json:"reason"`
+	Muted       bool       `json:"muted"` // Бан нельзя обжаловать
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"` // Время окончания (nil у бессрочного бана)
+}
+
+// Expired - закончился ли бан к моменту now
+func (b *Ban) Expired(now time.Time) bool {
+	return b.ExpiresAt != nil && !now.Before(*b.ExpiresAt)
+}
+
+// Модель апелляции на бан
+type BanAppeal struct {
+	ID          string     `json:"id"`
+	CommunityID string     `json:"communityId"`
+	UserID      string     `json:"userId"`
+	Message     string     `json:"message"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ResolvedAt  *time.Time `json:"resolvedAt"` // Время решения модератора (nil у открытых апелляций)
+	Accepted    bool       `json:"accepted"`   // Бан снят по апелляции
+}
@@ -4,9 +4,41 @@ import "time"
 
 // Модель комментария к посту
 type Comment struct {
-	ID        string    `json:"id"`
-	PostID    string    `json:"postId"`   // ID поста, к которому прикреплён комментарий
-	ParentID  *string   `json:"parentId"` // ID родительского комментария (null, если корневой)
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID            string           `json:"id"`
+	PostID        string           `json:"postId"`   // ID поста, к которому прикреплён комментарий
+	ParentID      *string          `json:"parentId"` // ID родительского комментария (null, если корневой)
+	AuthorID      *string          `json:"authorId"` // ID автора (nil у анонимных комментариев, созданных до появления аккаунтов)
+	Content       string           `json:"content"`
+	ContentHTML   string           `json:"contentHtml"` // Content, отрисованный из Markdown; обновляется вместе с Content
+	CreatedAt     time.Time        `json:"createdAt"`
+	EditedAt      *time.Time       `json:"editedAt"`  // Время последней правки (nil, если не правился)
+	Depth         int              `json:"depth"`     // Глубина вложенности (0 - корневой комментарий)
+	Path          string           `json:"-"`         // Материализованный путь от корня треда, задаёт порядок отображения
+	DeletedAt     *time.Time       `json:"deletedAt"` // Время удаления; удалённый комментарий с ответами остаётся в дереве как заглушка
+	Hidden        bool             `json:"-"`         // Скрыт из всех выборок (удалённый комментарий без ответов)
+	Upvotes       int              `json:"upvotes"`   // Денормализованные счётчики голосов
+	Downvotes     int              `json:"downvotes"`
+	ModStatus     ModerationStatus `json:"modStatus"`
+	RemovalReason *string          `json:"removalReason"` // Причина удаления модератором
+	Locked        bool             `json:"locked"`        // Ответы на комментарий и его поддерево запрещены
+}
+
+// Score - рейтинг комментария
+func (c *Comment) Score() int {
+	return c.Upvotes - c.Downvotes
+}
+
+// DeletedPlaceholder - текст, который показывается вместо удалённого комментария
+const DeletedPlaceholder = "[deleted]"
+
+// Deleted - удалён ли комментарий
+func (c *Comment) Deleted() bool {
+	return c.DeletedAt != nil
+}
+
+// Узел дерева комментариев. Дерево отдаётся плоским списком в порядке обхода в глубину
+type CommentNode struct {
+	Comment        *Comment `json:"comment"`
+	Depth          int      `json:"depth"`          // Глубина вложенности (0 - корневой комментарий)
+	HasMoreReplies bool     `json:"hasMoreReplies"` // Есть ответы, не вошедшие в дерево из-за ограничений
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Модель сообщества (аналог сабреддита)
+type Community struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"` // Уникальное без учёта регистра имя, используется в адресах
+	Description string    `json:"description"`
+	CreatorID   *string   `json:"creatorId"` // ID создателя (nil, если аккаунт удалён)
+	CreatedAt   time.Time `json:"createdAt"`
+	MemberCount int       `json:"memberCount"` // Денормализованное число участников
+}
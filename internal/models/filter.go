@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Решение фильтра контента
+type FilterVerdict string
+
+const (
+	FilterAccept FilterVerdict = "accept" // контент публикуется
+	FilterFlag   FilterVerdict = "flag"   // контент публикуется скрытым до проверки модератором
+	FilterReject FilterVerdict = "reject" // контент отклоняется
+)
+
+// Severity - строгость решения: из решений нескольких фильтров действует самое строгое
+func (v FilterVerdict) Severity() int {
+	switch v {
+	case FilterFlag:
+		return 1
+	case FilterReject:
+		return 2
+	}
+	return 0
+}
+
+// Вид проверяемого контента
+type ContentKind string
+
+const (
+	ContentPost    ContentKind = "post"
+	ContentComment ContentKind = "comment"
+)
+
+// Модель записи журнала фильтра контента
+type FilterDecision struct {
+	ID        string        `json:"id"`
+	Kind      ContentKind   `json:"kind"`
+	TargetID  *string       `json:"targetId"` // ID опубликованного поста или комментария (nil у отклонённого)
+	AuthorID  *string       `json:"authorId"` // ID автора (nil, если аккаунт удалён)
+	Content   string        `json:"content"`  // Проверенный текст, у поста - заголовок и текст
+	Verdict   FilterVerdict `json:"verdict"`
+	Filter    string        `json:"filter"` // Имя фильтра, вынесшего решение
+	Reason    string        `json:"reason"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Вид подписки на интерес
+type InterestKind string
+
+const (
+	InterestAuthor InterestKind = "author" // посты автора, Target - ID пользователя
+	InterestTag    InterestKind = "tag"    // посты с тегом, Target - тег
+)
+
+// Модель подписки пользователя на автора или тег для домашней ленты
+type Interest struct {
+	UserID    string       `json:"userId"`
+	Kind      InterestKind `json:"kind"`
+	Target    string       `json:"target"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
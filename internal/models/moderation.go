@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Состояние модерации поста или комментария
+type ModerationStatus string
+
+const (
+	ModerationVisible  ModerationStatus = "visible"  // виден всем, модератор его не проверял
+	ModerationFlagged  ModerationStatus = "flagged"  // скрыт до проверки модератором, например из-за жалоб
+	ModerationApproved ModerationStatus = "approved" // одобрен модератором
+	ModerationRemoved  ModerationStatus = "removed"  // удалён модератором
+)
+
+// Valid - допустимое ли состояние
+func (s ModerationStatus) Valid() bool {
+	switch s {
+	case ModerationVisible, ModerationFlagged, ModerationApproved, ModerationRemoved:
+		return true
+	}
+	return false
+}
+
+// Listed - показывается ли объект в списках постов и комментариев
+func (s ModerationStatus) Listed() bool {
+	return s != ModerationFlagged && s != ModerationRemoved
+}
+
+// RemovedPlaceholder - текст, который показывается вместо скрытого модерацией контента
+const RemovedPlaceholder = "[removed]"
+
+// Модель жалобы на пост или комментарий
+type Report struct {
+	ID         string     `json:"id"`
+	ReporterID string     `json:"reporterId"`
+	TargetID   string     `json:"targetId"` // ID поста или комментария
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt"` // Время решения модератора (nil у открытых жалоб)
+}
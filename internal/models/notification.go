@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Вид уведомления
+type NotificationKind string
+
+const (
+	NotificationPostReply    NotificationKind = "post_reply"    // ответ на пост получателя
+	NotificationCommentReply NotificationKind = "comment_reply" // ответ на комментарий получателя
+	NotificationMention      NotificationKind = "mention"       // упоминание @username в комментарии
+)
+
+// Модель уведомления о новом комментарии
+type Notification struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"userId"` // ID получателя
+	Kind      NotificationKind `json:"kind"`
+	ActorID   *string          `json:"actorId"` // ID автора комментария (nil, если аккаунт удалён)
+	PostID    string           `json:"postId"`
+	CommentID string           `json:"commentId"`
+	CreatedAt time.Time        `json:"createdAt"`
+	ReadAt    *time.Time       `json:"readAt"` // Время прочтения (nil у непрочитанных)
+}
+
+// Read - прочитано ли уведомление
+func (n *Notification) Read() bool {
+	return n.ReadAt != nil
+}
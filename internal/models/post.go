@@ -1,9 +1,27 @@
 package models
 
+import "time"
+
 // Модель поста
 type Post struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	Content       string `json:"content"`
-	AllowComments bool   `json:"allowComments"`
+	ID            string           `json:"id"`
+	AuthorID      *string          `json:"authorId"`    // ID автора (nil у анонимных постов, созданных до появления аккаунтов)
+	CommunityID   *string          `json:"communityId"` // ID сообщества (nil у постов вне сообществ)
+	Title         string           `json:"title"`
+	Content       string           `json:"content"`
+	ContentHTML   string           `json:"contentHtml"` // Content, отрисованный из Markdown; обновляется вместе с Content
+	Tags          []string         `json:"tags"`        // Теги в нижнем регистре без повторов
+	AllowComments bool             `json:"allowComments"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"` // Время последнего изменения любого поля
+	EditedAt      *time.Time       `json:"editedAt"`  // Время последней правки текста автором (nil, если не правился)
+	Upvotes       int              `json:"upvotes"`   // Денормализованные счётчики голосов
+	Downvotes     int              `json:"downvotes"` // для сортировки без агрегации
+	ModStatus     ModerationStatus `json:"modStatus"`
+	RemovalReason *string          `json:"removalReason"` // Причина удаления модератором
+}
+
+// Score - рейтинг поста
+func (p Post) Score() int {
+	return p.Upvotes - p.Downvotes
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Ревизия поста или комментария. Ревизии только добавляются: первая
+// сохраняется при создании, следующие - при каждой правке текста
+type Revision struct {
+	ID        string    `json:"id"`
+	TargetID  string    `json:"targetId"` // ID поста или комментария
+	Version   int       `json:"version"`  // Номер версии, начиная с 1
+	EditorID  *string   `json:"editorId"` // Кто внёс правку (автор или модератор)
+	Title     string    `json:"title"`    // Заголовок версии, у комментариев пустой
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Роль пользователя на сайте
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Valid - допустимая ли роль
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Модель пользователя
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"` // bcrypt-хеш пароля, наружу не отдаётся
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// IsModerator - может ли пользователь модерировать чужой контент
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+// IsAdmin - может ли пользователь назначать роли
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Модель сессии. Хранится только хеш токена, сам токен знает лишь клиент
+type Session struct {
+	TokenHash string    `json:"-"`
+	UserID    string    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Expired - истёк ли срок действия сессии на момент now
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
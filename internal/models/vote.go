@@ -0,0 +1,15 @@
+package models
+
+// VoteDirection - голос пользователя за пост или комментарий
+type VoteDirection int
+
+const (
+	VoteDown VoteDirection = -1
+	VoteNone VoteDirection = 0 // голоса нет или он отозван
+	VoteUp   VoteDirection = 1
+)
+
+// Valid - допустимо ли значение голоса
+func (d VoteDirection) Valid() bool {
+	return d >= VoteDown && d <= VoteUp
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+type contextKey int
+
+const clientIPKey contextKey = iota
+
+// WithClientIP кладёт адрес клиента в контекст запроса
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext возвращает адрес клиента или пустую строку
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// Middleware кладёт адрес клиента в контекст запроса. С TrustProxy адрес
+// берётся из последнего значения X-Forwarded-For - его дописывает наш прокси,
+// а предыдущие значения присылает клиент и может подделать
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), l.clientIP(r))))
+	})
+}
+
+// clientIP определяет адрес клиента HTTP-запроса
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.Config.TrustProxy {
+		// Заголовок может повторяться, прокси дописывает адрес в конец последнего
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			forwarded := values[len(values)-1]
+			if last := strings.TrimSpace(forwarded[strings.LastIndex(forwarded, ",")+1:]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// AroundFields - middleware полей gqlgen: перед вызовом резолвера мутации
+// проверяет лимиты операции для текущего пользователя и адреса клиента
+func (l *Limiter) AroundFields(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" {
+		return next(ctx)
+	}
+
+	viewerID := ""
+	if viewer := auth.ViewerFromContext(ctx); viewer != nil {
+		viewerID = viewer.ID
+	}
+	if err := l.Allow(ctx, fc.Field.Name, viewerID, ClientIPFromContext(ctx)); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PostgresStore хранит корзины в таблице rate_limits, чтобы лимиты
+// были общими для нескольких экземпляров сервера
+type PostgresStore struct {
+	DB        *sql.DB
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+// refilledSQL - число токенов корзины b после пополнения на момент $4
+// при вместимости $2 и скорости $3 токенов в секунду
+const refilledSQL = "LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM ($4::timestamp - b.updated_at))::float8, 0) * $3::float8)"
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	now = now.UTC()
+	capacity, rate := float64(limit.Requests), limit.rate()
+
+	// Пополнение и списание выполняются одним оператором под блокировкой строки;
+	// пустая корзина не обновляется, и оператор не возвращает строк
+	var tokens float64
+	err := s.DB.QueryRowContext(ctx, fmt.Sprintf(`INSERT INTO rate_limits AS b (key, tokens, updated_at, full_at)
+		VALUES ($1, $2::float8 - 1, $4, $4::timestamp + make_interval(secs => 1 / $3::float8))
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = %[1]s - 1,
+			updated_at = GREATEST(b.updated_at, $4),
+			full_at = $4::timestamp + make_interval(secs => ($2::float8 - %[1]s + 1) / $3::float8)
+		WHERE %[1]s >= 1
+		RETURNING tokens`, refilledSQL),
+		key, capacity, rate, now).Scan(&tokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.DB.QueryRowContext(ctx, "SELECT "+refilledSQL+" FROM rate_limits b WHERE key = $1",
+			key, capacity, rate, now).Scan(&tokens)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return secondsToDuration((1 - tokens) / rate), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	s.prune(ctx, now)
+	return 0, nil
+}
+
+func (s *PostgresStore) Refund(ctx context.Context, key string, limit Limit) error {
+	_, err := s.DB.ExecContext(ctx, `UPDATE rate_limits SET
+			tokens = LEAST($2::float8, tokens + 1),
+			full_at = GREATEST(updated_at, full_at - make_interval(secs => 1 / $3::float8))
+		WHERE key = $1`,
+		key, float64(limit.Requests), limit.rate())
+	return err
+}
+
+// prune удаляет наполнившиеся корзины не чаще раза в pruneInterval
+func (s *PostgresStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < pruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM rate_limits WHERE full_at <= $1", now); err != nil {
+		log.Println("Failed to prune rate limits:", err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package ratelimit ограничивает частоту мутаций корзинами токенов: отдельная
+// корзина заводится на пользователя и операцию и на IP-адрес и операцию.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateLimited - лимит запросов исчерпан
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// LimitedError - лимит операции исчерпан; повторить запрос можно через RetryAfter.
+// Совпадает с ErrRateLimited при проверке через errors.Is
+type LimitedError struct {
+	Operation  string
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("%s for %s, retry after %s", ErrRateLimited, e.Operation, e.RetryAfter)
+}
+
+func (e *LimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfterSeconds возвращает подсказку для клиента в целых секундах с округлением вверх
+func (e *LimitedError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Limit - не больше Requests запросов за Per; корзина вмещает Requests токенов
+// и равномерно пополняется за Per. Нулевой Requests снимает ограничение
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unlimited - отсутствует ли ограничение
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// rate - скорость пополнения корзины в токенах в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// Rule - ограничения операции для одного пользователя и для одного IP-адреса.
+// Анонимные запросы ограничиваются только по IP
+type Rule struct {
+	Viewer Limit
+	IP     Limit
+}
+
+// Config - ограничения мутаций. Operations задаёт правила отдельных мутаций
+// по имени поля, остальные мутации ограничиваются правилом Default
+type Config struct {
+	Default    Rule
+	Operations map[string]Rule
+	// TrustProxy - брать адрес клиента из X-Forwarded-For: сервер стоит за одним
+	// обратным прокси, который дописывает адрес клиента в конец заголовка
+	TrustProxy bool
+}
+
+// DefaultConfig - ограничения по умолчанию: публикация и вход строже остальных мутаций
+func DefaultConfig() Config {
+	return Config{
+		Default: Rule{
+			Viewer: Limit{Requests: 60, Per: time.Minute},
+			IP:     Limit{Requests: 120, Per: time.Minute},
+		},
+		Operations: map[string]Rule{
+			"addPost":    {Viewer: Limit{Requests: 5, Per: time.Minute}, IP: Limit{Requests: 20, Per: time.Minute}},
+			"addComment": {Viewer: Limit{Requests: 10, Per: time.Minute}, IP: Limit{Requests: 40, Per: time.Minute}},
+			"report":     {Viewer: Limit{Requests: 10, Per: time.Minute}, IP: Limit{Requests: 40, Per: time.Minute}},
+			"register":   {IP: Limit{Requests: 5, Per: time.Hour}},
+			"login":      {IP: Limit{Requests: 10, Per: time.Minute}},
+		},
+	}
+}
+
+// rule возвращает правило мутации
+func (c Config) rule(operation string) Rule {
+	if rule, ok := c.Operations[operation]; ok {
+		return rule
+	}
+	return c.Default
+}
+
+// ParseConfig применяет к base переопределения из строки вида
+// "addComment.viewer=10/1m,addComment.ip=40/1m,*.ip=200/1m", где "*" - правило
+// по умолчанию, а лимит 0/1m снимает ограничение
+func ParseConfig(base Config, spec string) (Config, error) {
+	config := Config{Default: base.Default, Operations: make(map[string]Rule), TrustProxy: base.TrustProxy}
+	for operation, rule := range base.Operations {
+		config.Operations[operation] = rule
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("invalid rate limit %q: expected operation.scope=requests/period", item)
+		}
+		operation, scope, ok := strings.Cut(key, ".")
+		if !ok || operation == "" {
+			return Config{}, fmt.Errorf("invalid rate limit %q: expected operation.scope", key)
+		}
+		limit, err := parseLimit(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid rate limit %q: %w", item, err)
+		}
+
+		rule := config.Default
+		if operation != "*" {
+			rule = config.rule(operation)
+		}
+		switch scope {
+		case "viewer":
+			rule.Viewer = limit
+		case "ip":
+			rule.IP = limit
+		default:
+			return Config{}, fmt.Errorf("invalid rate limit %q: scope must be viewer or ip", item)
+		}
+		if operation == "*" {
+			config.Default = rule
+		} else {
+			config.Operations[operation] = rule
+		}
+	}
+	return config, nil
+}
+
+// parseLimit разбирает лимит вида "10/1m"
+func parseLimit(value string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, errors.New("expected requests/period")
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid number of requests %q", requests)
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("invalid period %q", period)
+	}
+	return Limit{Requests: n, Per: per}, nil
+}
+
+// Limiter проверяет лимиты мутаций по корзинам в Store
+type Limiter struct {
+	Config Config
+	Store  Store
+	now    func() time.Time
+}
+
+func New(config Config, store Store) *Limiter {
+	return &Limiter{Config: config, Store: store, now: time.Now}
+}
+
+// bucketRef - корзина и её лимит
+type bucketRef struct {
+	key   string
+	limit Limit
+}
+
+// Allow забирает по токену из корзин пользователя (пустой viewerID - анонимный запрос)
+// и IP-адреса для операции. Возвращает *LimitedError, если одна из корзин пуста;
+// тогда токены, уже забранные у других корзин, возвращаются, чтобы отклонённый
+// запрос не расходовал общий лимит адреса. Ошибка хранилища лимитов не блокирует запрос
+func (l *Limiter) Allow(ctx context.Context, operation, viewerID, ip string) error {
+	rule := l.Config.rule(operation)
+	var buckets []bucketRef
+	if viewerID != "" && !rule.Viewer.Unlimited() {
+		buckets = append(buckets, bucketRef{"viewer:" + viewerID + ":" + operation, rule.Viewer})
+	}
+	if ip != "" && !rule.IP.Unlimited() {
+		buckets = append(buckets, bucketRef{"ip:" + ip + ":" + operation, rule.IP})
+	}
+
+	now := l.now()
+	var taken []bucketRef
+	for _, bucket := range buckets {
+		retryAfter, err := l.Store.Take(ctx, bucket.key, bucket.limit, now)
+		if err != nil {
+			log.Printf("Failed to check rate limit %s: %v", bucket.key, err)
+			continue
+		}
+		if retryAfter > 0 {
+			log.Printf("Rate limit %s exceeded for %s", bucket.limit, bucket.key)
+			l.refund(ctx, taken)
+			return &LimitedError{Operation: operation, RetryAfter: retryAfter}
+		}
+		taken = append(taken, bucket)
+	}
+	return nil
+}
+
+// refund возвращает токены корзинам отклонённого запроса
+func (l *Limiter) refund(ctx context.Context, buckets []bucketRef) {
+	for _, bucket := range buckets {
+		if err := l.Store.Refund(ctx, bucket.key, bucket.limit); err != nil {
+			log.Printf("Failed to refund rate limit %s: %v", bucket.key, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/auth"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// testLimiter создаёт ограничитель с управляемыми часами
+func testLimiter(config Config) (*Limiter, *time.Time) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	limiter := New(config, NewMemoryStore())
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestAllow_RefillsBucket(t *testing.T) {
+	limiter, now := testLimiter(Config{Default: Rule{Viewer: Limit{Requests: 2, Per: time.Minute}}})
+	ctx := context.Background()
+
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u1", ""))
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u1", ""))
+
+	err := limiter.Allow(ctx, "addComment", "u1", "")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	var limited *LimitedError
+	assert.ErrorAs(t, err, &limited)
+	assert.Equal(t, 30*time.Second, limited.RetryAfter)
+
+	// Корзины других пользователей и операций независимы
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u2", ""))
+	assert.NoError(t, limiter.Allow(ctx, "addPost", "u1", ""))
+
+	*now = now.Add(30 * time.Second)
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u1", ""))
+	assert.ErrorIs(t, limiter.Allow(ctx, "addComment", "u1", ""), ErrRateLimited)
+}
+
+func TestAllow_LimitsByIP(t *testing.T) {
+	limiter, _ := testLimiter(Config{Default: Rule{
+		Viewer: Limit{Requests: 10, Per: time.Minute},
+		IP:     Limit{Requests: 2, Per: time.Minute},
+	}})
+	ctx := context.Background()
+
+	// Запросы разных пользователей с одного адреса расходуют общую корзину
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u1", "10.0.0.1"))
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "", "10.0.0.1"))
+	assert.ErrorIs(t, limiter.Allow(ctx, "addComment", "u2", "10.0.0.1"), ErrRateLimited)
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u2", "10.0.0.2"))
+}
+
+func TestAllow_RejectedRequestKeepsTokens(t *testing.T) {
+	limiter, _ := testLimiter(Config{Default: Rule{
+		Viewer: Limit{Requests: 1, Per: time.Minute},
+		IP:     Limit{Requests: 2, Per: time.Minute},
+	}})
+	ctx := context.Background()
+
+	// Запросы упёршегося в свой лимит пользователя не расходуют корзину адреса
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u1", "10.0.0.1"))
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, limiter.Allow(ctx, "addComment", "u1", "10.0.0.1"), ErrRateLimited)
+	}
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u2", "10.0.0.1"))
+
+	// Отклонённый по адресу запрос возвращает токен пользователя
+	assert.ErrorIs(t, limiter.Allow(ctx, "addComment", "u3", "10.0.0.1"), ErrRateLimited)
+	assert.NoError(t, limiter.Allow(ctx, "addComment", "u3", "10.0.0.2"))
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(DefaultConfig(), "addComment.viewer=3/1s, *.ip=0/1m,vote.viewer=100/1h")
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 3, Per: time.Second}, config.rule("addComment").Viewer)
+	assert.Equal(t, DefaultConfig().Operations["addComment"].IP, config.rule("addComment").IP)
+	assert.True(t, config.Default.IP.Unlimited())
+	assert.Equal(t, Limit{Requests: 100, Per: time.Hour}, config.rule("vote").Viewer)
+	// Базовая конфигурация не меняется
+	assert.Equal(t, 10, DefaultConfig().Operations["addComment"].Viewer.Requests)
+
+	for _, spec := range []string{"addComment=3/1s", "addComment.user=3/1s", "addComment.viewer=3", "addComment.viewer=x/1s"} {
+		_, err := ParseConfig(DefaultConfig(), spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAroundFields(t *testing.T) {
+	limiter, _ := testLimiter(Config{Default: Rule{Viewer: Limit{Requests: 1, Per: time.Minute}}})
+	viewer := &models.User{ID: "u1", Username: "alice"}
+	resolve := func(ctx context.Context) (interface{}, error) { return "ok", nil }
+	field := func(object, name string) context.Context {
+		ctx := auth.WithViewer(context.Background(), viewer, "token")
+		return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+			Object: object,
+			Field:  graphql.CollectedField{Field: &ast.Field{Name: name}},
+		})
+	}
+
+	result, err := limiter.AroundFields(field("Mutation", "addComment"), resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	_, err = limiter.AroundFields(field("Mutation", "addComment"), resolve)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// Запросы не ограничиваются
+	_, err = limiter.AroundFields(field("Query", "posts"), resolve)
+	assert.NoError(t, err)
+	_, err = limiter.AroundFields(field("Query", "posts"), resolve)
+	assert.NoError(t, err)
+}
+
+func TestMiddleware_ClientIP(t *testing.T) {
+	var ip string
+	handler := func(limiter *Limiter) http.Handler {
+		return limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip = ClientIPFromContext(r.Context())
+		}))
+	}
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.RemoteAddr = "10.0.0.1:54321"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+
+	handler(New(Config{}, NewMemoryStore())).ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "10.0.0.1", ip)
+
+	// Адрес, дописанный прокси, - последний; первые значения присылает клиент
+	handler(New(Config{TrustProxy: true}, NewMemoryStore())).ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "203.0.113.7", ip)
+}
+
+func TestMiddleware_SpoofedForwardedFor(t *testing.T) {
+	limiter, _ := testLimiter(Config{Default: Rule{IP: Limit{Requests: 1, Per: time.Minute}}, TrustProxy: true})
+	var errs []error
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs = append(errs, limiter.Allow(r.Context(), "addComment", "", ClientIPFromContext(r.Context())))
+	}))
+
+	// Подделанное первое значение не даёт новую корзину
+	for _, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/query", nil)
+		req.Header.Add("X-Forwarded-For", spoofed)
+		req.Header.Add("X-Forwarded-For", "192.0.2.9, 203.0.113.7")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrRateLimited)
+}
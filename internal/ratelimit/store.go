@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store хранит состояние корзин. Take пополняет корзину key по лимиту на момент now
+// и забирает из неё токен. Если токена нет, корзина не меняется, а Take возвращает
+// положительное время до появления токена. Refund возвращает в корзину токен,
+// забранный Take, не переполняя её
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error)
+	Refund(ctx context.Context, key string, limit Limit) error
+}
+
+// bucket - корзина токенов
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // когда корзина наполнится, после этого её можно забыть
+}
+
+// take пополняет корзину на момент now и забирает токен; возвращает время
+// до появления токена, если корзина пуста
+func (b *bucket) take(limit Limit, now time.Time) time.Duration {
+	rate := limit.rate()
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+elapsed*rate)
+		b.updated = now
+	}
+	if b.tokens < 1 {
+		return secondsToDuration((1 - b.tokens) / rate)
+	}
+	b.tokens--
+	b.full = now.Add(secondsToDuration((float64(limit.Requests) - b.tokens) / rate))
+	return 0
+}
+
+// refund возвращает токен в корзину
+func (b *bucket) refund(limit Limit) {
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+1)
+	b.full = b.full.Add(-secondsToDuration(1 / limit.rate()))
+}
+
+// secondsToDuration переводит секунды в длительность с округлением вверх до миллисекунды
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds*1000)) * time.Millisecond
+}
+
+// pruneInterval - как часто хранилища удаляют наполнившиеся корзины
+const pruneInterval = time.Minute
+
+// MemoryStore хранит корзины в памяти процесса - подходит для одного экземпляра сервера
+type MemoryStore struct {
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+	retryAfter := b.take(limit, now)
+	s.prune(now)
+	return retryAfter, nil
+}
+
+func (s *MemoryStore) Refund(ctx context.Context, key string, limit Limit) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b, exists := s.buckets[key]; exists {
+		b.refund(limit)
+	}
+	return nil
+}
+
+// prune удаляет наполнившиеся корзины не чаще раза в pruneInterval.
+// Вызывается под блокировкой
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// Ограничения размера страницы
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor - позиция в keyset-пагинации. Клиенту отдаётся в виде непрозрачной строки.
+// Кроме ID хранит исходные значения, из которых вычисляется ключ сортировки,
+// поэтому один курсор подходит для любого порядка.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Upvotes   int       `json:"u,omitempty"`
+	Downvotes int       `json:"d,omitempty"`
+	Rank      float64   `json:"r,omitempty"` // релевантность результата поиска
+}
+
+// EncodeCursor кодирует курсор в строку base64
+func EncodeCursor(c Cursor) string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		// Cursor состоит только из сериализуемых полей
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает строку курсора, полученную от клиента
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// after сообщает, идёт ли позиция (createdAt, id) строго после курсора
+func (c Cursor) after(createdAt time.Time, id string) bool {
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.After(c.CreatedAt)
+	}
+	return id > c.ID
+}
+
+// PageSize приводит запрошенный размер страницы к допустимому диапазону
+func PageSize(first int) int {
+	if first <= 0 {
+		return DefaultPageSize
+	}
+	if first > MaxPageSize {
+		return MaxPageSize
+	}
+	return first
+}
+
+// CommentCursor возвращает курсор, указывающий на комментарий
+func CommentCursor(c *models.Comment) string {
+	return EncodeCursor(Cursor{CreatedAt: c.CreatedAt, ID: c.ID, Upvotes: c.Upvotes, Downvotes: c.Downvotes})
+}
+
+// PostCursor возвращает курсор, указывающий на пост
+func PostCursor(p *models.Post) string {
+	return EncodeCursor(Cursor{CreatedAt: p.CreatedAt, ID: p.ID, Upvotes: p.Upvotes, Downvotes: p.Downvotes})
+}
+
+// post восстанавливает из курсора значения, участвующие в сортировке постов
+func (c Cursor) post() *models.Post {
+	return &models.Post{ID: c.ID, CreatedAt: c.CreatedAt, Upvotes: c.Upvotes, Downvotes: c.Downvotes}
+}
+
+// comment восстанавливает из курсора значения, участвующие в сортировке комментариев
+func (c Cursor) comment() *models.Comment {
+	return &models.Comment{ID: c.ID, CreatedAt: c.CreatedAt, Upvotes: c.Upvotes, Downvotes: c.Downvotes}
+}
+
+// NotificationCursor возвращает курсор, указывающий на уведомление
+func NotificationCursor(n *models.Notification) string {
+	return EncodeCursor(Cursor{CreatedAt: n.CreatedAt, ID: n.ID})
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// MaxCommentLength - максимальная длина комментария в символах
+const MaxCommentLength = 2000
+
+// MaxPostContentLength - максимальная длина текста поста в символах. Текст
+// отрисовывается в HTML при каждой правке, поэтому его длина тоже ограничена
+const MaxPostContentLength = 40000
+
+// Ограничения сообщества
+const (
+	MinCommunityNameLength        = 3
+	MaxCommunityNameLength        = 21
+	MaxCommunityDescriptionLength = 500
+)
+
+// Ограничения текстов модерации в символах
+const (
+	MaxReasonLength = 200  // причина жалобы, удаления или бана
+	MaxAppealLength = 1000 // текст апелляции
+)
+
+// MaxBanDuration - наибольший срок временного бана
+const MaxBanDuration = 365 * 24 * time.Hour
+
+// Ограничения тегов поста
+const (
+	MaxPostTags  = 5
+	MaxTagLength = 32
+)
+
+var tagPattern = regexp.MustCompile(fmt.Sprintf(`^[a-z0-9_-]{1,%d}$`, MaxTagLength))
+
+var communityNamePattern = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9_]{%d,%d}$`,
+	MinCommunityNameLength, MaxCommunityNameLength))
+
+// Доменные ошибки хранилища. Оба бэкенда возвращают одни и те же значения,
+// поэтому их можно проверять через errors.Is независимо от типа хранилища.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrPostNotFound      = fmt.Errorf("post %w", ErrNotFound)
+	ErrCommentNotFound   = fmt.Errorf("comment %w", ErrNotFound)
+	ErrUserNotFound      = fmt.Errorf("user %w", ErrNotFound)
+	ErrSessionNotFound   = fmt.Errorf("session %w", ErrNotFound)
+	ErrTargetNotFound    = fmt.Errorf("post or comment %w", ErrNotFound)
+	ErrCommunityNotFound = fmt.Errorf("community %w", ErrNotFound)
+	ErrBanNotFound       = fmt.Errorf("ban %w", ErrNotFound)
+	ErrAppealNotFound    = fmt.Errorf("ban appeal %w", ErrNotFound)
+	ErrCommentsDisabled  = errors.New("comments are disabled for this post")
+	ErrCommentDeleted    = errors.New("comment has been deleted")
+	ErrContentTooLong    = errors.New("content is too long")
+	ErrInvalidID         = errors.New("invalid id")
+	ErrInvalidParent     = errors.New("parent comment belongs to another post")
+	ErrInvalidVote       = errors.New("invalid vote direction")
+	ErrInvalidTag        = fmt.Errorf("invalid tag: must be 1-%d letters, digits, hyphens or underscores", MaxTagLength)
+	ErrTooManyTags       = fmt.Errorf("too many tags: at most %d per post", MaxPostTags)
+	ErrInvalidInterest   = errors.New("invalid interest kind")
+	ErrCommentLocked     = errors.New("comment thread is locked")
+	ErrEmptyReason       = errors.New("reason must not be empty")
+	ErrBanned            = errors.New("user is banned from community")
+	ErrInvalidBan        = fmt.Errorf("invalid ban duration: must be positive and at most %d days", MaxBanDuration/(24*time.Hour))
+	ErrAppealsMuted      = errors.New("ban appeals are muted")
+	ErrInvalidModeration = errors.New("invalid moderation status")
+	ErrInvalidRole       = errors.New("invalid user role")
+	ErrInvalidCommunity  = fmt.Errorf("invalid community name: must be %d-%d letters, digits or underscores",
+		MinCommunityNameLength, MaxCommunityNameLength)
+	ErrConflict       = errors.New("conflict")
+	ErrUsernameTaken  = fmt.Errorf("%w: username is already taken", ErrConflict)
+	ErrCommunityTaken = fmt.Errorf("%w: community name is already taken", ErrConflict)
+	ErrAppealPending  = fmt.Errorf("%w: ban appeal is already pending", ErrConflict)
+	ErrAppealResolved = fmt.Errorf("%w: ban appeal is already resolved", ErrConflict)
+	ErrInternal       = errors.New("internal storage error")
+)
+
+// Коды ошибок PostgreSQL, которые переводятся в доменные ошибки
+const (
+	pgInvalidTextRepresentation = "22P02"
+	pgStringDataRightTruncation = "22001"
+	pgForeignKeyViolation       = "23503"
+	pgUniqueViolation           = "23505"
+)
+
+// foreignKeyErrors - ошибка нарушения внешнего ключа по ссылающейся колонке:
+// ссылка на несуществующий объект означает, что этот объект не найден
+var foreignKeyErrors = map[string]error{
+	"post_id":      ErrPostNotFound,
+	"comment_id":   ErrCommentNotFound,
+	"parent_id":    ErrCommentNotFound,
+	"community_id": ErrCommunityNotFound,
+	"user_id":      ErrUserNotFound,
+	"author_id":    ErrUserNotFound,
+	"editor_id":    ErrUserNotFound,
+	"edited_by":    ErrUserNotFound,
+	"actor_id":     ErrUserNotFound,
+	"creator_id":   ErrUserNotFound,
+	"reporter_id":  ErrUserNotFound,
+	"moderator_id": ErrUserNotFound,
+}
+
+// constraintErrors - ошибки нарушения ограничений схемы по имени ограничения.
+// Остальные ограничения проверяются до запроса, их нарушение - внутренняя ошибка
+var constraintErrors = map[string]error{
+	"users_username_idx":            ErrUsernameTaken,
+	"users_role_check":              ErrInvalidRole,
+	"communities_name_idx":          ErrCommunityTaken,
+	"communities_name_check":        ErrInvalidCommunity,
+	"communities_description_check": ErrContentTooLong,
+	"ban_appeals_open_idx":          ErrAppealPending,
+	"ban_appeals_message_check":     ErrContentTooLong,
+	"bans_reason_check":             ErrContentTooLong,
+	"reports_reason_check":          ErrContentTooLong,
+	"posts_content_length":          ErrContentTooLong,
+	"posts_tags_check":              ErrTooManyTags,
+	"posts_mod_status_check":        ErrInvalidModeration,
+	"posts_removal_reason_check":    ErrContentTooLong,
+	"comments_content_check":        ErrContentTooLong,
+	"comments_mod_status_check":     ErrInvalidModeration,
+	"comments_removal_reason_check": ErrContentTooLong,
+	"votes_value_check":             ErrInvalidVote,
+	"interests_kind_check":          ErrInvalidInterest,
+}
+
+// validateID проверяет, что идентификатор является UUID
+func validateID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	return nil
+}
+
+// validateIDs проверяет список идентификаторов
+func validateIDs(ids []string) error {
+	for _, id := range ids {
+		if err := validateID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateContent проверяет длину текста комментария
+func validateContent(content string) error {
+	if utf8.RuneCountInString(content) > MaxCommentLength {
+		return ErrContentTooLong
+	}
+	return nil
+}
+
+// validatePostContent проверяет длину текста поста
+func validatePostContent(content string) error {
+	if utf8.RuneCountInString(content) > MaxPostContentLength {
+		return ErrContentTooLong
+	}
+	return nil
+}
+
+// normalizeText обрезает пробелы вокруг текста модерации и проверяет, что он
+// не пустой и не длиннее maxLength символов
+func normalizeText(text string, maxLength int) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrEmptyReason
+	}
+	if utf8.RuneCountInString(text) > maxLength {
+		return "", ErrContentTooLong
+	}
+	return text, nil
+}
+
+// normalizeReason проверяет причину жалобы, удаления или бана
+func normalizeReason(reason string) (string, error) {
+	return normalizeText(reason, MaxReasonLength)
+}
+
+// BannedError - пользователь забанен в сообществе. Совпадает с ErrBanned
+// при проверке через errors.Is
+type BannedError struct {
+	CommunityID string
+	Reason      string
+	ExpiresAt   *time.Time // nil у бессрочного бана
+}
+
+func (e *BannedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrBanned, e.Reason)
+}
+
+func (e *BannedError) Is(target error) bool {
+	return target == ErrBanned
+}
+
+// bannedError возвращает ошибку о бане пользователя
+func bannedError(ban *models.Ban) error {
+	return &BannedError{CommunityID: ban.CommunityID, Reason: ban.Reason, ExpiresAt: ban.ExpiresAt}
+}
+
+// normalizeTag приводит тег к нижнему регистру и проверяет его
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+	return tag, nil
+}
+
+// normalizeTags приводит теги поста к нижнему регистру и убирает повторы, сохраняя порядок
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > MaxPostTags {
+		return nil, ErrTooManyTags
+	}
+	return normalized, nil
+}
+
+// validateCommunity проверяет имя и длину описания сообщества
+func validateCommunity(name, description string) error {
+	if !communityNamePattern.MatchString(name) {
+		return ErrInvalidCommunity
+	}
+	if utf8.RuneCountInString(description) > MaxCommunityDescriptionLength {
+		return ErrContentTooLong
+	}
+	return nil
+}
+
+// mapPostgresError переводит ошибки драйвера в доменные ошибки. Нарушение
+// ограничения переводится по его имени, нарушение внешнего ключа - по ссылающейся
+// колонке. notFound возвращается вместо sql.ErrNoRows и при нарушении неизвестного
+// внешнего ключа; неизвестные ошибки логируются и скрываются за ErrInternal,
+// чтобы не утекать клиенту.
+func mapPostgresError(err, notFound error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if mapped, ok := constraintErrors[pqErr.Constraint]; ok {
+			return mapped
+		}
+		switch pqErr.Code {
+		case pgInvalidTextRepresentation:
+			return ErrInvalidID
+		case pgForeignKeyViolation:
+			column := strings.TrimSuffix(strings.TrimPrefix(pqErr.Constraint, pqErr.Table+"_"), "_fkey")
+			if mapped, ok := foreignKeyErrors[column]; ok {
+				return mapped
+			}
+			return notFound
+		case pgUniqueViolation:
+			return ErrConflict
+		case pgStringDataRightTruncation:
+			return ErrContentTooLong
+		}
+	}
+
+	log.Println("Unexpected database error:", err)
+	return ErrInternal
+}
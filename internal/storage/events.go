@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// publish публикует событие об объекте data в тему. Ошибка только логируется:
+// изменение уже сохранено, а доставка подписчикам - лучшее усилие
+func publish(ctx context.Context, broker events.Broker, topic, eventType, id string, data interface{}) {
+	if broker == nil {
+		return
+	}
+	event, err := events.NewEvent(eventType, id, data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", eventType, err)
+		return
+	}
+	if err := broker.Publish(ctx, topic, event); err != nil {
+		log.Printf("Failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// publishPostAdded публикует новый пост в общую тему и тему его сообщества
+func publishPostAdded(ctx context.Context, broker events.Broker, post *models.Post) {
+	publish(ctx, broker, events.PostsTopic, events.PostAdded, post.ID, post)
+	if post.CommunityID != nil {
+		publish(ctx, broker, events.CommunityTopic(*post.CommunityID), events.PostAdded, post.ID, post)
+	}
+}
+
+// publishComment публикует событие комментария в тему его поста
+func publishComment(ctx context.Context, broker events.Broker, eventType string, comment *models.Comment) {
+	publish(ctx, broker, events.PostTopic(comment.PostID), eventType, comment.ID, comment)
+}
+
+// publishTarget публикует изменение поста или комментария, например решение модератора
+func publishTarget(ctx context.Context, broker events.Broker, target *Target) {
+	if target.Post != nil {
+		publish(ctx, broker, events.PostTopic(target.Post.ID), events.PostUpdated, target.Post.ID, target.Post)
+		return
+	}
+	publishComment(ctx, broker, events.CommentUpdated, target.Comment)
+}
+
+// publishScore публикует новые счётчики голосов поста или комментария
+func publishScore(ctx context.Context, broker events.Broker, target *Target) {
+	id := target.ID()
+	publish(ctx, broker, events.ScoreTopic(id), events.ScoreChanged, id, target)
+}
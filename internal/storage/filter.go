@@ -0,0 +1,15 @@
+package storage
+
+// ClassifierStats - статистика обучения классификатора спама: число обучающих
+// текстов каждого класса и в скольких из них встречалось каждое слово
+type ClassifierStats struct {
+	SpamDocs int
+	HamDocs  int
+	Tokens   map[string]TokenCounts
+}
+
+// TokenCounts - в скольких спамных и обычных обучающих текстах встречалось слово
+type TokenCounts struct {
+	Spam int
+	Ham  int
+}
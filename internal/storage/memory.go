@@ -1,11 +1,14 @@
 package storage
 
 import (
-	"errors"
+	"context"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/markdown"
 	"github.com/MosinFAM/graphql-posts/internal/models"
 
 	"github.com/google/uuid"
@@ -13,162 +16,650 @@ import (
 
 // MemoryStorage - хранилище в памяти
 type MemoryStorage struct {
-	posts         map[string]models.Post
-	comments      map[string][]models.Comment
-	subscriptions map[string][]chan *models.Comment
-	mu            sync.RWMutex
+	posts           map[string]models.Post
+	comments        map[string][]*models.Comment // комментарии поста, упорядоченные по (CreatedAt, ID)
+	commentsByID    map[string]*models.Comment
+	roots           map[string][]*models.Comment               // корневые комментарии поста
+	replies         map[string][]*models.Comment               // индекс родитель → дочерние комментарии
+	revisions       map[string][]*models.Revision              // ID поста или комментария → ревизии по возрастанию версии
+	votes           map[string]map[string]models.VoteDirection // ID поста или комментария → ID пользователя → голос
+	communities     map[string]models.Community
+	communityNames  map[string]string                 // имя сообщества в нижнем регистре → ID
+	members         map[string]map[string]bool        // ID сообщества → ID участников
+	interests       map[string][]*models.Interest     // ID пользователя → подписки в порядке создания
+	notifications   map[string][]*models.Notification // ID получателя → уведомления в порядке создания
+	reports         map[string][]*models.Report       // ID поста или комментария → жалобы в порядке создания
+	bans            map[string]map[string]*models.Ban // ID сообщества → ID пользователя → бан
+	appeals         map[string]*models.BanAppeal      // ID апелляции → апелляция
+	classifier      ClassifierStats
+	filterDecisions []*models.FilterDecision // журнал фильтра контента в порядке создания
+	postIndex       *searchIndex
+	commentIndex    *searchIndex
+	users           map[string]models.User
+	usernames       map[string]string         // имя пользователя в нижнем регистре → ID
+	sessions        map[string]models.Session // хеш токена → сессия
+	lastTime        time.Time                 // последняя выданная метка времени, см. now
+	Events          events.Broker             // nil - события не публикуются
+	mu              sync.RWMutex
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		posts:         make(map[string]models.Post),
-		comments:      make(map[string][]models.Comment),
-		subscriptions: make(map[string][]chan *models.Comment),
+		posts:          make(map[string]models.Post),
+		comments:       make(map[string][]*models.Comment),
+		commentsByID:   make(map[string]*models.Comment),
+		roots:          make(map[string][]*models.Comment),
+		replies:        make(map[string][]*models.Comment),
+		revisions:      make(map[string][]*models.Revision),
+		votes:          make(map[string]map[string]models.VoteDirection),
+		communities:    make(map[string]models.Community),
+		communityNames: make(map[string]string),
+		members:        make(map[string]map[string]bool),
+		interests:      make(map[string][]*models.Interest),
+		notifications:  make(map[string][]*models.Notification),
+		reports:        make(map[string][]*models.Report),
+		bans:           make(map[string]map[string]*models.Ban),
+		appeals:        make(map[string]*models.BanAppeal),
+		classifier:     ClassifierStats{Tokens: make(map[string]TokenCounts)},
+		postIndex:      newSearchIndex(),
+		commentIndex:   newSearchIndex(),
+		users:          make(map[string]models.User),
+		usernames:      make(map[string]string),
+		sessions:       make(map[string]models.Session),
 	}
 }
 
-func (s *MemoryStorage) GetAllPosts() ([]models.Post, error) {
+func (s *MemoryStorage) GetAllPosts(ctx context.Context, opts PostListOptions) (*PostPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	log.Println("Fetching all posts from memory")
-	if len(s.posts) == 0 {
-		log.Println("No posts found")
-		return nil, errors.New("no posts found")
+
+	var cursor *models.Post
+	if opts.After != "" {
+		c, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c.post()
+	}
+
+	sortOrder := opts.SortOrder()
+	since, limited := opts.TimeRange.Since(time.Now())
+
+	// Отбираем посты за период, идущие после курсора, и сортируем их
+	posts := make([]*models.Post, 0, len(s.posts))
+	for id := range s.posts {
+		post := s.posts[id]
+		if limited && post.CreatedAt.Before(since) {
+			continue
+		}
+		if !post.ModStatus.Listed() || !opts.matches(&post) {
+			continue
+		}
+		if cursor != nil && !postRankLess(sortOrder, cursor, &post) {
+			continue
+		}
+		posts = append(posts, &post)
 	}
+	sort.Slice(posts, func(i, j int) bool {
+		return postRankLess(sortOrder, posts[i], posts[j])
+	})
 
-	result := make([]models.Post, 0, len(s.posts))
-	for _, post := range s.posts {
-		result = append(result, post)
+	limit := PageSize(opts.First)
+	page := &PostPage{Posts: make([]models.Post, 0, min(limit, len(posts)))}
+	for i, post := range posts {
+		if i == limit {
+			page.HasNextPage = true
+			break
+		}
+		page.Posts = append(page.Posts, *post)
 	}
 
 	log.Println("Successfully fetched posts")
-	return result, nil
+	return page, nil
 }
 
-func (s *MemoryStorage) GetPostByID(id string) (*models.Post, error) {
+func (s *MemoryStorage) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	log.Printf("Fetching post with ID: %s", id)
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
 	post, exists := s.posts[id]
 	if !exists {
 		log.Println("Post not found")
-		return nil, errors.New("post not found")
+		return nil, ErrPostNotFound
 	}
 	return &post, nil
 }
 
-func (s *MemoryStorage) AddPost(title, content string, allowComments bool) (models.Post, error) {
+func (s *MemoryStorage) AddPost(ctx context.Context, authorID string, communityID *string, title, content string, tags []string,
+	allowComments bool, status models.ModerationStatus) (models.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Post{}, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.checkAuthor(authorID); err != nil {
+		return models.Post{}, err
+	}
+	if err := checkInitialStatus(status); err != nil {
+		return models.Post{}, err
+	}
+	if err := validatePostContent(content); err != nil {
+		return models.Post{}, err
+	}
+	if communityID != nil {
+		if err := validateID(*communityID); err != nil {
+			return models.Post{}, err
+		}
+		if _, exists := s.communities[*communityID]; !exists {
+			return models.Post{}, ErrCommunityNotFound
+		}
+		if ban := s.activeBan(*communityID, authorID); ban != nil {
+			return models.Post{}, bannedError(ban)
+		}
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return models.Post{}, err
+	}
+
 	post := models.Post{
 		ID:            uuid.New().String(),
+		AuthorID:      &authorID,
+		CommunityID:   communityID,
 		Title:         title,
 		Content:       content,
+		ContentHTML:   markdown.Render(content),
+		Tags:          tags,
 		AllowComments: allowComments,
+		CreatedAt:     s.now(),
+		ModStatus:     status,
 	}
+	post.UpdatedAt = post.CreatedAt
 	log.Printf("Adding new post: %+v", post)
 	s.posts[post.ID] = post
+	s.indexPost(&post)
+	s.addRevision(post.ID, authorID, post.Title, post.Content, post.CreatedAt)
+	publishPostAdded(ctx, s.Events, &post)
 	return post, nil
 }
 
-func (s *MemoryStorage) AddComment(postID string, parentID *string, content string) (*models.Comment, error) {
+func (s *MemoryStorage) UpdatePost(ctx context.Context, editorID, id string, update PostUpdate) (*models.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Updating post %s", id)
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	if update.Content != nil {
+		if err := validatePostContent(*update.Content); err != nil {
+			return nil, err
+		}
+	}
+	post, exists := s.posts[id]
+	if !exists {
+		log.Println("Post not found")
+		return nil, ErrPostNotFound
+	}
+
+	now := s.now()
+	edited := update.edits(&post)
+	if edited {
+		post.EditedAt = &now
+	}
+	if update.Title != nil {
+		post.Title = *update.Title
+	}
+	if update.Content != nil && *update.Content != post.Content {
+		post.Content = *update.Content
+		post.ContentHTML = markdown.Render(post.Content)
+	}
+	if update.AllowComments != nil {
+		post.AllowComments = *update.AllowComments
+	}
+	post.UpdatedAt = now
+	s.posts[id] = post
+	if edited {
+		s.indexPost(&post)
+		s.addRevision(id, editorID, post.Title, post.Content, now)
+	}
+
+	publish(ctx, s.Events, events.PostTopic(id), events.PostUpdated, id, post)
+
+	return &post, nil
+}
+
+func (s *MemoryStorage) AddComment(ctx context.Context, authorID, postID string, parentID *string, content string,
+	status models.ModerationStatus) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	log.Printf("Adding comment to post %s", postID)
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if err := validateID(*parentID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkAuthor(authorID); err != nil {
+		return nil, err
+	}
+	if err := checkInitialStatus(status); err != nil {
+		return nil, err
+	}
 	post, exists := s.posts[postID]
 	if !exists {
 		log.Println("Post not found")
-		return nil, errors.New("post not found")
+		return nil, ErrPostNotFound
+	}
+	if post.CommunityID != nil {
+		if ban := s.activeBan(*post.CommunityID, authorID); ban != nil {
+			return nil, bannedError(ban)
+		}
 	}
 	if !post.AllowComments {
-		return nil, errors.New("comments are disabled for this post")
+		return nil, ErrCommentsDisabled
 	}
-	if len(content) > 2000 {
-		return nil, errors.New("comment is too long")
+	if err := validateContent(content); err != nil {
+		return nil, err
 	}
 
-	comment := models.Comment{
-		ID:        uuid.New().String(),
-		PostID:    postID,
-		ParentID:  nil,
-		Content:   content,
-		CreatedAt: time.Now(),
-	}
+	// Родитель должен существовать и относиться к тому же посту
+	parentPath := ""
+	depth := 0
 	if parentID != nil {
-		comment.ParentID = parentID
-	}
-
-	s.comments[postID] = append(s.comments[postID], comment)
-
-	log.Println("Notificating...")
-	// Уведомляем подписчиков
-	go func() {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		if subscribers, ok := s.subscriptions[postID]; ok {
-			for i := 0; i < len(subscribers); {
-				select {
-				case subscribers[i] <- &comment:
-					i++
-				default: // Если канал закрыт или клиент отключился — удаляем подписку
-					subscribers = append(subscribers[:i], subscribers[i+1:]...)
-				}
-			}
-			s.subscriptions[postID] = subscribers
+		parent, exists := s.commentsByID[*parentID]
+		if !exists || parent.Hidden {
+			return nil, ErrCommentNotFound
+		}
+		if parent.PostID != postID {
+			return nil, ErrInvalidParent
+		}
+		if parent.Deleted() {
+			return nil, ErrCommentDeleted
 		}
-	}()
+		if s.threadLocked(parent) {
+			return nil, ErrCommentLocked
+		}
+		parentPath = parent.Path
+		depth = parent.Depth + 1
+	}
+
+	comment := models.Comment{
+		ID:          uuid.New().String(),
+		PostID:      postID,
+		ParentID:    parentID,
+		AuthorID:    &authorID,
+		Content:     content,
+		ContentHTML: markdown.Render(content),
+		CreatedAt:   s.now(),
+		Depth:       depth,
+		ModStatus:   status,
+	}
+	comment.Path = commentPath(parentPath, comment.CreatedAt, comment.ID)
+
+	stored := comment
+	s.insertComment(&stored)
+	s.indexComment(&stored)
+	s.addRevision(comment.ID, authorID, "", comment.Content, comment.CreatedAt)
+
+	publishComment(ctx, s.Events, events.CommentAdded, &comment)
 
 	log.Printf("Comment added: %+v", comment)
 	return &comment, nil
 }
 
-func (s *MemoryStorage) GetCommentsByPostID(postID string, limit, offset int) ([]*models.Comment, error) {
+func (s *MemoryStorage) GetCommentByID(ctx context.Context, id string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	comment, exists := s.commentsByID[id]
+	if !exists || comment.Hidden {
+		return nil, ErrCommentNotFound
+	}
+	c := *comment
+	return &c, nil
+}
+
+func (s *MemoryStorage) UpdateComment(ctx context.Context, editorID, id, content string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Updating comment %s", id)
+	comment, err := s.liveComment(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateContent(content); err != nil {
+		return nil, err
+	}
+
+	if content != comment.Content {
+		now := s.now()
+		comment.Content = content
+		comment.ContentHTML = markdown.Render(content)
+		comment.EditedAt = &now
+		s.indexComment(comment)
+		s.addRevision(id, editorID, "", content, now)
+	}
+
+	c := *comment
+	publishComment(ctx, s.Events, events.CommentUpdated, &c)
+	return &c, nil
+}
+
+func (s *MemoryStorage) DeleteComment(ctx context.Context, id string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Deleting comment %s", id)
+	comment, err := s.liveComment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	comment.DeletedAt = &now
+	s.indexComment(comment)
+	// Комментарий без ответов скрываем, с ответами - оставляем заглушкой
+	if len(s.replies[id]) == 0 {
+		comment.Hidden = true
+		s.removeComment(comment)
+	}
+
+	c := *comment
+	publishComment(ctx, s.Events, events.CommentDeleted, &c)
+	return &c, nil
+}
+
+// liveComment возвращает комментарий, который можно менять. Вызывается под блокировкой
+func (s *MemoryStorage) liveComment(id string) (*models.Comment, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	comment, exists := s.commentsByID[id]
+	if !exists || comment.Hidden {
+		return nil, ErrCommentNotFound
+	}
+	if comment.Deleted() {
+		return nil, ErrCommentDeleted
+	}
+	return comment, nil
+}
+
+func (s *MemoryStorage) GetCommentsByPostID(ctx context.Context, postID string, opts CommentListOptions) (*CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	log.Printf("Getting comment by post id %s", postID)
 
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
 	if _, exists := s.posts[postID]; !exists {
 		log.Println("Post not found")
-		return nil, errors.New("post not found")
+		return nil, ErrPostNotFound
 	}
 
-	comments, exists := s.comments[postID]
-	if !exists {
-		log.Println("No comments found for this post")
-		return nil, errors.New("no comments found for this post")
+	return paginateComments(listedComments(s.comments[postID]), opts)
+}
+
+func (s *MemoryStorage) GetReplies(ctx context.Context, parentID string, opts CommentListOptions) (*CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Пагинация
-	start := offset
-	end := offset + limit
-	if start > len(comments) {
-		return []*models.Comment{}, nil
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log.Printf("Getting replies to comment %s", parentID)
+	if err := validateID(parentID); err != nil {
+		return nil, err
 	}
-	if end > len(comments) {
-		end = len(comments)
+	if parent, exists := s.commentsByID[parentID]; !exists || parent.Hidden {
+		return nil, ErrCommentNotFound
+	}
+
+	return paginateComments(listedComments(s.replies[parentID]), opts)
+}
+
+func (s *MemoryStorage) GetCommentTree(ctx context.Context, postID string, maxDepth, maxChildren int, sortOrder CommentSort) ([]*models.CommentNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log.Printf("Getting comment tree for post %s", postID)
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrPostNotFound
 	}
 
-	var result []*models.Comment
-	for i := start; i < end; i++ {
-		result = append(result, &comments[i])
+	maxDepth, maxChildren = treeLimits(maxDepth, maxChildren)
+	sortOrder = commentSortOrder(sortOrder)
+
+	// Обход в глубину по индексу родитель → дочерние, без просмотра всех комментариев поста;
+	// каждая группа соседей сортируется отдельно
+	nodes := []*models.CommentNode{}
+	var walk func(children []*models.Comment, depth int)
+	walk = func(children []*models.Comment, depth int) {
+		for i, comment := range sortComments(children, sortOrder) {
+			if i == maxChildren {
+				break
+			}
+			c := *comment
+			grandchildren := s.replies[comment.ID]
+			node := &models.CommentNode{Comment: &c, Depth: depth}
+			nodes = append(nodes, node)
+			if depth == maxDepth {
+				node.HasMoreReplies = len(grandchildren) > 0
+				continue
+			}
+			node.HasMoreReplies = len(grandchildren) > maxChildren
+			walk(grandchildren, depth+1)
+		}
 	}
-	return result, nil
+	walk(s.roots[postID], 0)
+
+	return nodes, nil
 }
 
-func (s *MemoryStorage) SubscribeToComments(postID string) (<-chan *models.Comment, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *MemoryStorage) GetThread(ctx context.Context, commentID string) ([]*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log.Printf("Getting thread of comment %s", commentID)
+	if err := validateID(commentID); err != nil {
+		return nil, err
+	}
+	root, exists := s.commentsByID[commentID]
+	if !exists || root.Hidden {
+		return nil, ErrCommentNotFound
+	}
+
+	// Обход в глубину по упорядоченным спискам ответов совпадает с порядком путей
+	var (
+		thread []*models.Comment
+		walk   func(comment *models.Comment)
+	)
+	walk = func(comment *models.Comment) {
+		c := *comment
+		thread = append(thread, &c)
+		for _, reply := range s.replies[comment.ID] {
+			walk(reply)
+		}
+	}
+	walk(root)
+
+	return thread, nil
+}
+
+// now возвращает строго возрастающие метки времени с точностью до микросекунды,
+// как в PostgreSQL, чтобы порядок вставки совпадал с порядком (CreatedAt, ID).
+// Вызывается под блокировкой на запись.
+func (s *MemoryStorage) now() time.Time {
+	t := time.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(s.lastTime) {
+		t = s.lastTime.Add(time.Microsecond)
+	}
+	s.lastTime = t
+	return t
+}
+
+// insertComment добавляет комментарий в индексы хранилища
+func (s *MemoryStorage) insertComment(comment *models.Comment) {
+	s.commentsByID[comment.ID] = comment
+	s.comments[comment.PostID] = insertOrdered(s.comments[comment.PostID], comment)
+	if comment.ParentID == nil {
+		s.roots[comment.PostID] = insertOrdered(s.roots[comment.PostID], comment)
+	} else {
+		s.replies[*comment.ParentID] = insertOrdered(s.replies[*comment.ParentID], comment)
+	}
+}
+
+// removeComment убирает скрытый комментарий из упорядоченных индексов,
+// в commentsByID он остаётся, чтобы на него нельзя было ответить
+func (s *MemoryStorage) removeComment(comment *models.Comment) {
+	s.comments[comment.PostID] = removeOrdered(s.comments[comment.PostID], comment)
+	if comment.ParentID == nil {
+		s.roots[comment.PostID] = removeOrdered(s.roots[comment.PostID], comment)
+	} else {
+		s.replies[*comment.ParentID] = removeOrdered(s.replies[*comment.ParentID], comment)
+	}
+}
+
+// removeOrdered удаляет комментарий из списка, упорядоченного по (CreatedAt, ID)
+func removeOrdered(comments []*models.Comment, comment *models.Comment) []*models.Comment {
+	i := sort.Search(len(comments), func(i int) bool {
+		return !Cursor{CreatedAt: comments[i].CreatedAt, ID: comments[i].ID}.after(comment.CreatedAt, comment.ID)
+	})
+	if i == len(comments) || comments[i] != comment {
+		return comments
+	}
+	return append(comments[:i], comments[i+1:]...)
+}
+
+// insertOrdered вставляет комментарий в список, упорядоченный по (CreatedAt, ID)
+func insertOrdered(comments []*models.Comment, comment *models.Comment) []*models.Comment {
+	i := sort.Search(len(comments), func(i int) bool {
+		return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}.after(comments[i].CreatedAt, comments[i].ID)
+	})
+	comments = append(comments, nil)
+	copy(comments[i+1:], comments[i:])
+	comments[i] = comment
+	return comments
+}
+
+// listedComments возвращает комментарии, не скрытые модерацией, сохраняя порядок
+func listedComments(comments []*models.Comment) []*models.Comment {
+	listed := make([]*models.Comment, 0, len(comments))
+	for _, comment := range comments {
+		if comment.ModStatus.Listed() {
+			listed = append(listed, comment)
+		}
+	}
+	return listed
+}
+
+// paginateComments возвращает страницу из упорядоченного по (CreatedAt, ID) списка
+// в порядке сортировки opts.Sort
+func paginateComments(comments []*models.Comment, opts CommentListOptions) (*CommentPage, error) {
+	sortOrder := opts.sortOrder()
+	comments = sortComments(comments, sortOrder)
 
-	log.Printf("Subscribing to comments for post %s", postID)
-	ch := make(chan *models.Comment, 1)
+	// По курсору ищем первую позицию после него, иначе используем offset
+	start := opts.Offset
+	if opts.After != "" {
+		cursor, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		position := cursor.comment()
+		start = sort.Search(len(comments), func(i int) bool {
+			return commentRankLess(sortOrder, position, comments[i])
+		})
+	}
+	if start > len(comments) {
+		start = len(comments)
+	}
+	end := start + PageSize(opts.First)
+	if end > len(comments) {
+		end = len(comments)
+	}
 
-	// Добавляем подписчика в список
-	s.subscriptions[postID] = append(s.subscriptions[postID], ch)
+	page := &CommentPage{
+		Comments:    make([]*models.Comment, 0, end-start),
+		HasNextPage: end < len(comments),
+	}
+	for _, comment := range comments[start:end] {
+		c := *comment
+		page.Comments = append(page.Comments, &c)
+	}
+	return page, nil
+}
 
-	log.Println("Listening for comments on comments_channel")
-	return ch, nil
+// sortComments упорядочивает список, отсортированный по (CreatedAt, ID), в порядке sortOrder.
+// Для OLD возвращается исходный список, иначе - отсортированная копия
+func sortComments(comments []*models.Comment, sortOrder CommentSort) []*models.Comment {
+	if sortOrder == CommentSortOld {
+		return comments
+	}
+	sorted := make([]*models.Comment, len(comments))
+	copy(sorted, comments)
+	sort.Slice(sorted, func(i, j int) bool {
+		return commentRankLess(sortOrder, sorted[i], sorted[j])
+	})
+	return sorted
 }
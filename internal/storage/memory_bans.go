@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) BanUser(ctx context.Context, opts BanOptions) (*models.Ban, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Banning user %s in community %s", opts.UserID, opts.CommunityID)
+	reason, err := opts.check()
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := s.communities[opts.CommunityID]; !exists {
+		return nil, ErrCommunityNotFound
+	}
+	if err := s.checkAuthor(opts.UserID); err != nil {
+		return nil, err
+	}
+	if err := s.checkAuthor(opts.ModeratorID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	ban := &models.Ban{
+		ID:          uuid.New().String(),
+		CommunityID: opts.CommunityID,
+		UserID:      opts.UserID,
+		ModeratorID: &opts.ModeratorID,
+		Reason:      reason,
+		Muted:       opts.Muted,
+		CreatedAt:   now,
+		ExpiresAt:   opts.expiresAt(now),
+	}
+	if s.bans[opts.CommunityID] == nil {
+		s.bans[opts.CommunityID] = make(map[string]*models.Ban)
+	}
+	s.bans[opts.CommunityID][opts.UserID] = ban
+	s.closeAppeals(opts.CommunityID, opts.UserID, now)
+	s.pruneBans(time.Now())
+
+	b := *ban
+	return &b, nil
+}
+
+func (s *MemoryStorage) UnbanUser(ctx context.Context, communityID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Unbanning user %s in community %s", userID, communityID)
+	if err := validateID(communityID); err != nil {
+		return err
+	}
+	if err := validateID(userID); err != nil {
+		return err
+	}
+	if _, exists := s.communities[communityID]; !exists {
+		return ErrCommunityNotFound
+	}
+
+	delete(s.bans[communityID], userID)
+	s.closeAppeals(communityID, userID, s.now())
+	return nil
+}
+
+func (s *MemoryStorage) GetBans(ctx context.Context, communityID string) ([]*models.Ban, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log.Printf("Fetching bans of community %s", communityID)
+	if err := validateID(communityID); err != nil {
+		return nil, err
+	}
+	if _, exists := s.communities[communityID]; !exists {
+		return nil, ErrCommunityNotFound
+	}
+
+	now := time.Now()
+	result := []*models.Ban{}
+	for _, ban := range s.bans[communityID] {
+		if ban.Expired(now) {
+			continue
+		}
+		b := *ban
+		result = append(result, &b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+func (s *MemoryStorage) AppealBan(ctx context.Context, communityID, userID, message string) (*models.BanAppeal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("User %s appeals ban in community %s", userID, communityID)
+	message, err := normalizeText(message, MaxAppealLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateID(communityID); err != nil {
+		return nil, err
+	}
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	ban := s.activeBan(communityID, userID)
+	if ban == nil {
+		return nil, ErrBanNotFound
+	}
+	if ban.Muted {
+		return nil, ErrAppealsMuted
+	}
+	if s.openAppeal(communityID, userID) != nil {
+		return nil, ErrAppealPending
+	}
+
+	appeal := &models.BanAppeal{
+		ID:          uuid.New().String(),
+		CommunityID: communityID,
+		UserID:      userID,
+		Message:     message,
+		CreatedAt:   s.now(),
+	}
+	s.appeals[appeal.ID] = appeal
+
+	a := *appeal
+	return &a, nil
+}
+
+func (s *MemoryStorage) ResolveAppeal(ctx context.Context, appealID string, accepted bool) (*models.BanAppeal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Resolving ban appeal %s, accepted: %t", appealID, accepted)
+	if err := validateID(appealID); err != nil {
+		return nil, err
+	}
+	appeal, exists := s.appeals[appealID]
+	if !exists {
+		return nil, ErrAppealNotFound
+	}
+	if appeal.ResolvedAt != nil {
+		return nil, ErrAppealResolved
+	}
+
+	now := s.now()
+	appeal.ResolvedAt = &now
+	appeal.Accepted = accepted
+	if accepted {
+		delete(s.bans[appeal.CommunityID], appeal.UserID)
+	}
+
+	a := *appeal
+	return &a, nil
+}
+
+// appealQueue возвращает открытые апелляции на действующие баны как элементы
+// очереди модерации. Вызывается под блокировкой
+func (s *MemoryStorage) appealQueue(communityID string) []*ModQueueItem {
+	var items []*ModQueueItem
+	for _, appeal := range s.appeals {
+		if appeal.ResolvedAt != nil || (communityID != "" && appeal.CommunityID != communityID) {
+			continue
+		}
+		ban := s.activeBan(appeal.CommunityID, appeal.UserID)
+		if ban == nil {
+			continue
+		}
+		a := *appeal
+		items = append(items, &ModQueueItem{Appeal: &a, Reasons: []string{ban.Reason}, LastReportedAt: a.CreatedAt})
+	}
+	return items
+}
+
+// activeBan возвращает действующий бан пользователя в сообществе или nil.
+// Вызывается под блокировкой
+func (s *MemoryStorage) activeBan(communityID, userID string) *models.Ban {
+	ban, exists := s.bans[communityID][userID]
+	if !exists || ban.Expired(time.Now()) {
+		return nil
+	}
+	return ban
+}
+
+// openAppeal возвращает открытую апелляцию пользователя в сообществе или nil.
+// Вызывается под блокировкой
+func (s *MemoryStorage) openAppeal(communityID, userID string) *models.BanAppeal {
+	for _, appeal := range s.appeals {
+		if appeal.CommunityID == communityID && appeal.UserID == userID && appeal.ResolvedAt == nil {
+			return appeal
+		}
+	}
+	return nil
+}
+
+// closeAppeals закрывает открытые апелляции пользователя в сообществе без
+// снятия бана. Вызывается под блокировкой на запись
+func (s *MemoryStorage) closeAppeals(communityID, userID string, now time.Time) {
+	if appeal := s.openAppeal(communityID, userID); appeal != nil {
+		appeal.ResolvedAt = &now
+	}
+}
+
+// pruneBans удаляет истёкшие баны. Вызывается под блокировкой на запись
+func (s *MemoryStorage) pruneBans(now time.Time) {
+	for _, bans := range s.bans {
+		for userID, ban := range bans {
+			if ban.Expired(now) {
+				delete(bans, userID)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) CreateCommunity(ctx context.Context, creatorID, name, description string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Creating community %s", name)
+	if err := s.checkAuthor(creatorID); err != nil {
+		return nil, err
+	}
+	if err := validateCommunity(name, description); err != nil {
+		return nil, err
+	}
+	key := strings.ToLower(name)
+	if _, exists := s.communityNames[key]; exists {
+		return nil, ErrCommunityTaken
+	}
+
+	community := models.Community{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Description: description,
+		CreatorID:   &creatorID,
+		CreatedAt:   s.now(),
+		MemberCount: 1,
+	}
+	s.communities[community.ID] = community
+	s.communityNames[key] = community.ID
+	s.members[community.ID] = map[string]bool{creatorID: true}
+	return &community, nil
+}
+
+func (s *MemoryStorage) GetCommunityByID(ctx context.Context, id string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	community, exists := s.communities[id]
+	if !exists {
+		return nil, ErrCommunityNotFound
+	}
+	return &community, nil
+}
+
+func (s *MemoryStorage) GetCommunityByName(ctx context.Context, name string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.communityNames[strings.ToLower(name)]
+	if !exists {
+		return nil, ErrCommunityNotFound
+	}
+	community := s.communities[id]
+	return &community, nil
+}
+
+func (s *MemoryStorage) JoinCommunity(ctx context.Context, userID, communityID string) (*models.Community, error) {
+	return s.setMembership(ctx, userID, communityID, true)
+}
+
+func (s *MemoryStorage) LeaveCommunity(ctx context.Context, userID, communityID string) (*models.Community, error) {
+	return s.setMembership(ctx, userID, communityID, false)
+}
+
+func (s *MemoryStorage) IsMember(ctx context.Context, userID, communityID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.members[communityID][userID], nil
+}
+
+// setMembership добавляет пользователя в сообщество или убирает из него
+func (s *MemoryStorage) setMembership(ctx context.Context, userID, communityID string, member bool) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Setting membership of %s in community %s to %t", userID, communityID, member)
+	if err := s.checkAuthor(userID); err != nil {
+		return nil, err
+	}
+	if err := validateID(communityID); err != nil {
+		return nil, err
+	}
+	community, exists := s.communities[communityID]
+	if !exists {
+		return nil, ErrCommunityNotFound
+	}
+
+	members := s.members[communityID]
+	if members[userID] != member {
+		if member {
+			members[userID] = true
+		} else {
+			delete(members, userID)
+		}
+		community.MemberCount = len(members)
+		s.communities[communityID] = community
+	}
+	return &community, nil
+}
+
+func (s *MemoryStorage) GetJoinedCommunities(ctx context.Context, userID string) ([]*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	communities := []*models.Community{}
+	for id, members := range s.members {
+		if members[userID] {
+			community := s.communities[id]
+			communities = append(communities, &community)
+		}
+	}
+	sort.Slice(communities, func(i, j int) bool {
+		return strings.ToLower(communities[i].Name) < strings.ToLower(communities[j].Name)
+	})
+	return communities, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) TrainClassifier(ctx context.Context, spam bool, tokens []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Training spam classifier, spam: %t", spam)
+	s.countClassifierDoc(spam, tokens, 1)
+	return nil
+}
+
+func (s *MemoryStorage) UntrainClassifier(ctx context.Context, spam bool, tokens []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Untraining spam classifier, spam: %t", spam)
+	s.countClassifierDoc(spam, tokens, -1)
+	return nil
+}
+
+// countClassifierDoc добавляет (delta = 1) или вычитает (delta = -1) обучающий текст;
+// счётчики не опускаются ниже нуля. Вызывается под блокировкой
+func (s *MemoryStorage) countClassifierDoc(spam bool, tokens []string, delta int) {
+	if spam {
+		s.classifier.SpamDocs = max(s.classifier.SpamDocs+delta, 0)
+	} else {
+		s.classifier.HamDocs = max(s.classifier.HamDocs+delta, 0)
+	}
+	seen := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		counts := s.classifier.Tokens[token]
+		if spam {
+			counts.Spam = max(counts.Spam+delta, 0)
+		} else {
+			counts.Ham = max(counts.Ham+delta, 0)
+		}
+		if counts == (TokenCounts{}) {
+			delete(s.classifier.Tokens, token)
+			continue
+		}
+		s.classifier.Tokens[token] = counts
+	}
+}
+
+func (s *MemoryStorage) GetClassifierStats(ctx context.Context, tokens []string) (*ClassifierStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := &ClassifierStats{
+		SpamDocs: s.classifier.SpamDocs,
+		HamDocs:  s.classifier.HamDocs,
+		Tokens:   make(map[string]TokenCounts),
+	}
+	for _, token := range tokens {
+		if counts, exists := s.classifier.Tokens[token]; exists {
+			stats.Tokens[token] = counts
+		}
+	}
+	return stats, nil
+}
+
+func (s *MemoryStorage) RecordFilterDecision(ctx context.Context, decision models.FilterDecision) (*models.FilterDecision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decision.ID = uuid.New().String()
+	decision.CreatedAt = s.now()
+	log.Printf("Recording filter decision %s by %s", decision.Verdict, decision.Filter)
+	s.filterDecisions = append(s.filterDecisions, &decision)
+
+	d := decision
+	return &d, nil
+}
+
+func (s *MemoryStorage) GetFilterDecisions(ctx context.Context, first int) ([]*models.FilterDecision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limit := PageSize(first)
+	result := []*models.FilterDecision{}
+	for i := len(s.filterDecisions) - 1; i >= 0 && len(result) < limit; i-- {
+		d := *s.filterDecisions[i]
+		result = append(result, &d)
+	}
+	return result, nil
+}
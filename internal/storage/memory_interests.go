@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+func (s *MemoryStorage) FollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) (*models.Interest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("User %s follows %s %s", userID, kind, target)
+	if err := s.checkAuthor(userID); err != nil {
+		return nil, err
+	}
+	target, err := interestTarget(kind, target)
+	if err != nil {
+		return nil, err
+	}
+	if kind == models.InterestAuthor {
+		if err := s.checkAuthor(target); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, interest := range s.interests[userID] {
+		if interest.Kind == kind && interest.Target == target {
+			i := *interest
+			return &i, nil
+		}
+	}
+	interest := &models.Interest{UserID: userID, Kind: kind, Target: target, CreatedAt: s.now()}
+	s.interests[userID] = append(s.interests[userID], interest)
+	i := *interest
+	return &i, nil
+}
+
+func (s *MemoryStorage) UnfollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("User %s unfollows %s %s", userID, kind, target)
+	target, err := interestTarget(kind, target)
+	if err != nil {
+		return err
+	}
+
+	interests := s.interests[userID]
+	for i, interest := range interests {
+		if interest.Kind == kind && interest.Target == target {
+			s.interests[userID] = append(interests[:i:i], interests[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetInterests(ctx context.Context, userID string) ([]*models.Interest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	interests := make([]*models.Interest, 0, len(s.interests[userID]))
+	for _, interest := range s.interests[userID] {
+		i := *interest
+		interests = append(interests, &i)
+	}
+	return interests, nil
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) Report(ctx context.Context, reporterID, targetID, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("User %s reports %s", reporterID, targetID)
+	reason, err := normalizeReason(reason)
+	if err != nil {
+		return err
+	}
+	if err := s.checkAuthor(reporterID); err != nil {
+		return err
+	}
+	target, err := s.moderationTarget(targetID)
+	if err != nil {
+		return err
+	}
+
+	open := 0
+	for _, report := range s.reports[targetID] {
+		if report.ResolvedAt != nil {
+			continue
+		}
+		if report.ReporterID == reporterID {
+			return nil
+		}
+		open++
+	}
+	s.reports[targetID] = append(s.reports[targetID], &models.Report{
+		ID:         uuid.New().String(),
+		ReporterID: reporterID,
+		TargetID:   targetID,
+		Reason:     reason,
+		CreatedAt:  s.now(),
+	})
+
+	if open+1 >= FlagReportThreshold {
+		s.setModStatus(target, models.ModerationFlagged, nil, true)
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetModQueue(ctx context.Context, opts ModQueueOptions) ([]*ModQueueItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log.Println("Fetching moderation queue")
+	if opts.CommunityID != "" {
+		if err := validateID(opts.CommunityID); err != nil {
+			return nil, err
+		}
+	}
+	// inCommunity - относится ли пост к сообществу из фильтра
+	inCommunity := func(postID string) bool {
+		communityID := s.posts[postID].CommunityID
+		return opts.CommunityID == "" || (communityID != nil && *communityID == opts.CommunityID)
+	}
+
+	var items []*ModQueueItem
+	for id := range s.posts {
+		post := s.posts[id]
+		if !inCommunity(id) {
+			continue
+		}
+		if item := s.queueItem(id, post.ModStatus, post.CreatedAt); item != nil {
+			item.Post = &post
+			items = append(items, item)
+		}
+	}
+	for id, comment := range s.commentsByID {
+		if comment.Hidden || comment.Deleted() || !inCommunity(comment.PostID) {
+			continue
+		}
+		if item := s.queueItem(id, comment.ModStatus, comment.CreatedAt); item != nil {
+			c := *comment
+			item.Comment = &c
+			items = append(items, item)
+		}
+	}
+	items = append(items, s.appealQueue(opts.CommunityID)...)
+
+	return sortModQueue(items, PageSize(opts.First)), nil
+}
+
+// queueItem собирает открытые жалобы на объект. Возвращает nil, если объект
+// не ждёт решения модератора. Вызывается под блокировкой
+func (s *MemoryStorage) queueItem(id string, status models.ModerationStatus, createdAt time.Time) *ModQueueItem {
+	if status == models.ModerationRemoved {
+		return nil
+	}
+	item := &ModQueueItem{LastReportedAt: createdAt}
+	reasons := map[string]bool{}
+	for _, report := range s.reports[id] {
+		if report.ResolvedAt != nil {
+			continue
+		}
+		item.ReportCount++
+		item.LastReportedAt = report.CreatedAt
+		if !reasons[report.Reason] {
+			reasons[report.Reason] = true
+			item.Reasons = append(item.Reasons, report.Reason)
+		}
+	}
+	if item.ReportCount == 0 && status != models.ModerationFlagged {
+		return nil
+	}
+	sort.Strings(item.Reasons)
+	return item
+}
+
+func (s *MemoryStorage) Moderate(ctx context.Context, targetID string, status models.ModerationStatus, reason *string) (*ModerationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Setting moderation status of %s to %s", targetID, status)
+	reason, err := checkModeration(status, reason)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.moderationTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ModerationResult{}
+	if target.Post != nil {
+		result.PrevStatus, result.PrevReason = target.Post.ModStatus, target.Post.RemovalReason
+	} else {
+		result.PrevStatus, result.PrevReason = target.Comment.ModStatus, target.Comment.RemovalReason
+	}
+	s.setModStatus(target, status, reason, false)
+	result.Target = *target
+	if resolves(status) {
+		now := s.now()
+		for _, report := range s.reports[targetID] {
+			if report.ResolvedAt == nil {
+				report.ResolvedAt = &now
+			}
+		}
+	}
+	publishTarget(ctx, s.Events, target)
+	return result, nil
+}
+
+func (s *MemoryStorage) LockComment(ctx context.Context, id string, locked bool) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Setting lock of comment %s to %t", id, locked)
+	comment, err := s.liveComment(id)
+	if err != nil {
+		return nil, err
+	}
+	comment.Locked = locked
+
+	c := *comment
+	publishComment(ctx, s.Events, events.CommentUpdated, &c)
+	return &c, nil
+}
+
+// moderationTarget находит пост или комментарий по ID. Удалённые комментарии
+// не модерируются. Вызывается под блокировкой
+func (s *MemoryStorage) moderationTarget(targetID string) (*Target, error) {
+	if err := validateID(targetID); err != nil {
+		return nil, err
+	}
+	if post, exists := s.posts[targetID]; exists {
+		return &Target{Post: &post}, nil
+	}
+	comment, err := s.liveComment(targetID)
+	if errors.Is(err, ErrCommentNotFound) {
+		return nil, ErrTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := *comment
+	return &Target{Comment: &c}, nil
+}
+
+// setModStatus сохраняет статус модерации и обновляет поисковый индекс; копия
+// в target тоже обновляется. С onlyVisible статус меняется только у объектов,
+// которые модератор ещё не проверял. Вызывается под блокировкой на запись
+func (s *MemoryStorage) setModStatus(target *Target, status models.ModerationStatus, reason *string, onlyVisible bool) {
+	if target.Post != nil {
+		post := s.posts[target.Post.ID]
+		if onlyVisible && post.ModStatus != models.ModerationVisible {
+			return
+		}
+		post.ModStatus, post.RemovalReason = status, reason
+		s.posts[post.ID] = post
+		s.indexPost(&post)
+		*target.Post = post
+		return
+	}
+	comment := s.commentsByID[target.Comment.ID]
+	if onlyVisible && comment.ModStatus != models.ModerationVisible {
+		return
+	}
+	comment.ModStatus, comment.RemovalReason = status, reason
+	s.indexComment(comment)
+	*target.Comment = *comment
+}
+
+// threadLocked - заблокирован ли комментарий или один из его предков. Вызывается под блокировкой
+func (s *MemoryStorage) threadLocked(comment *models.Comment) bool {
+	for comment != nil {
+		if comment.Locked {
+			return true
+		}
+		if comment.ParentID == nil {
+			return false
+		}
+		comment = s.commentsByID[*comment.ParentID]
+	}
+	return false
+}
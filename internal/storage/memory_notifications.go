@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) CreateNotification(ctx context.Context, notification models.Notification) (*models.Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Notifying user %s about comment %s", notification.UserID, notification.CommentID)
+	if err := s.checkAuthor(notification.UserID); err != nil {
+		return nil, err
+	}
+
+	notification.ID = uuid.New().String()
+	notification.CreatedAt = s.now()
+	notification.ReadAt = nil
+	stored := notification
+	s.notifications[notification.UserID] = append(s.notifications[notification.UserID], &stored)
+
+	publish(ctx, s.Events, events.UserTopic(notification.UserID), events.NotificationCreated, notification.ID, notification)
+
+	return &notification, nil
+}
+
+func (s *MemoryStorage) GetNotifications(ctx context.Context, userID string, opts NotificationListOptions) (*NotificationPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cursor *Cursor
+	if opts.After != "" {
+		c, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &c
+	}
+
+	// Уведомления хранятся в порядке создания, отдаём от новых к старым
+	page := &NotificationPage{Notifications: []*models.Notification{}}
+	limit := PageSize(opts.First)
+	notifications := s.notifications[userID]
+	for i := len(notifications) - 1; i >= 0; i-- {
+		n := notifications[i]
+		if opts.UnreadOnly && n.Read() {
+			continue
+		}
+		if cursor != nil && (n.ID == cursor.ID || cursor.after(n.CreatedAt, n.ID)) {
+			continue
+		}
+		if len(page.Notifications) == limit {
+			page.HasNextPage = true
+			break
+		}
+		c := *n
+		page.Notifications = append(page.Notifications, &c)
+	}
+	return page, nil
+}
+
+func (s *MemoryStorage) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, n := range s.notifications[userID] {
+		if !n.Read() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStorage) MarkNotificationsRead(ctx context.Context, userID string, ids []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Marking notifications of user %s as read", userID)
+	if err := validateIDs(ids); err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	marked := 0
+	for _, n := range s.notifications[userID] {
+		if !n.Read() && (len(ids) == 0 || slices.Contains(ids, n.ID)) {
+			n.ReadAt = &now
+			marked++
+		}
+	}
+	return marked, nil
+}
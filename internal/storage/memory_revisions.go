@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) GetPostRevisions(ctx context.Context, postID string) ([]*models.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrPostNotFound
+	}
+	return s.copyRevisions(postID), nil
+}
+
+func (s *MemoryStorage) GetCommentRevisions(ctx context.Context, commentID string) ([]*models.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validateID(commentID); err != nil {
+		return nil, err
+	}
+	if comment, exists := s.commentsByID[commentID]; !exists || comment.Hidden {
+		return nil, ErrCommentNotFound
+	}
+	return s.copyRevisions(commentID), nil
+}
+
+// addRevision дописывает следующую версию текста. Вызывается под блокировкой на запись
+func (s *MemoryStorage) addRevision(targetID, editorID, title, content string, at time.Time) {
+	revisions := s.revisions[targetID]
+	s.revisions[targetID] = append(revisions, &models.Revision{
+		ID:        uuid.New().String(),
+		TargetID:  targetID,
+		Version:   len(revisions) + 1,
+		EditorID:  &editorID,
+		Title:     title,
+		Content:   content,
+		CreatedAt: at,
+	})
+}
+
+// copyRevisions возвращает копию истории, чтобы вызывающий не менял хранилище
+func (s *MemoryStorage) copyRevisions(targetID string) []*models.Revision {
+	revisions := make([]*models.Revision, 0, len(s.revisions[targetID]))
+	for _, revision := range s.revisions[targetID] {
+		r := *revision
+		revisions = append(revisions, &r)
+	}
+	return revisions
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// searchIndex - инвертированный индекс для полнотекстового поиска в памяти
+type searchIndex struct {
+	postings map[string]map[string]float64 // слово → ID документа → суммарный вес вхождений
+	docs     map[string]indexedDoc         // ID документа → его слова, чтобы переиндексировать и удалять
+}
+
+type indexedDoc struct {
+	terms  []string
+	length int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string]indexedDoc),
+	}
+}
+
+// add индексирует документ, заменяя его предыдущую версию. У комментариев title пустой
+func (ix *searchIndex) add(id, title, content string) {
+	ix.remove(id)
+
+	weights := map[string]float64{}
+	titleWords, contentWords := tokenize(title), tokenize(content)
+	for _, word := range titleWords {
+		weights[word] += titleWeight
+	}
+	for _, word := range contentWords {
+		weights[word] += contentWeight
+	}
+
+	doc := indexedDoc{length: len(titleWords) + len(contentWords)}
+	for word, weight := range weights {
+		if ix.postings[word] == nil {
+			ix.postings[word] = make(map[string]float64)
+		}
+		ix.postings[word][id] = weight
+		doc.terms = append(doc.terms, word)
+	}
+	ix.docs[id] = doc
+}
+
+// remove убирает документ из индекса
+func (ix *searchIndex) remove(id string) {
+	for _, word := range ix.docs[id].terms {
+		delete(ix.postings[word], id)
+		if len(ix.postings[word]) == 0 {
+			delete(ix.postings, word)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// search возвращает релевантность документов, содержащих все слова terms
+func (ix *searchIndex) search(terms []string) map[string]float64 {
+	// Перебираем самый короткий список вхождений и проверяем остальные слова
+	shortest := ix.postings[terms[0]]
+	for _, term := range terms[1:] {
+		if len(ix.postings[term]) < len(shortest) {
+			shortest = ix.postings[term]
+		}
+	}
+
+	ranks := map[string]float64{}
+	weights := map[string]float64{}
+	for id := range shortest {
+		matched := true
+		for _, term := range terms {
+			weight, ok := ix.postings[term][id]
+			if !ok {
+				matched = false
+				break
+			}
+			weights[term] = weight
+		}
+		if matched {
+			ranks[id] = textRank(weights, ix.docs[id].length, terms)
+		}
+	}
+	return ranks
+}
+
+func (s *MemoryStorage) Search(ctx context.Context, opts SearchOptions) (*SearchPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log.Printf("Searching %q", opts.Query)
+	searchType, err := opts.searchType()
+	if err != nil {
+		return nil, err
+	}
+	terms, err := queryTerms(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CommunityID != "" {
+		if err := validateID(opts.CommunityID); err != nil {
+			return nil, err
+		}
+	}
+	var cursor *Cursor
+	if opts.After != "" {
+		c, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &c
+	}
+
+	// inCommunity - относится ли пост к сообществу из фильтра
+	inCommunity := func(postID string) bool {
+		communityID := s.posts[postID].CommunityID
+		return opts.CommunityID == "" || (communityID != nil && *communityID == opts.CommunityID)
+	}
+
+	var results []SearchResult
+	switch searchType {
+	case SearchPosts:
+		for id, rank := range s.postIndex.search(terms) {
+			if !inCommunity(id) || (cursor != nil && !cursor.searchAfter(rank, id)) {
+				continue
+			}
+			post := s.posts[id]
+			results = append(results, SearchResult{Post: &post, Rank: rank})
+		}
+	case SearchComments:
+		for id, rank := range s.commentIndex.search(terms) {
+			comment := s.commentsByID[id]
+			if !inCommunity(comment.PostID) || (cursor != nil && !cursor.searchAfter(rank, id)) {
+				continue
+			}
+			c := *comment
+			results = append(results, SearchResult{Comment: &c, Rank: rank})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID() > results[j].ID()
+	})
+
+	page := &SearchPage{}
+	limit := PageSize(opts.First)
+	if len(results) > limit {
+		results, page.HasNextPage = results[:limit], true
+	}
+	// Фрагменты строим только для попавших на страницу результатов
+	for i := range results {
+		if results[i].Post != nil {
+			results[i].Snippet = snippet(results[i].Post.Content, terms)
+		} else {
+			results[i].Snippet = snippet(results[i].Comment.Content, terms)
+		}
+	}
+	page.Results = results
+	return page, nil
+}
+
+// indexPost обновляет пост в поисковом индексе; скрытые модерацией посты
+// из поиска исключаются. Вызывается под блокировкой
+func (s *MemoryStorage) indexPost(post *models.Post) {
+	if !post.ModStatus.Listed() {
+		s.postIndex.remove(post.ID)
+		return
+	}
+	s.postIndex.add(post.ID, post.Title, post.Content)
+}
+
+// indexComment обновляет комментарий в поисковом индексе; удалённые и скрытые
+// модерацией комментарии из поиска исключаются. Вызывается под блокировкой
+func (s *MemoryStorage) indexComment(comment *models.Comment) {
+	if comment.Deleted() || !comment.ModStatus.Listed() {
+		s.commentIndex.remove(comment.ID)
+		return
+	}
+	s.commentIndex.add(comment.ID, "", comment.Content)
+}
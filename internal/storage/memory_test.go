@@ -1,38 +1,48 @@
 package storage
 
 import (
+	"context"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetAllPosts_Empty(t *testing.T) {
 	storage := NewMemoryStorage()
 
-	posts, err := storage.GetAllPosts()
+	page, err := storage.GetAllPosts(context.Background(), PostListOptions{})
 
-	assert.Error(t, err)
-	assert.Nil(t, posts)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Posts)
+	assert.False(t, page.HasNextPage)
 }
 
 func TestGetAllPosts_ExistingPosts(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	_, err := storage.AddPost("Post 1", "Content", true)
+	_, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 	assert.NoError(t, err)
 
-	posts, err := storage.GetAllPosts()
+	page, err := storage.GetAllPosts(context.Background(), PostListOptions{})
 
 	assert.NoError(t, err)
-	assert.Len(t, posts, 1)
-	assert.Equal(t, "Post 1", posts[0].Title)
+	assert.Len(t, page.Posts, 1)
+	assert.Equal(t, "Post 1", page.Posts[0].Title)
 }
 
 func TestGetPostByID_NotFound(t *testing.T) {
 	storage := NewMemoryStorage()
 
-	post, err := storage.GetPostByID("nonexistent-id")
+	post, err := storage.GetPostByID(context.Background(), "nonexistent-id")
 
 	assert.Error(t, err)
 	assert.Nil(t, post)
@@ -40,11 +50,12 @@ func TestGetPostByID_NotFound(t *testing.T) {
 
 func TestGetPostByID_Found(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", true)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 	assert.NoError(t, err)
 
-	fetchedPost, err := storage.GetPostByID(post.ID)
+	fetchedPost, err := storage.GetPostByID(context.Background(), post.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, post.ID, fetchedPost.ID)
@@ -53,8 +64,9 @@ func TestGetPostByID_Found(t *testing.T) {
 
 func TestAddPost(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", true)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, post.ID)
@@ -62,10 +74,28 @@ func TestAddPost(t *testing.T) {
 	assert.True(t, post.AllowComments)
 }
 
+func TestAddPost_Timestamps(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	before := time.Now().UTC().Add(-time.Second)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+
+	assert.NoError(t, err)
+	assert.True(t, post.CreatedAt.After(before))
+	assert.Equal(t, post.CreatedAt, post.UpdatedAt)
+	assert.Nil(t, post.EditedAt)
+
+	stored, err := storage.GetPostByID(context.Background(), post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, post.CreatedAt, stored.CreatedAt)
+}
+
 func TestAddComment_NoPost(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	comment, err := storage.AddComment("nonexistent-post-id", nil, "Test comment")
+	comment, err := storage.AddComment(context.Background(), author, "nonexistent-post-id", nil, "Test comment", models.ModerationVisible)
 
 	assert.Error(t, err)
 	assert.Nil(t, comment)
@@ -73,11 +103,12 @@ func TestAddComment_NoPost(t *testing.T) {
 
 func TestAddComment_CommentsDisabled(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", false)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, false, models.ModerationVisible)
 	assert.NoError(t, err)
 
-	comment, err := storage.AddComment(post.ID, nil, "Test comment")
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, "Test comment", models.ModerationVisible)
 
 	assert.Error(t, err)
 	assert.Nil(t, comment)
@@ -85,11 +116,12 @@ func TestAddComment_CommentsDisabled(t *testing.T) {
 
 func TestAddComment_Success(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", true)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 	assert.NoError(t, err)
 
-	comment, err := storage.AddComment(post.ID, nil, "Test comment")
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, "Test comment", models.ModerationVisible)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, comment.ID)
@@ -99,12 +131,13 @@ func TestAddComment_Success(t *testing.T) {
 
 func TestAddComment_LongContent(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", true)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 	assert.NoError(t, err)
 
 	longContent := string(make([]byte, 2001)) // Exceeding 2000 chars
-	comment, err := storage.AddComment(post.ID, nil, longContent)
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, longContent, models.ModerationVisible)
 
 	assert.Error(t, err)
 	assert.Nil(t, comment)
@@ -113,7 +146,7 @@ func TestAddComment_LongContent(t *testing.T) {
 func TestGetCommentsByPostID_NotFound(t *testing.T) {
 	storage := NewMemoryStorage()
 
-	comments, err := storage.GetCommentsByPostID("nonexistent-post-id", 10, 0)
+	comments, err := storage.GetCommentsByPostID(context.Background(), "nonexistent-post-id", CommentListOptions{First: 10})
 
 	assert.Error(t, err)
 	assert.Nil(t, comments)
@@ -121,38 +154,1525 @@ func TestGetCommentsByPostID_NotFound(t *testing.T) {
 
 func TestGetCommentsByPostID_Success(t *testing.T) {
 	storage := NewMemoryStorage()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", true)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 	assert.NoError(t, err)
 
-	_, err = storage.AddComment(post.ID, nil, "Test comment")
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "Test comment", models.ModerationVisible)
 	assert.NoError(t, err)
 
-	comments, err := storage.GetCommentsByPostID(post.ID, 10, 0)
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{First: 10})
 
 	assert.NoError(t, err)
-	assert.Len(t, comments, 1)
-	assert.Equal(t, "Test comment", comments[0].Content)
+	assert.Len(t, page.Comments, 1)
+	assert.Equal(t, "Test comment", page.Comments[0].Content)
+	assert.False(t, page.HasNextPage)
 }
 
-func TestSubscribeToComments_Success(t *testing.T) {
+func TestAddComment_PublishesEvent(t *testing.T) {
 	storage := NewMemoryStorage()
+	storage.Events = events.NewMemoryBroker()
+	author := testUser(t, storage)
 
-	post, err := storage.AddPost("Post 1", "Content", true)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
 	assert.NoError(t, err)
 
-	ch, err := storage.SubscribeToComments(post.ID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := storage.Events.Subscribe(ctx, events.PostTopic(post.ID))
 	assert.NoError(t, err)
-	assert.NotNil(t, ch)
 
-	_, err = storage.AddComment(post.ID, nil, "Test comment")
+	added, err := storage.AddComment(context.Background(), author, post.ID, nil, "Test comment", models.ModerationVisible)
 	assert.NoError(t, err)
 
-	// Получение комментария с канала
+	// Событие несёт комментарий целиком
 	select {
-	case comment := <-ch:
+	case event := <-ch:
+		assert.Equal(t, events.CommentAdded, event.Type)
+		assert.Equal(t, added.ID, event.ID)
+		var comment models.Comment
+		ok, err := event.Decode(&comment)
+		assert.True(t, ok)
+		assert.NoError(t, err)
 		assert.Equal(t, "Test comment", comment.Content)
+		assert.Equal(t, added.CreatedAt, comment.CreatedAt)
 	case <-time.After(1 * time.Second):
 		assert.Fail(t, "Failed to receive comment")
 	}
 }
+
+func TestGetAllPosts_CanceledContext(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	page, err := storage.GetAllPosts(ctx, PostListOptions{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, page)
+}
+
+func TestGetPostByID_InvalidID(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	post, err := storage.GetPostByID(context.Background(), "nonexistent-id")
+
+	assert.ErrorIs(t, err, ErrInvalidID)
+	assert.Nil(t, post)
+}
+
+func TestGetPostByID_NotFoundError(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	post, err := storage.GetPostByID(context.Background(), uuid.New().String())
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, ErrPostNotFound)
+	assert.Nil(t, post)
+}
+
+func TestAddComment_TypedErrors(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	closed, err := storage.AddPost(context.Background(), author, nil, "Closed", "Content", nil, false, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, closed.ID, nil, "Test comment", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommentsDisabled)
+
+	open, err := storage.AddPost(context.Background(), author, nil, "Open", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, open.ID, nil, string(make([]rune, MaxCommentLength+1)), models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrContentTooLong)
+}
+
+func TestPostContentLength(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	long := strings.Repeat("ж", MaxPostContentLength+1)
+	_, err := storage.AddPost(context.Background(), author, nil, "Long", long, nil, true, models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrContentTooLong)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post", long[:MaxPostContentLength*2], nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.UpdatePost(context.Background(), author, post.ID, PostUpdate{Content: &long})
+	assert.ErrorIs(t, err, ErrContentTooLong)
+}
+
+func TestGetCommentsByPostID_Empty(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, page.Comments)
+	assert.False(t, page.HasNextPage)
+}
+
+func TestGetCommentsByPostID_CursorPagination(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	for _, content := range []string{"c1", "c2", "c3"} {
+		_, err = storage.AddComment(context.Background(), author, post.ID, nil, content, models.ModerationVisible)
+		assert.NoError(t, err)
+	}
+
+	first, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{First: 2})
+	assert.NoError(t, err)
+	assert.Len(t, first.Comments, 2)
+	assert.True(t, first.HasNextPage)
+
+	// Новый комментарий во время листания не должен сдвигать следующую страницу
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "c4", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	after := CommentCursor(first.Comments[1])
+	second, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{First: 2, After: after})
+	assert.NoError(t, err)
+	assert.Len(t, second.Comments, 2)
+	assert.Equal(t, "c3", second.Comments[0].Content)
+	assert.Equal(t, "c4", second.Comments[1].Content)
+	assert.False(t, second.HasNextPage)
+}
+
+func TestGetCommentsByPostID_InvalidCursor(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{After: "garbage"})
+
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	assert.Nil(t, page)
+}
+
+func TestGetReplies(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "root", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "reply 1", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "reply 2", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	page, err := storage.GetReplies(context.Background(), root.ID, CommentListOptions{First: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page.Comments, 1)
+	assert.Equal(t, "reply 1", page.Comments[0].Content)
+	assert.True(t, page.HasNextPage)
+
+	_, err = storage.GetReplies(context.Background(), uuid.New().String(), CommentListOptions{})
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+}
+
+func TestGetCommentTree(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "root", models.ModerationVisible)
+	assert.NoError(t, err)
+	child, err := storage.AddComment(context.Background(), author, post.ID, &root.ID, "child", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &child.ID, "grandchild", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "child 2", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "root 2", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	// Полное дерево в порядке обхода в глубину
+	nodes, err := storage.GetCommentTree(context.Background(), post.ID, -1, 0, CommentSortOld)
+	assert.NoError(t, err)
+	contents := make([]string, 0, len(nodes))
+	depths := make([]int, 0, len(nodes))
+	for _, node := range nodes {
+		contents = append(contents, node.Comment.Content)
+		depths = append(depths, node.Depth)
+	}
+	assert.Equal(t, []string{"root", "child", "grandchild", "child 2", "root 2"}, contents)
+	assert.Equal(t, []int{0, 1, 2, 1, 0}, depths)
+
+	// Ограничение глубины и количества дочерних комментариев
+	nodes, err = storage.GetCommentTree(context.Background(), post.ID, 1, 1, CommentSortOld)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "root", nodes[0].Comment.Content)
+	assert.True(t, nodes[0].HasMoreReplies)
+	assert.Equal(t, "child", nodes[1].Comment.Content)
+	assert.True(t, nodes[1].HasMoreReplies)
+}
+
+func TestGetCommentTree_PostNotFound(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	nodes, err := storage.GetCommentTree(context.Background(), uuid.New().String(), -1, 0, CommentSortOld)
+
+	assert.ErrorIs(t, err, ErrPostNotFound)
+	assert.Nil(t, nodes)
+}
+
+func TestAddComment_ParentValidation(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	other, err := storage.AddPost(context.Background(), author, nil, "Post 2", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	foreign, err := storage.AddComment(context.Background(), author, other.ID, nil, "foreign", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	missing := uuid.New().String()
+	_, err = storage.AddComment(context.Background(), author, post.ID, &missing, "reply", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+
+	_, err = storage.AddComment(context.Background(), author, post.ID, &foreign.ID, "reply", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrInvalidParent)
+}
+
+func TestAddComment_PathAndDepth(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "root", models.ModerationVisible)
+	assert.NoError(t, err)
+	reply, err := storage.AddComment(context.Background(), author, post.ID, &root.ID, "reply", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, root.Depth)
+	assert.Equal(t, 1, reply.Depth)
+	assert.True(t, strings.HasPrefix(reply.Path, root.Path+"."))
+}
+
+func TestGetThread(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "root", models.ModerationVisible)
+	assert.NoError(t, err)
+	child, err := storage.AddComment(context.Background(), author, post.ID, &root.ID, "child", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "other root", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "child 2", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &child.ID, "grandchild", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	thread, err := storage.GetThread(context.Background(), root.ID)
+	assert.NoError(t, err)
+
+	contents := make([]string, 0, len(thread))
+	for i, comment := range thread {
+		contents = append(contents, comment.Content)
+		// Порядок отображения совпадает с сортировкой материализованных путей
+		if i > 0 {
+			assert.Less(t, thread[i-1].Path, comment.Path)
+		}
+	}
+	assert.Equal(t, []string{"root", "child", "grandchild", "child 2"}, contents)
+}
+
+func TestGetAllPosts_NewestFirstWithCursor(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	for _, title := range []string{"p1", "p2", "p3"} {
+		_, err := storage.AddPost(context.Background(), author, nil, title, "Content", nil, true, models.ModerationVisible)
+		assert.NoError(t, err)
+	}
+
+	first, err := storage.GetAllPosts(context.Background(), PostListOptions{First: 2, Sort: PostSortNew})
+	assert.NoError(t, err)
+	assert.Len(t, first.Posts, 2)
+	assert.Equal(t, "p3", first.Posts[0].Title)
+	assert.Equal(t, "p2", first.Posts[1].Title)
+	assert.True(t, first.HasNextPage)
+
+	after := PostCursor(&first.Posts[1])
+	second, err := storage.GetAllPosts(context.Background(), PostListOptions{First: 2, Sort: PostSortNew, After: after})
+	assert.NoError(t, err)
+	assert.Len(t, second.Posts, 1)
+	assert.Equal(t, "p1", second.Posts[0].Title)
+	assert.False(t, second.HasNextPage)
+}
+
+func TestGetAllPosts_SortByScore(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	low, err := storage.AddPost(context.Background(), author, nil, "low", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	high, err := storage.AddPost(context.Background(), author, nil, "high", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	split, err := storage.AddPost(context.Background(), author, nil, "split", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	// Счётчики голосов задаём напрямую
+	storage.mu.Lock()
+	setVotes := func(id string, up, down int) {
+		post := storage.posts[id]
+		post.Upvotes, post.Downvotes = up, down
+		storage.posts[id] = post
+	}
+	setVotes(low.ID, 0, 0)
+	setVotes(high.ID, 10, 0)
+	setVotes(split.ID, 6, 5)
+	storage.mu.Unlock()
+
+	titles := func(sort PostSort) []string {
+		page, err := storage.GetAllPosts(context.Background(), PostListOptions{Sort: sort})
+		assert.NoError(t, err)
+		result := make([]string, 0, len(page.Posts))
+		for _, post := range page.Posts {
+			result = append(result, post.Title)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"high", "split", "low"}, titles(PostSortTop))
+	assert.Equal(t, "split", titles(PostSortControversial)[0])
+	assert.Equal(t, "high", titles(PostSortHot)[0])
+}
+
+func TestGetCommentsByPostID_Sort(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	// Счётчики голосов задаём напрямую: few - высокая доля при малом числе голосов,
+	// many - та же доля при большом, split - голоса поровну
+	votes := map[string][2]int{"none": {0, 0}, "few": {2, 0}, "many": {40, 2}, "split": {20, 20}}
+	for _, content := range []string{"none", "few", "many", "split"} {
+		comment, err := storage.AddComment(context.Background(), author, post.ID, nil, content, models.ModerationVisible)
+		assert.NoError(t, err)
+		storage.mu.Lock()
+		storage.commentsByID[comment.ID].Upvotes = votes[content][0]
+		storage.commentsByID[comment.ID].Downvotes = votes[content][1]
+		storage.mu.Unlock()
+	}
+
+	contents := func(sort CommentSort) []string {
+		// Листаем по одному комментарию, чтобы проверить курсор в каждом порядке
+		var result []string
+		opts := CommentListOptions{First: 1, Sort: sort}
+		for {
+			page, err := storage.GetCommentsByPostID(context.Background(), post.ID, opts)
+			assert.NoError(t, err)
+			for _, comment := range page.Comments {
+				result = append(result, comment.Content)
+				opts.After = CommentCursor(comment)
+			}
+			if !page.HasNextPage {
+				return result
+			}
+		}
+	}
+
+	assert.Equal(t, []string{"none", "few", "many", "split"}, contents(""))
+	assert.Equal(t, []string{"none", "few", "many", "split"}, contents(CommentSortOld))
+	assert.Equal(t, []string{"split", "many", "few", "none"}, contents(CommentSortNew))
+	assert.Equal(t, []string{"many", "few", "split", "none"}, contents(CommentSortBest))
+	assert.Equal(t, []string{"many", "few", "none", "split"}, contents(CommentSortTop))
+	assert.Equal(t, []string{"split", "many", "none", "few"}, contents(CommentSortControversial))
+}
+
+func TestGetCommentTree_SortPerSiblingGroup(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	first, err := storage.AddComment(context.Background(), author, post.ID, nil, "first", models.ModerationVisible)
+	assert.NoError(t, err)
+	second, err := storage.AddComment(context.Background(), author, post.ID, nil, "second", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &first.ID, "old reply", models.ModerationVisible)
+	assert.NoError(t, err)
+	popular, err := storage.AddComment(context.Background(), author, post.ID, &first.ID, "popular reply", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	_, err = storage.Vote(context.Background(), author, second.ID, models.VoteUp)
+	assert.NoError(t, err)
+	_, err = storage.Vote(context.Background(), author, popular.ID, models.VoteUp)
+	assert.NoError(t, err)
+
+	nodes, err := storage.GetCommentTree(context.Background(), post.ID, -1, 0, CommentSortTop)
+	assert.NoError(t, err)
+	var contents []string
+	for _, node := range nodes {
+		contents = append(contents, node.Comment.Content)
+	}
+	assert.Equal(t, []string{"second", "first", "popular reply", "old reply"}, contents)
+}
+
+func TestWilson(t *testing.T) {
+	assert.Zero(t, wilson(0, 0))
+	assert.Less(t, wilson(2, 0), wilson(40, 2))
+	assert.Less(t, wilson(20, 20), wilson(2, 0))
+	assert.InDelta(t, 0.984, wilson(100, 0), 0.001)
+}
+
+func TestGetAllPosts_TimeRange(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	old, err := storage.AddPost(context.Background(), author, nil, "old", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddPost(context.Background(), author, nil, "fresh", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	storage.mu.Lock()
+	post := storage.posts[old.ID]
+	post.CreatedAt = time.Now().AddDate(0, 0, -2)
+	storage.posts[old.ID] = post
+	storage.mu.Unlock()
+
+	page, err := storage.GetAllPosts(context.Background(), PostListOptions{TimeRange: TimeRangeDay})
+	assert.NoError(t, err)
+	assert.Len(t, page.Posts, 1)
+	assert.Equal(t, "fresh", page.Posts[0].Title)
+
+	page, err = storage.GetAllPosts(context.Background(), PostListOptions{TimeRange: TimeRangeAll})
+	assert.NoError(t, err)
+	assert.Len(t, page.Posts, 2)
+}
+
+func TestCreateUser_UsernameTaken(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	user, err := storage.CreateUser(context.Background(), "Alice", "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	_, err = storage.CreateUser(context.Background(), "alice", "hash")
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+
+	found, err := storage.GetUserByUsername(context.Background(), "ALICE")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+}
+
+func TestSetUserRole(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	user, err := storage.SetUserRole(context.Background(), author, models.RoleModerator)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, user.Role)
+	found, err := storage.GetUserByID(context.Background(), author)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, found.Role)
+
+	_, err = storage.SetUserRole(context.Background(), author, models.Role("owner"))
+	assert.ErrorIs(t, err, ErrInvalidRole)
+	_, err = storage.SetUserRole(context.Background(), uuid.New().String(), models.RoleAdmin)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestSessions(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	now := time.Now().UTC()
+	err := storage.CreateSession(context.Background(), models.Session{TokenHash: "h1", UserID: author, CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	assert.NoError(t, err)
+	err = storage.CreateSession(context.Background(), models.Session{TokenHash: "h2", UserID: author, CreatedAt: now, ExpiresAt: now})
+	assert.NoError(t, err)
+
+	session, err := storage.GetSession(context.Background(), "h1")
+	assert.NoError(t, err)
+	assert.Equal(t, author, session.UserID)
+
+	_, err = storage.GetSession(context.Background(), "h2")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	assert.NoError(t, storage.DeleteSession(context.Background(), "h1"))
+	_, err = storage.GetSession(context.Background(), "h1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestAddPost_UnknownAuthor(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	_, err := storage.AddPost(context.Background(), uuid.NewString(), nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUpdatePost(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storage.Events = events.NewMemoryBroker()
+	updates, err := storage.Events.Subscribe(ctx, events.PostTopic(post.ID))
+	assert.NoError(t, err)
+
+	disabled := false
+	updated, err := storage.UpdatePost(context.Background(), author, post.ID, PostUpdate{AllowComments: &disabled})
+	assert.NoError(t, err)
+	assert.False(t, updated.AllowComments)
+	assert.True(t, updated.UpdatedAt.After(post.UpdatedAt))
+	assert.Nil(t, updated.EditedAt)
+
+	select {
+	case event := <-updates:
+		var received models.Post
+		_, err := event.Decode(&received)
+		assert.NoError(t, err)
+		assert.Equal(t, events.PostUpdated, event.Type)
+		assert.False(t, received.AllowComments)
+	case <-time.After(time.Second):
+		t.Fatal("post update was not delivered")
+	}
+
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "Test comment", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommentsDisabled)
+
+	title := "Post 1 (edited)"
+	updated, err = storage.UpdatePost(context.Background(), author, post.ID, PostUpdate{Title: &title})
+	assert.NoError(t, err)
+	assert.Equal(t, title, updated.Title)
+	assert.Equal(t, "Content", updated.Content)
+	assert.NotNil(t, updated.EditedAt)
+
+	_, err = storage.UpdatePost(context.Background(), author, uuid.NewString(), PostUpdate{Title: &title})
+	assert.ErrorIs(t, err, ErrPostNotFound)
+}
+
+func TestUpdateComment(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, "Test comment", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	updated, err := storage.UpdateComment(context.Background(), author, comment.ID, "Edited comment")
+	assert.NoError(t, err)
+	assert.Equal(t, "Edited comment", updated.Content)
+	assert.NotNil(t, updated.EditedAt)
+
+	_, err = storage.UpdateComment(context.Background(), author, comment.ID, strings.Repeat("a", MaxCommentLength+1))
+	assert.ErrorIs(t, err, ErrContentTooLong)
+
+	stored, err := storage.GetCommentByID(context.Background(), comment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Edited comment", stored.Content)
+}
+
+func TestDeleteComment_TombstoneAndHidden(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "root", models.ModerationVisible)
+	assert.NoError(t, err)
+	reply, err := storage.AddComment(context.Background(), author, post.ID, &root.ID, "reply", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	// У корня есть ответ - остаётся заглушка
+	deleted, err := storage.DeleteComment(context.Background(), root.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted.Deleted())
+	assert.False(t, deleted.Hidden)
+
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "late reply", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommentDeleted)
+	_, err = storage.UpdateComment(context.Background(), author, root.ID, "edit")
+	assert.ErrorIs(t, err, ErrCommentDeleted)
+	_, err = storage.DeleteComment(context.Background(), root.ID)
+	assert.ErrorIs(t, err, ErrCommentDeleted)
+
+	// Ответ без дочерних комментариев скрывается
+	deleted, err = storage.DeleteComment(context.Background(), reply.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted.Hidden)
+
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Comments, 1)
+	assert.Equal(t, root.ID, page.Comments[0].ID)
+	assert.True(t, page.Comments[0].Deleted())
+
+	nodes, err := storage.GetCommentTree(context.Background(), post.ID, -1, 0, CommentSortOld)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+	assert.False(t, nodes[0].HasMoreReplies)
+
+	_, err = storage.GetCommentByID(context.Background(), reply.ID)
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+	_, err = storage.GetThread(context.Background(), reply.ID)
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+}
+
+func TestRevisions(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+	moderator := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	content := "Content v2"
+	_, err = storage.UpdatePost(context.Background(), moderator, post.ID, PostUpdate{Content: &content})
+	assert.NoError(t, err)
+
+	// Смена allowComments не создаёт версию
+	disabled := false
+	_, err = storage.UpdatePost(context.Background(), author, post.ID, PostUpdate{AllowComments: &disabled})
+	assert.NoError(t, err)
+
+	revisions, err := storage.GetPostRevisions(context.Background(), post.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[0].Version)
+	assert.Equal(t, author, *revisions[0].EditorID)
+	assert.Equal(t, 2, revisions[1].Version)
+	assert.Equal(t, moderator, *revisions[1].EditorID)
+	assert.Equal(t, "Content v2", revisions[1].Content)
+
+	enabled := true
+	_, err = storage.UpdatePost(context.Background(), author, post.ID, PostUpdate{AllowComments: &enabled})
+	assert.NoError(t, err)
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, "Comment", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.UpdateComment(context.Background(), author, comment.ID, "Comment")
+	assert.NoError(t, err)
+	_, err = storage.UpdateComment(context.Background(), author, comment.ID, "Comment v2")
+	assert.NoError(t, err)
+
+	revisions, err = storage.GetCommentRevisions(context.Background(), comment.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "Comment v2", revisions[1].Content)
+
+	_, err = storage.GetPostRevisions(context.Background(), uuid.NewString())
+	assert.ErrorIs(t, err, ErrPostNotFound)
+}
+
+func TestVote(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+	voter := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	target, err := storage.Vote(context.Background(), voter, post.ID, models.VoteUp)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, target.Post.Upvotes)
+
+	// Повторный голос того же пользователя не добавляется, а заменяет предыдущий
+	_, err = storage.Vote(context.Background(), voter, post.ID, models.VoteUp)
+	assert.NoError(t, err)
+	target, err = storage.Vote(context.Background(), voter, post.ID, models.VoteDown)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, target.Post.Upvotes)
+	assert.Equal(t, 1, target.Post.Downvotes)
+
+	_, err = storage.Vote(context.Background(), author, post.ID, models.VoteDown)
+	assert.NoError(t, err)
+	stored, err := storage.GetPostByID(context.Background(), post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, -2, stored.Score())
+
+	direction, err := storage.GetVote(context.Background(), voter, post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VoteDown, direction)
+
+	target, err = storage.Vote(context.Background(), voter, post.ID, models.VoteNone)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, target.Post.Downvotes)
+	direction, err = storage.GetVote(context.Background(), voter, post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VoteNone, direction)
+
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, "Comment", models.ModerationVisible)
+	assert.NoError(t, err)
+	target, err = storage.Vote(context.Background(), voter, comment.ID, models.VoteUp)
+	assert.NoError(t, err)
+	assert.Nil(t, target.Post)
+	assert.Equal(t, 1, target.Comment.Score())
+
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Comments[0].Upvotes)
+
+	_, err = storage.DeleteComment(context.Background(), comment.ID)
+	assert.NoError(t, err)
+	_, err = storage.Vote(context.Background(), voter, comment.ID, models.VoteUp)
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+
+	_, err = storage.Vote(context.Background(), voter, uuid.NewString(), models.VoteUp)
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+	_, err = storage.Vote(context.Background(), voter, post.ID, models.VoteDirection(2))
+	assert.ErrorIs(t, err, ErrInvalidVote)
+}
+
+func TestVote_DeletedComment(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "root", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "reply", models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.DeleteComment(context.Background(), root.ID)
+	assert.NoError(t, err)
+
+	_, err = storage.Vote(context.Background(), author, root.ID, models.VoteUp)
+	assert.ErrorIs(t, err, ErrCommentDeleted)
+}
+
+func TestCommunities(t *testing.T) {
+	storage := NewMemoryStorage()
+	creator := testUser(t, storage)
+	member := testUser(t, storage)
+
+	community, err := storage.CreateCommunity(context.Background(), creator, "golang", "Go programming")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, community.MemberCount)
+
+	_, err = storage.CreateCommunity(context.Background(), creator, "GoLang", "")
+	assert.ErrorIs(t, err, ErrCommunityTaken)
+	_, err = storage.CreateCommunity(context.Background(), creator, "go lang", "")
+	assert.ErrorIs(t, err, ErrInvalidCommunity)
+	_, err = storage.CreateCommunity(context.Background(), creator, "rust", strings.Repeat("a", MaxCommunityDescriptionLength+1))
+	assert.ErrorIs(t, err, ErrContentTooLong)
+
+	found, err := storage.GetCommunityByName(context.Background(), "GOLANG")
+	assert.NoError(t, err)
+	assert.Equal(t, community.ID, found.ID)
+	_, err = storage.GetCommunityByName(context.Background(), "rust")
+	assert.ErrorIs(t, err, ErrCommunityNotFound)
+
+	// Вступление и выход идемпотентны
+	joined, err := storage.JoinCommunity(context.Background(), member, community.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, joined.MemberCount)
+	joined, err = storage.JoinCommunity(context.Background(), member, community.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, joined.MemberCount)
+
+	isMember, err := storage.IsMember(context.Background(), member, community.ID)
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+
+	left, err := storage.LeaveCommunity(context.Background(), member, community.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, left.MemberCount)
+	left, err = storage.LeaveCommunity(context.Background(), member, community.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, left.MemberCount)
+
+	_, err = storage.JoinCommunity(context.Background(), member, uuid.NewString())
+	assert.ErrorIs(t, err, ErrCommunityNotFound)
+}
+
+func TestGetAllPosts_Community(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	community, err := storage.CreateCommunity(context.Background(), author, "golang", "")
+	assert.NoError(t, err)
+	inside, err := storage.AddPost(context.Background(), author, &community.ID, "inside", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	assert.Equal(t, community.ID, *inside.CommunityID)
+	_, err = storage.AddPost(context.Background(), author, nil, "outside", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	page, err := storage.GetAllPosts(context.Background(), PostListOptions{CommunityIDs: []string{community.ID}})
+	assert.NoError(t, err)
+	assert.Len(t, page.Posts, 1)
+	assert.Equal(t, "inside", page.Posts[0].Title)
+
+	page, err = storage.GetAllPosts(context.Background(), PostListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Posts, 2)
+
+	unknown := uuid.NewString()
+	_, err = storage.AddPost(context.Background(), author, &unknown, "lost", "Content", nil, true, models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommunityNotFound)
+}
+
+func TestAddPost_Tags(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content",
+		[]string{"Go", " graphql ", "go"}, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "graphql"}, post.Tags)
+
+	_, err = storage.AddPost(context.Background(), author, nil, "Post 2", "Content", []string{"no spaces"}, true, models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	_, err = storage.AddPost(context.Background(), author, nil, "Post 3", "Content",
+		[]string{"a", "b", "c", "d", "e", "f"}, true, models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrTooManyTags)
+}
+
+func TestGetAllPosts_Filters(t *testing.T) {
+	storage := NewMemoryStorage()
+	alice := testUser(t, storage)
+	bob := testUser(t, storage)
+
+	_, err := storage.AddPost(context.Background(), alice, nil, "alice go", "Content", []string{"go"}, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddPost(context.Background(), bob, nil, "bob rust", "Content", []string{"rust"}, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddPost(context.Background(), bob, nil, "bob go", "Content", []string{"go", "web"}, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	titles := func(opts PostListOptions) []string {
+		opts.Sort = PostSortNew
+		page, err := storage.GetAllPosts(context.Background(), opts)
+		assert.NoError(t, err)
+		var titles []string
+		for _, post := range page.Posts {
+			titles = append(titles, post.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"alice go"}, titles(PostListOptions{AuthorIDs: []string{alice}}))
+	assert.Equal(t, []string{"bob go", "alice go"}, titles(PostListOptions{Tags: []string{"go"}}))
+	assert.Equal(t, []string{"bob go", "bob rust"}, titles(PostListOptions{Tags: []string{"rust", "web"}}))
+	assert.Equal(t, []string{"bob go"}, titles(PostListOptions{AuthorIDs: []string{bob}, Tags: []string{"go"}}))
+}
+
+func TestInterests(t *testing.T) {
+	storage := NewMemoryStorage()
+	user := testUser(t, storage)
+	author := testUser(t, storage)
+
+	_, err := storage.FollowInterest(context.Background(), user, models.InterestTag, "GoLang")
+	assert.NoError(t, err)
+	_, err = storage.FollowInterest(context.Background(), user, models.InterestAuthor, author)
+	assert.NoError(t, err)
+	// Повторная подписка ничего не меняет
+	_, err = storage.FollowInterest(context.Background(), user, models.InterestTag, "golang")
+	assert.NoError(t, err)
+
+	interests, err := storage.GetInterests(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Len(t, interests, 2)
+	assert.Equal(t, models.InterestTag, interests[0].Kind)
+	assert.Equal(t, "golang", interests[0].Target)
+	assert.Equal(t, author, interests[1].Target)
+
+	_, err = storage.FollowInterest(context.Background(), user, models.InterestAuthor, uuid.NewString())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = storage.FollowInterest(context.Background(), user, models.InterestTag, "bad tag")
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	assert.NoError(t, storage.UnfollowInterest(context.Background(), user, models.InterestTag, "GOLANG"))
+	assert.NoError(t, storage.UnfollowInterest(context.Background(), user, models.InterestTag, "golang"))
+	interests, err = storage.GetInterests(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Len(t, interests, 1)
+}
+
+func TestGetJoinedCommunities(t *testing.T) {
+	storage := NewMemoryStorage()
+	user := testUser(t, storage)
+	other := testUser(t, storage)
+
+	zeta, err := storage.CreateCommunity(context.Background(), other, "zeta", "")
+	assert.NoError(t, err)
+	_, err = storage.CreateCommunity(context.Background(), other, "Alpha", "")
+	assert.NoError(t, err)
+	alpha, err := storage.GetCommunityByName(context.Background(), "alpha")
+	assert.NoError(t, err)
+
+	_, err = storage.JoinCommunity(context.Background(), user, zeta.ID)
+	assert.NoError(t, err)
+	_, err = storage.JoinCommunity(context.Background(), user, alpha.ID)
+	assert.NoError(t, err)
+
+	communities, err := storage.GetJoinedCommunities(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Len(t, communities, 2)
+	assert.Equal(t, "Alpha", communities[0].Name)
+	assert.Equal(t, "zeta", communities[1].Name)
+}
+
+func TestSearch_Posts(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	titled, err := storage.AddPost(context.Background(), author, nil, "GraphQL subscriptions", "How to <stream> updates", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	mentioned, err := storage.AddPost(context.Background(), author, nil, "Notes", "Subscriptions in GraphQL are great", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	_, err = storage.AddPost(context.Background(), author, nil, "GraphQL", "Queries only", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	page, err := storage.Search(context.Background(), SearchOptions{Query: "graphql SUBSCRIPTIONS"})
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 2)
+	// Совпадение в заголовке весит больше совпадения в тексте
+	assert.Equal(t, titled.ID, page.Results[0].Post.ID)
+	assert.Equal(t, mentioned.ID, page.Results[1].Post.ID)
+	assert.Greater(t, page.Results[0].Rank, page.Results[1].Rank)
+	assert.Equal(t, "How to &lt;stream&gt; updates", page.Results[0].Snippet)
+	assert.Equal(t, "<mark>Subscriptions</mark> in <mark>GraphQL</mark> are great", page.Results[1].Snippet)
+
+	// Правка поста сразу отражается в индексе
+	content := "Nothing relevant"
+	_, err = storage.UpdatePost(context.Background(), author, mentioned.ID, PostUpdate{Content: &content})
+	assert.NoError(t, err)
+	page, err = storage.Search(context.Background(), SearchOptions{Query: "subscriptions"})
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 1)
+
+	_, err = storage.Search(context.Background(), SearchOptions{Query: " !? "})
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+}
+
+func TestSearch_CommentsWithCursor(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	community, err := storage.CreateCommunity(context.Background(), author, "golang", "")
+	assert.NoError(t, err)
+	inside, err := storage.AddPost(context.Background(), author, &community.ID, "Inside", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	outside, err := storage.AddPost(context.Background(), author, nil, "Outside", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	var ids []string
+	for _, postID := range []string{inside.ID, inside.ID, outside.ID} {
+		comment, err := storage.AddComment(context.Background(), author, postID, nil, "generics are here", models.ModerationVisible)
+		assert.NoError(t, err)
+		ids = append(ids, comment.ID)
+	}
+	_, err = storage.DeleteComment(context.Background(), ids[1])
+	assert.NoError(t, err)
+
+	page, err := storage.Search(context.Background(), SearchOptions{Query: "generics", Type: SearchComments, First: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 1)
+	assert.True(t, page.HasNextPage)
+	assert.Equal(t, "<mark>generics</mark> are here", page.Results[0].Snippet)
+
+	after := SearchCursor(&page.Results[0])
+	next, err := storage.Search(context.Background(), SearchOptions{Query: "generics", Type: SearchComments, After: after})
+	assert.NoError(t, err)
+	assert.Len(t, next.Results, 1)
+	assert.False(t, next.HasNextPage)
+	assert.ElementsMatch(t, []string{ids[0], ids[2]}, []string{page.Results[0].Comment.ID, next.Results[0].Comment.ID})
+
+	page, err = storage.Search(context.Background(), SearchOptions{Query: "generics", Type: SearchComments, CommunityID: community.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 1)
+	assert.Equal(t, ids[0], page.Results[0].Comment.ID)
+}
+
+func TestSnippet(t *testing.T) {
+	words := strings.Fields(strings.Repeat("filler ", 40) + "needle " + strings.Repeat("tail ", 40))
+	result := snippet(strings.Join(words, " "), []string{"needle"})
+	assert.Contains(t, result, "<mark>needle</mark>")
+	assert.Len(t, strings.Fields(result), snippetMaxWords)
+	assert.True(t, strings.HasPrefix(result, "filler"))
+
+	assert.Equal(t, "a &amp; b", snippet("a & b", []string{"c"}))
+}
+
+func TestContentHTML(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "**bold**", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p><strong>bold</strong></p>\n", post.ContentHTML)
+
+	content := "_italic_"
+	updated, err := storage.UpdatePost(context.Background(), author, post.ID, PostUpdate{Content: &content})
+	assert.NoError(t, err)
+	assert.Equal(t, "<p><em>italic</em></p>\n", updated.ContentHTML)
+
+	comment, err := storage.AddComment(context.Background(), author, post.ID, nil, "<b>hi</b>", models.ModerationVisible)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>&lt;b&gt;hi&lt;/b&gt;</p>\n", comment.ContentHTML)
+
+	edited, err := storage.UpdateComment(context.Background(), author, comment.ID, "`code`")
+	assert.NoError(t, err)
+	assert.Equal(t, "<p><code>code</code></p>\n", edited.ContentHTML)
+
+	// Ограничение длины считается по исходному тексту, а не по HTML
+	long := strings.Repeat("<", MaxCommentLength)
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, long, models.ModerationVisible)
+	assert.NoError(t, err)
+}
+
+func TestNotifications(t *testing.T) {
+	storage := NewMemoryStorage()
+	recipient, actor := testUser(t, storage), testUser(t, storage)
+	post, err := storage.AddPost(context.Background(), recipient, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		comment, err := storage.AddComment(context.Background(), actor, post.ID, nil, "Reply "+strconv.Itoa(i), models.ModerationVisible)
+		assert.NoError(t, err)
+		n, err := storage.CreateNotification(context.Background(), models.Notification{
+			UserID: recipient, Kind: models.NotificationPostReply, ActorID: &actor, PostID: post.ID, CommentID: comment.ID,
+		})
+		assert.NoError(t, err)
+		assert.False(t, n.Read())
+		ids = append(ids, n.ID)
+	}
+
+	// Постранично от новых к старым
+	page, err := storage.GetNotifications(context.Background(), recipient, NotificationListOptions{First: 2})
+	assert.NoError(t, err)
+	assert.True(t, page.HasNextPage)
+	assert.Equal(t, []string{ids[2], ids[1]}, []string{page.Notifications[0].ID, page.Notifications[1].ID})
+
+	page, err = storage.GetNotifications(context.Background(), recipient, NotificationListOptions{
+		First: 2, After: NotificationCursor(page.Notifications[1]),
+	})
+	assert.NoError(t, err)
+	assert.False(t, page.HasNextPage)
+	assert.Len(t, page.Notifications, 1)
+	assert.Equal(t, ids[0], page.Notifications[0].ID)
+
+	// Чужие уведомления не отмечаются
+	marked, err := storage.MarkNotificationsRead(context.Background(), actor, []string{ids[0]})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, marked)
+
+	marked, err = storage.MarkNotificationsRead(context.Background(), recipient, []string{ids[0]})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	count, err := storage.CountUnreadNotifications(context.Background(), recipient)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	page, err = storage.GetNotifications(context.Background(), recipient, NotificationListOptions{UnreadOnly: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Notifications, 2)
+
+	// Пустой список отмечает все непрочитанные
+	marked, err = storage.MarkNotificationsRead(context.Background(), recipient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, marked)
+
+	_, err = storage.MarkNotificationsRead(context.Background(), recipient, []string{"not-a-uuid"})
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	_, err = storage.CreateNotification(context.Background(), models.Notification{UserID: uuid.NewString()})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestCreateNotification_PublishesEvent(t *testing.T) {
+	storage := NewMemoryStorage()
+	storage.Events = events.NewMemoryBroker()
+	recipient, other := testUser(t, storage), testUser(t, storage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := storage.Events.Subscribe(ctx, events.UserTopic(recipient))
+	assert.NoError(t, err)
+
+	_, err = storage.CreateNotification(context.Background(), models.Notification{UserID: other, Kind: models.NotificationMention})
+	assert.NoError(t, err)
+	created, err := storage.CreateNotification(context.Background(), models.Notification{UserID: recipient, Kind: models.NotificationMention})
+	assert.NoError(t, err)
+
+	select {
+	case event := <-ch:
+		assert.Equal(t, events.NotificationCreated, event.Type)
+		assert.Equal(t, created.ID, event.ID)
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+	}
+	cancel()
+}
+
+func TestReport_FlagsAfterThreshold(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "spam spam", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	reporters := make([]string, FlagReportThreshold)
+	for i := range reporters {
+		reporters[i] = testUser(t, storage)
+	}
+	for _, reporter := range reporters[:FlagReportThreshold-1] {
+		assert.NoError(t, storage.Report(context.Background(), reporter, post.ID, " spam "))
+		// Повторная жалоба не считается
+		assert.NoError(t, storage.Report(context.Background(), reporter, post.ID, "ads"))
+	}
+
+	queue, err := storage.GetModQueue(context.Background(), ModQueueOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, FlagReportThreshold-1, queue[0].ReportCount)
+	assert.Equal(t, []string{"spam"}, queue[0].Reasons)
+	assert.Equal(t, models.ModerationVisible, queue[0].Post.ModStatus)
+
+	assert.NoError(t, storage.Report(context.Background(), reporters[FlagReportThreshold-1], post.ID, "ads"))
+	flagged, err := storage.GetPostByID(context.Background(), post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ModerationFlagged, flagged.ModStatus)
+
+	// Скрытый пост не попадает в список и поиск
+	page, err := storage.GetAllPosts(context.Background(), PostListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Posts)
+	results, err := storage.Search(context.Background(), SearchOptions{Query: "spam"})
+	assert.NoError(t, err)
+	assert.Empty(t, results.Results)
+
+	// Одобрение закрывает жалобы и возвращает пост в списки
+	target, err := storage.Moderate(context.Background(), post.ID, models.ModerationApproved, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ModerationApproved, target.Post.ModStatus)
+	queue, err = storage.GetModQueue(context.Background(), ModQueueOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+	page, err = storage.GetAllPosts(context.Background(), PostListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Posts, 1)
+
+	// Одобренный пост жалобы больше не скрывают
+	for _, reporter := range reporters {
+		assert.NoError(t, storage.Report(context.Background(), reporter, post.ID, "spam"))
+	}
+	approved, err := storage.GetPostByID(context.Background(), post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ModerationApproved, approved.ModStatus)
+
+	assert.ErrorIs(t, storage.Report(context.Background(), reporters[0], post.ID, "  "), ErrEmptyReason)
+	assert.ErrorIs(t, storage.Report(context.Background(), reporters[0], uuid.NewString(), "spam"), ErrTargetNotFound)
+}
+
+func TestModerate_RemoveComment(t *testing.T) {
+	storage := NewMemoryStorage()
+	author, reporter := testUser(t, storage), testUser(t, storage)
+	community, err := storage.CreateCommunity(context.Background(), author, "golang", "")
+	assert.NoError(t, err)
+	post, err := storage.AddPost(context.Background(), author, &community.ID, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	other, err := storage.AddPost(context.Background(), author, nil, "Post 2", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	kept, err := storage.AddComment(context.Background(), author, post.ID, nil, "Useful", models.ModerationVisible)
+	assert.NoError(t, err)
+	spam, err := storage.AddComment(context.Background(), author, post.ID, nil, "Buy now", models.ModerationVisible)
+	assert.NoError(t, err)
+	elsewhere, err := storage.AddComment(context.Background(), author, other.ID, nil, "Buy later", models.ModerationVisible)
+	assert.NoError(t, err)
+	assert.NoError(t, storage.Report(context.Background(), reporter, spam.ID, "spam"))
+	assert.NoError(t, storage.Report(context.Background(), reporter, elsewhere.ID, "spam"))
+
+	queue, err := storage.GetModQueue(context.Background(), ModQueueOptions{CommunityID: community.ID})
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, spam.ID, queue[0].Comment.ID)
+
+	reason := "advertising"
+	target, err := storage.Moderate(context.Background(), spam.ID, models.ModerationRemoved, &reason)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ModerationRemoved, target.Comment.ModStatus)
+	assert.Equal(t, &reason, target.Comment.RemovalReason)
+	assert.Equal(t, models.ModerationVisible, target.PrevStatus)
+	assert.Nil(t, target.PrevReason)
+
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Comments, 1)
+	assert.Equal(t, kept.ID, page.Comments[0].ID)
+
+	// В дереве удалённый модератором комментарий остаётся, текст скрывает API
+	tree, err := storage.GetCommentTree(context.Background(), post.ID, -1, 0, CommentSortOld)
+	assert.NoError(t, err)
+	assert.Len(t, tree, 2)
+
+	queue, err = storage.GetModQueue(context.Background(), ModQueueOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, elsewhere.ID, queue[0].Comment.ID)
+
+	_, err = storage.Moderate(context.Background(), spam.ID, models.ModerationStatus("hidden"), nil)
+	assert.ErrorIs(t, err, ErrInvalidModeration)
+}
+
+func TestAddComment_Flagged(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	// Отмеченный фильтром комментарий сразу скрыт и ждёт модератора
+	flagged, err := storage.AddComment(context.Background(), author, post.ID, nil, "Buy now", models.ModerationFlagged)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ModerationFlagged, flagged.ModStatus)
+
+	page, err := storage.GetCommentsByPostID(context.Background(), post.ID, CommentListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Comments)
+	queue, err := storage.GetModQueue(context.Background(), ModQueueOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, flagged.ID, queue[0].Comment.ID)
+
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "Removed", models.ModerationRemoved)
+	assert.ErrorIs(t, err, ErrInvalidModeration)
+	_, err = storage.AddPost(context.Background(), author, nil, "Post 2", "Content", nil, true, models.ModerationApproved)
+	assert.ErrorIs(t, err, ErrInvalidModeration)
+}
+
+func TestLockComment(t *testing.T) {
+	storage := NewMemoryStorage()
+	author := testUser(t, storage)
+	post, err := storage.AddPost(context.Background(), author, nil, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	root, err := storage.AddComment(context.Background(), author, post.ID, nil, "Root", models.ModerationVisible)
+	assert.NoError(t, err)
+	reply, err := storage.AddComment(context.Background(), author, post.ID, &root.ID, "Reply", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	locked, err := storage.LockComment(context.Background(), root.ID, true)
+	assert.NoError(t, err)
+	assert.True(t, locked.Locked)
+
+	// Блокировка распространяется на всё поддерево
+	_, err = storage.AddComment(context.Background(), author, post.ID, &root.ID, "Another", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommentLocked)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &reply.ID, "Nested", models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrCommentLocked)
+	_, err = storage.AddComment(context.Background(), author, post.ID, nil, "New thread", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	_, err = storage.LockComment(context.Background(), root.ID, false)
+	assert.NoError(t, err)
+	_, err = storage.AddComment(context.Background(), author, post.ID, &reply.ID, "Nested", models.ModerationVisible)
+	assert.NoError(t, err)
+}
+
+func TestBanUser_BlocksPostsAndComments(t *testing.T) {
+	storage := NewMemoryStorage()
+	moderator := testUser(t, storage)
+	user := testUser(t, storage)
+	community, err := storage.CreateCommunity(context.Background(), moderator, "golang", "")
+	assert.NoError(t, err)
+	post, err := storage.AddPost(context.Background(), moderator, &community.ID, "Post 1", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	ban, err := storage.BanUser(context.Background(), BanOptions{CommunityID: community.ID, UserID: user,
+		ModeratorID: moderator, Reason: " spam ", Duration: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, "spam", ban.Reason)
+	assert.NotNil(t, ban.ExpiresAt)
+
+	_, err = storage.AddPost(context.Background(), user, &community.ID, "Post 2", "Content", nil, true, models.ModerationVisible)
+	assert.ErrorIs(t, err, ErrBanned)
+	_, err = storage.AddComment(context.Background(), user, post.ID, nil, "Comment", models.ModerationVisible)
+	var banned *BannedError
+	assert.ErrorAs(t, err, &banned)
+	assert.Equal(t, "spam", banned.Reason)
+	assert.Equal(t, ban.ExpiresAt, banned.ExpiresAt)
+
+	// Вне сообщества бан не действует
+	_, err = storage.AddPost(context.Background(), user, nil, "Post 3", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+
+	bans, err := storage.GetBans(context.Background(), community.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bans, 1)
+
+	assert.NoError(t, storage.UnbanUser(context.Background(), community.ID, user))
+	assert.NoError(t, storage.UnbanUser(context.Background(), community.ID, user))
+	_, err = storage.AddComment(context.Background(), user, post.ID, nil, "Comment", models.ModerationVisible)
+	assert.NoError(t, err)
+
+	_, err = storage.BanUser(context.Background(), BanOptions{CommunityID: community.ID, UserID: user,
+		ModeratorID: moderator, Reason: "spam", Duration: MaxBanDuration + time.Hour})
+	assert.ErrorIs(t, err, ErrInvalidBan)
+	_, err = storage.BanUser(context.Background(), BanOptions{CommunityID: community.ID, UserID: user,
+		ModeratorID: moderator, Reason: "  "})
+	assert.ErrorIs(t, err, ErrEmptyReason)
+}
+
+func TestBanUser_Expires(t *testing.T) {
+	storage := NewMemoryStorage()
+	moderator := testUser(t, storage)
+	user := testUser(t, storage)
+	community, err := storage.CreateCommunity(context.Background(), moderator, "golang", "")
+	assert.NoError(t, err)
+
+	_, err = storage.BanUser(context.Background(), BanOptions{CommunityID: community.ID, UserID: user,
+		ModeratorID: moderator, Reason: "spam", Duration: time.Hour})
+	assert.NoError(t, err)
+
+	// Переносим окончание бана в прошлое
+	expired := time.Now().Add(-time.Minute)
+	storage.bans[community.ID][user].ExpiresAt = &expired
+
+	_, err = storage.AddPost(context.Background(), user, &community.ID, "Post", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	bans, err := storage.GetBans(context.Background(), community.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, bans)
+	_, err = storage.AppealBan(context.Background(), community.ID, user, "Sorry")
+	assert.ErrorIs(t, err, ErrBanNotFound)
+}
+
+func TestAppealBan_ModQueue(t *testing.T) {
+	storage := NewMemoryStorage()
+	moderator := testUser(t, storage)
+	user := testUser(t, storage)
+	muted := testUser(t, storage)
+	community, err := storage.CreateCommunity(context.Background(), moderator, "golang", "")
+	assert.NoError(t, err)
+
+	_, err = storage.BanUser(context.Background(), BanOptions{CommunityID: community.ID, UserID: user,
+		ModeratorID: moderator, Reason: "spam"})
+	assert.NoError(t, err)
+	_, err = storage.BanUser(context.Background(), BanOptions{CommunityID: community.ID, UserID: muted,
+		ModeratorID: moderator, Reason: "spam", Muted: true})
+	assert.NoError(t, err)
+
+	appeal, err := storage.AppealBan(context.Background(), community.ID, user, "It was a mistake")
+	assert.NoError(t, err)
+	_, err = storage.AppealBan(context.Background(), community.ID, user, "Please")
+	assert.ErrorIs(t, err, ErrAppealPending)
+	_, err = storage.AppealBan(context.Background(), community.ID, muted, "Please")
+	assert.ErrorIs(t, err, ErrAppealsMuted)
+
+	queue, err := storage.GetModQueue(context.Background(), ModQueueOptions{CommunityID: community.ID})
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, appeal.ID, queue[0].ID())
+	assert.Equal(t, []string{"spam"}, queue[0].Reasons)
+
+	resolved, err := storage.ResolveAppeal(context.Background(), appeal.ID, true)
+	assert.NoError(t, err)
+	assert.True(t, resolved.Accepted)
+	assert.NotNil(t, resolved.ResolvedAt)
+	_, err = storage.ResolveAppeal(context.Background(), appeal.ID, false)
+	assert.ErrorIs(t, err, ErrAppealResolved)
+
+	// Принятая апелляция снимает бан и уходит из очереди
+	_, err = storage.AddPost(context.Background(), user, &community.ID, "Post", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	queue, err = storage.GetModQueue(context.Background(), ModQueueOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+}
+
+func TestTrainClassifier(t *testing.T) {
+	storage := NewMemoryStorage()
+	ctx := context.Background()
+
+	assert.NoError(t, storage.TrainClassifier(ctx, true, []string{"buy", "pills", "buy"}))
+	assert.NoError(t, storage.TrainClassifier(ctx, false, []string{"buy", "golang"}))
+
+	stats, err := storage.GetClassifierStats(ctx, []string{"buy", "pills", "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.SpamDocs)
+	assert.Equal(t, 1, stats.HamDocs)
+	// Повтор слова в документе учитывается один раз, неизвестные слова не возвращаются
+	assert.Equal(t, map[string]TokenCounts{"buy": {Spam: 1, Ham: 1}, "pills": {Spam: 1}}, stats.Tokens)
+}
+
+func TestFilterDecisions_NewestFirst(t *testing.T) {
+	storage := NewMemoryStorage()
+	ctx := context.Background()
+
+	for _, verdict := range []models.FilterVerdict{models.FilterFlag, models.FilterReject} {
+		_, err := storage.RecordFilterDecision(ctx, models.FilterDecision{Kind: models.ContentComment, Verdict: verdict, Filter: "bayes"})
+		assert.NoError(t, err)
+	}
+
+	decisions, err := storage.GetFilterDecisions(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, decisions, 1)
+	assert.Equal(t, models.FilterReject, decisions[0].Verdict)
+	assert.NotEmpty(t, decisions[0].ID)
+}
+
+func TestEvents_ThreadLifecycle(t *testing.T) {
+	storage := NewMemoryStorage()
+	storage.Events = events.NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	author, voter := testUser(t, storage), testUser(t, storage)
+	community, err := storage.CreateCommunity(ctx, author, "golang", "")
+	assert.NoError(t, err)
+
+	// receive возвращает типы событий, накопившихся в подписке
+	receive := func(ch <-chan events.Event) []string {
+		var types []string
+		for len(ch) > 0 {
+			types = append(types, (<-ch).Type)
+		}
+		return types
+	}
+	all, err := storage.Events.Subscribe(ctx, events.PostsTopic)
+	assert.NoError(t, err)
+	inCommunity, err := storage.Events.Subscribe(ctx, events.CommunityTopic(community.ID))
+	assert.NoError(t, err)
+
+	_, err = storage.AddPost(ctx, author, nil, "Outside", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	post, err := storage.AddPost(ctx, author, &community.ID, "Inside", "Content", nil, true, models.ModerationVisible)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{events.PostAdded, events.PostAdded}, receive(all))
+	assert.Equal(t, []string{events.PostAdded}, receive(inCommunity))
+
+	thread, err := storage.Events.Subscribe(ctx, events.PostTopic(post.ID))
+	assert.NoError(t, err)
+	comment, err := storage.AddComment(ctx, author, post.ID, nil, "Comment", models.ModerationVisible)
+	assert.NoError(t, err)
+	score, err := storage.Events.Subscribe(ctx, events.ScoreTopic(comment.ID))
+	assert.NoError(t, err)
+
+	_, err = storage.UpdateComment(ctx, author, comment.ID, "Edited")
+	assert.NoError(t, err)
+	_, err = storage.LockComment(ctx, comment.ID, true)
+	assert.NoError(t, err)
+	_, err = storage.Moderate(ctx, comment.ID, models.ModerationApproved, nil)
+	assert.NoError(t, err)
+	_, err = storage.Vote(ctx, voter, comment.ID, models.VoteUp)
+	assert.NoError(t, err)
+	_, err = storage.DeleteComment(ctx, comment.ID)
+	assert.NoError(t, err)
+	disabled := false
+	_, err = storage.UpdatePost(ctx, author, post.ID, PostUpdate{AllowComments: &disabled})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{events.CommentAdded, events.CommentUpdated, events.CommentUpdated, events.CommentUpdated,
+		events.CommentDeleted, events.PostUpdated}, receive(thread))
+
+	event := <-score
+	assert.Equal(t, events.ScoreChanged, event.Type)
+	var target Target
+	_, err = event.Decode(&target)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, target.Comment.Upvotes)
+}
+
+// testUser создаёт пользователя для тестов и возвращает его ID
+func testUser(t *testing.T, storage *MemoryStorage) string {
+	t.Helper()
+	user, err := storage.CreateUser(context.Background(), "user_"+uuid.NewString()[:8], "hash")
+	assert.NoError(t, err)
+	return user.ID
+}
+
+func TestMapPostgresError(t *testing.T) {
+	violation := func(code pq.ErrorCode, table, constraint string) error {
+		return &pq.Error{Code: code, Table: table, Constraint: constraint}
+	}
+
+	// Внешний ключ переводится по ссылающейся колонке, а не по ожидаемому объекту
+	err := mapPostgresError(violation(pgForeignKeyViolation, "posts", "posts_community_id_fkey"), ErrUserNotFound)
+	assert.ErrorIs(t, err, ErrCommunityNotFound)
+	err = mapPostgresError(violation(pgForeignKeyViolation, "comments", "comments_parent_id_fkey"), ErrPostNotFound)
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+	err = mapPostgresError(violation(pgForeignKeyViolation, "posts", "posts_unknown_fkey"), ErrUserNotFound)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	assert.ErrorIs(t, mapPostgresError(violation("23505", "users", "users_username_idx"), ErrUserNotFound), ErrUsernameTaken)
+	assert.ErrorIs(t, mapPostgresError(violation("23514", "posts", "posts_tags_check"), ErrPostNotFound), ErrTooManyTags)
+	assert.ErrorIs(t, mapPostgresError(violation("23514", "comments", "comments_content_check"), ErrCommentNotFound), ErrContentTooLong)
+	// Нарушение ограничения, которое проверяется до запроса, - внутренняя ошибка
+	assert.ErrorIs(t, mapPostgresError(violation("23514", "votes", "votes_check"), ErrTargetNotFound), ErrInternal)
+}
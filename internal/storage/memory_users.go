@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *MemoryStorage) CreateUser(ctx context.Context, username, passwordHash string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Creating user %s", username)
+	key := strings.ToLower(username)
+	if _, exists := s.usernames[key]; exists {
+		return nil, ErrUsernameTaken
+	}
+
+	user := models.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         models.RoleUser,
+		CreatedAt:    s.now(),
+	}
+	s.users[user.ID] = user
+	s.usernames[key] = user.ID
+	return &user, nil
+}
+
+func (s *MemoryStorage) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	user, exists := s.users[id]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.usernames[strings.ToLower(username)]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	user := s.users[id]
+	return &user, nil
+}
+
+func (s *MemoryStorage) SetUserRole(ctx context.Context, userID string, role models.Role) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("Setting role of user %s to %s", userID, role)
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	user, exists := s.users[userID]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	user.Role = role
+	s.users[userID] = user
+	return &user, nil
+}
+
+func (s *MemoryStorage) CreateSession(ctx context.Context, session models.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[session.UserID]; !exists {
+		return ErrUserNotFound
+	}
+	if _, exists := s.sessions[session.TokenHash]; exists {
+		return ErrConflict
+	}
+	s.sessions[session.TokenHash] = session
+	s.pruneSessions(time.Now())
+	return nil
+}
+
+func (s *MemoryStorage) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[tokenHash]
+	if !exists || session.Expired(time.Now()) {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *MemoryStorage) DeleteSession(ctx context.Context, tokenHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[tokenHash]; !exists {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, tokenHash)
+	return nil
+}
+
+// checkAuthor проверяет, что автор контента существует. Вызывается под блокировкой
+func (s *MemoryStorage) checkAuthor(authorID string) error {
+	if err := validateID(authorID); err != nil {
+		return err
+	}
+	if _, exists := s.users[authorID]; !exists {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// pruneSessions удаляет истёкшие сессии. Вызывается под блокировкой на запись
+func (s *MemoryStorage) pruneSessions(now time.Time) {
+	for hash, session := range s.sessions {
+		if session.Expired(now) {
+			delete(s.sessions, hash)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+func (s *MemoryStorage) Vote(ctx context.Context, userID, targetID string, direction models.VoteDirection) (*Target, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("User %s votes %d for %s", userID, direction, targetID)
+	if !direction.Valid() {
+		return nil, ErrInvalidVote
+	}
+	if err := s.checkAuthor(userID); err != nil {
+		return nil, err
+	}
+	if err := validateID(targetID); err != nil {
+		return nil, err
+	}
+
+	if post, exists := s.posts[targetID]; exists {
+		previous := s.setVote(userID, targetID, direction)
+		post.Upvotes, post.Downvotes = tally(post.Upvotes, post.Downvotes, previous, direction)
+		s.posts[targetID] = post
+		target := &Target{Post: &post}
+		publishScore(ctx, s.Events, target)
+		return target, nil
+	}
+
+	comment, err := s.liveComment(targetID)
+	if errors.Is(err, ErrCommentNotFound) {
+		return nil, ErrTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	previous := s.setVote(userID, targetID, direction)
+	comment.Upvotes, comment.Downvotes = tally(comment.Upvotes, comment.Downvotes, previous, direction)
+	c := *comment
+	target := &Target{Comment: &c}
+	publishScore(ctx, s.Events, target)
+	return target, nil
+}
+
+func (s *MemoryStorage) GetVote(ctx context.Context, userID, targetID string) (models.VoteDirection, error) {
+	if err := ctx.Err(); err != nil {
+		return models.VoteNone, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.votes[targetID][userID], nil
+}
+
+// setVote сохраняет голос и возвращает предыдущий. Вызывается под блокировкой на запись
+func (s *MemoryStorage) setVote(userID, targetID string, direction models.VoteDirection) models.VoteDirection {
+	votes := s.votes[targetID]
+	previous := votes[userID]
+	if direction == models.VoteNone {
+		delete(votes, userID)
+		return previous
+	}
+	if votes == nil {
+		votes = make(map[string]models.VoteDirection)
+		s.votes[targetID] = votes
+	}
+	votes[userID] = direction
+	return previous
+}
+
+// tally возвращает счётчики голосов после замены голоса from на to
+func tally(upvotes, downvotes int, from, to models.VoteDirection) (int, int) {
+	switch from {
+	case models.VoteUp:
+		upvotes--
+	case models.VoteDown:
+		downvotes--
+	}
+	switch to {
+	case models.VoteUp:
+		upvotes++
+	case models.VoteDown:
+		downvotes++
+	}
+	return upvotes, downvotes
+}
@@ -1,40 +1,432 @@
 package storage
 
 import (
+	"context"
+
 	"github.com/MosinFAM/graphql-posts/internal/models"
 	"github.com/stretchr/testify/mock"
 )
 
+// MockStorage - мок хранилища для тестов резолверов.
+// Отменённый контекст возвращает ошибку до обращения к ожиданиям мока.
 type MockStorage struct {
 	mock.Mock
 }
 
-func (m *MockStorage) AddPost(title, content string, allowComments bool) (models.Post, error) {
-	args := m.Called(title, content, allowComments)
+func (m *MockStorage) AddPost(ctx context.Context, authorID string, communityID *string, title, content string, tags []string,
+	allowComments bool, status models.ModerationStatus) (models.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Post{}, err
+	}
+	args := m.Called(authorID, communityID, title, content, tags, allowComments, status)
 	return args.Get(0).(models.Post), args.Error(1)
 }
 
-func (m *MockStorage) GetAllPosts() ([]models.Post, error) {
-	args := m.Called()
-	return args.Get(0).([]models.Post), args.Error(1)
+func (m *MockStorage) GetAllPosts(ctx context.Context, opts PostListOptions) (*PostPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(opts)
+	return args.Get(0).(*PostPage), args.Error(1)
 }
 
-func (m *MockStorage) GetPostByID(id string) (*models.Post, error) {
+func (m *MockStorage) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	args := m.Called(id)
 	return args.Get(0).(*models.Post), args.Error(1)
 }
 
-func (m *MockStorage) AddComment(postID string, parentID *string, content string) (*models.Comment, error) {
-	args := m.Called(postID, parentID, content)
+func (m *MockStorage) AddComment(ctx context.Context, authorID, postID string, parentID *string, content string,
+	status models.ModerationStatus) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(authorID, postID, parentID, content, status)
 	return args.Get(0).(*models.Comment), args.Error(1)
 }
 
-func (m *MockStorage) GetCommentsByPostID(postID string, limit, offset int) ([]*models.Comment, error) {
-	args := m.Called(postID, limit, offset)
+func (m *MockStorage) GetCommentsByPostID(ctx context.Context, postID string, opts CommentListOptions) (*CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(postID, opts)
+	return args.Get(0).(*CommentPage), args.Error(1)
+}
+
+func (m *MockStorage) GetReplies(ctx context.Context, parentID string, opts CommentListOptions) (*CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(parentID, opts)
+	return args.Get(0).(*CommentPage), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentTree(ctx context.Context, postID string, maxDepth, maxChildren int, sort CommentSort) ([]*models.CommentNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(postID, maxDepth, maxChildren, sort)
+	return args.Get(0).([]*models.CommentNode), args.Error(1)
+}
+
+func (m *MockStorage) GetThread(ctx context.Context, commentID string) ([]*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(commentID)
 	return args.Get(0).([]*models.Comment), args.Error(1)
 }
 
-func (m *MockStorage) SubscribeToComments(postID string) (<-chan *models.Comment, error) {
+func (m *MockStorage) CreateUser(ctx context.Context, username, passwordHash string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(username, passwordHash)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStorage) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(id)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(username)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStorage) SetUserRole(ctx context.Context, userID string, role models.Role) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, role)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStorage) CreateSession(ctx context.Context, session models.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(tokenHash)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockStorage) DeleteSession(ctx context.Context, tokenHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockStorage) UpdatePost(ctx context.Context, editorID, id string, update PostUpdate) (*models.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(editorID, id, update)
+	return args.Get(0).(*models.Post), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentByID(ctx context.Context, id string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(id)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockStorage) UpdateComment(ctx context.Context, editorID, id, content string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(editorID, id, content)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockStorage) DeleteComment(ctx context.Context, id string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(id)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockStorage) GetPostRevisions(ctx context.Context, postID string) ([]*models.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	args := m.Called(postID)
-	return args.Get(0).(chan *models.Comment), args.Error(1)
+	return args.Get(0).([]*models.Revision), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentRevisions(ctx context.Context, commentID string) ([]*models.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(commentID)
+	return args.Get(0).([]*models.Revision), args.Error(1)
+}
+
+func (m *MockStorage) Vote(ctx context.Context, userID, targetID string, direction models.VoteDirection) (*Target, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, targetID, direction)
+	return args.Get(0).(*Target), args.Error(1)
+}
+
+func (m *MockStorage) GetVote(ctx context.Context, userID, targetID string) (models.VoteDirection, error) {
+	if err := ctx.Err(); err != nil {
+		return models.VoteNone, err
+	}
+	args := m.Called(userID, targetID)
+	return args.Get(0).(models.VoteDirection), args.Error(1)
+}
+
+func (m *MockStorage) CreateCommunity(ctx context.Context, creatorID, name, description string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(creatorID, name, description)
+	return args.Get(0).(*models.Community), args.Error(1)
+}
+
+func (m *MockStorage) GetCommunityByID(ctx context.Context, id string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(id)
+	return args.Get(0).(*models.Community), args.Error(1)
+}
+
+func (m *MockStorage) GetCommunityByName(ctx context.Context, name string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(name)
+	return args.Get(0).(*models.Community), args.Error(1)
+}
+
+func (m *MockStorage) JoinCommunity(ctx context.Context, userID, communityID string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, communityID)
+	return args.Get(0).(*models.Community), args.Error(1)
+}
+
+func (m *MockStorage) LeaveCommunity(ctx context.Context, userID, communityID string) (*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, communityID)
+	return args.Get(0).(*models.Community), args.Error(1)
+}
+
+func (m *MockStorage) IsMember(ctx context.Context, userID, communityID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	args := m.Called(userID, communityID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetJoinedCommunities(ctx context.Context, userID string) ([]*models.Community, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID)
+	return args.Get(0).([]*models.Community), args.Error(1)
+}
+
+func (m *MockStorage) FollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) (*models.Interest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, kind, target)
+	return args.Get(0).(*models.Interest), args.Error(1)
+}
+
+func (m *MockStorage) UnfollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(userID, kind, target)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetInterests(ctx context.Context, userID string) ([]*models.Interest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID)
+	return args.Get(0).([]*models.Interest), args.Error(1)
+}
+
+func (m *MockStorage) Search(ctx context.Context, opts SearchOptions) (*SearchPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(opts)
+	return args.Get(0).(*SearchPage), args.Error(1)
+}
+
+func (m *MockStorage) CreateNotification(ctx context.Context, notification models.Notification) (*models.Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(notification)
+	return args.Get(0).(*models.Notification), args.Error(1)
+}
+
+func (m *MockStorage) GetNotifications(ctx context.Context, userID string, opts NotificationListOptions) (*NotificationPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(userID, opts)
+	return args.Get(0).(*NotificationPage), args.Error(1)
+}
+
+func (m *MockStorage) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) MarkNotificationsRead(ctx context.Context, userID string, ids []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	args := m.Called(userID, ids)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) Report(ctx context.Context, reporterID, targetID, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(reporterID, targetID, reason)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetModQueue(ctx context.Context, opts ModQueueOptions) ([]*ModQueueItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(opts)
+	return args.Get(0).([]*ModQueueItem), args.Error(1)
+}
+
+func (m *MockStorage) Moderate(ctx context.Context, targetID string, status models.ModerationStatus, reason *string) (*ModerationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(targetID, status, reason)
+	return args.Get(0).(*ModerationResult), args.Error(1)
+}
+
+func (m *MockStorage) LockComment(ctx context.Context, id string, locked bool) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(id, locked)
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockStorage) BanUser(ctx context.Context, opts BanOptions) (*models.Ban, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(opts)
+	return args.Get(0).(*models.Ban), args.Error(1)
+}
+
+func (m *MockStorage) UnbanUser(ctx context.Context, communityID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(communityID, userID)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetBans(ctx context.Context, communityID string) ([]*models.Ban, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(communityID)
+	return args.Get(0).([]*models.Ban), args.Error(1)
+}
+
+func (m *MockStorage) AppealBan(ctx context.Context, communityID, userID, message string) (*models.BanAppeal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(communityID, userID, message)
+	return args.Get(0).(*models.BanAppeal), args.Error(1)
+}
+
+func (m *MockStorage) ResolveAppeal(ctx context.Context, appealID string, accepted bool) (*models.BanAppeal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(appealID, accepted)
+	return args.Get(0).(*models.BanAppeal), args.Error(1)
+}
+
+func (m *MockStorage) TrainClassifier(ctx context.Context, spam bool, tokens []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(spam, tokens)
+	return args.Error(0)
+}
+
+func (m *MockStorage) UntrainClassifier(ctx context.Context, spam bool, tokens []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	args := m.Called(spam, tokens)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetClassifierStats(ctx context.Context, tokens []string) (*ClassifierStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(tokens)
+	return args.Get(0).(*ClassifierStats), args.Error(1)
+}
+
+func (m *MockStorage) RecordFilterDecision(ctx context.Context, decision models.FilterDecision) (*models.FilterDecision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(decision)
+	return args.Get(0).(*models.FilterDecision), args.Error(1)
+}
+
+func (m *MockStorage) GetFilterDecisions(ctx context.Context, first int) ([]*models.FilterDecision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args := m.Called(first)
+	return args.Get(0).([]*models.FilterDecision), args.Error(1)
 }
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// FlagReportThreshold - число открытых жалоб, после которого непроверенный
+// модератором пост или комментарий скрывается до проверки
+const FlagReportThreshold = 3
+
+// ModQueueOptions - параметры очереди модерации. Пустой CommunityID означает
+// все посты и комментарии, иначе - посты сообщества и комментарии к ним
+type ModQueueOptions struct {
+	CommunityID string
+	First       int
+}
+
+// ModQueueItem - пост или комментарий, ожидающий решения модератора: на него
+// есть открытые жалобы или он скрыт до проверки. Reasons - различные причины
+// открытых жалоб в алфавитном порядке.
+// Открытая апелляция на действующий бан тоже попадает в очередь: тогда заполнено
+// только поле Appeal, а Reasons содержит причину бана
+type ModQueueItem struct {
+	Target
+	Appeal         *models.BanAppeal
+	ReportCount    int
+	Reasons        []string
+	LastReportedAt time.Time // время последней жалобы, создания объекта или апелляции
+}
+
+// ID возвращает ID объекта элемента очереди
+func (i *ModQueueItem) ID() string {
+	if i.Appeal != nil {
+		return i.Appeal.ID
+	}
+	return i.Target.ID()
+}
+
+// ModerationResult - пост или комментарий после решения модератора и его статус
+// модерации и причина удаления до решения
+type ModerationResult struct {
+	Target
+	PrevStatus models.ModerationStatus
+	PrevReason *string
+}
+
+// BanOptions - параметры бана пользователя в сообществе. Нулевой Duration
+// означает бессрочный бан, Muted запрещает обжаловать бан
+type BanOptions struct {
+	CommunityID string
+	UserID      string
+	ModeratorID string
+	Reason      string
+	Duration    time.Duration
+	Muted       bool
+}
+
+// check проверяет параметры бана и возвращает нормализованную причину
+func (o BanOptions) check() (string, error) {
+	if o.Duration < 0 || o.Duration > MaxBanDuration {
+		return "", ErrInvalidBan
+	}
+	for _, id := range []string{o.CommunityID, o.UserID, o.ModeratorID} {
+		if err := validateID(id); err != nil {
+			return "", err
+		}
+	}
+	return normalizeReason(o.Reason)
+}
+
+// expiresAt возвращает время окончания бана, выданного в момент now
+func (o BanOptions) expiresAt(now time.Time) *time.Time {
+	if o.Duration == 0 {
+		return nil
+	}
+	expiresAt := now.Add(o.Duration)
+	return &expiresAt
+}
+
+// resolves - закрывает ли решение модератора открытые жалобы
+func resolves(status models.ModerationStatus) bool {
+	return status == models.ModerationApproved || status == models.ModerationRemoved
+}
+
+// checkModeration проверяет решение модератора и причину удаления; причина
+// сохраняется только у удалённых объектов
+func checkModeration(status models.ModerationStatus, reason *string) (*string, error) {
+	if !status.Valid() {
+		return nil, ErrInvalidModeration
+	}
+	if status != models.ModerationRemoved || reason == nil {
+		return nil, nil
+	}
+	normalized, err := normalizeReason(*reason)
+	if err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// checkInitialStatus проверяет статус нового поста или комментария: он публикуется
+// видимым или скрытым до проверки модератором, например по решению фильтра контента
+func checkInitialStatus(status models.ModerationStatus) error {
+	if status != models.ModerationVisible && status != models.ModerationFlagged {
+		return ErrInvalidModeration
+	}
+	return nil
+}
+
+// sortModQueue упорядочивает очередь: больше жалоб - выше, при равенстве
+// выше объект с более свежей жалобой, затем - по ID. Возвращает не больше limit элементов
+func sortModQueue(items []*ModQueueItem, limit int) []*ModQueueItem {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.ReportCount != b.ReportCount {
+			return a.ReportCount > b.ReportCount
+		}
+		if !a.LastReportedAt.Equal(b.LastReportedAt) {
+			return a.LastReportedAt.After(b.LastReportedAt)
+		}
+		return a.ID() > b.ID()
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// ID возвращает ID поста или комментария
+func (t *Target) ID() string {
+	if t.Post != nil {
+		return t.Post.ID
+	}
+	return t.Comment.ID
+}
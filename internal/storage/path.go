@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pathSeparator разделяет сегменты материализованного пути (как в ltree)
+const pathSeparator = "."
+
+// commentPath строит материализованный путь комментария: путь родителя и сегмент
+// фиксированной длины из времени создания и ID. Сегменты упорядочивают соседей
+// по (CreatedAt, ID), поэтому побайтовая сортировка путей даёт порядок обхода треда
+// в глубину. Формат совпадает с заполнением колонки path в миграции.
+func commentPath(parentPath string, createdAt time.Time, id string) string {
+	segment := fmt.Sprintf("%016x%s", createdAt.UnixMicro(), strings.ReplaceAll(id, "-", ""))
+	if parentPath == "" {
+		return segment
+	}
+	return parentPath + pathSeparator + segment
+}
@@ -1,12 +1,16 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/markdown"
 	"github.com/MosinFAM/graphql-posts/internal/models"
 
 	"github.com/google/uuid"
@@ -15,186 +19,718 @@ import (
 
 // PostgresStorage - хранилище в PostgreSQL
 type PostgresStorage struct {
-	DB         *sql.DB
-	DataSource string
+	DB     *sql.DB
+	Events events.Broker // nil - события не публикуются
 }
 
-func NewPostgresStorage(db *sql.DB, dataSource string) *PostgresStorage {
-	return &PostgresStorage{DB: db, DataSource: dataSource}
+func NewPostgresStorage(db *sql.DB, broker events.Broker) *PostgresStorage {
+	return &PostgresStorage{DB: db, Events: broker}
 }
 
-func (s *PostgresStorage) GetAllPosts() ([]models.Post, error) {
+func (s *PostgresStorage) GetAllPosts(ctx context.Context, opts PostListOptions) (*PostPage, error) {
 	log.Println("Fetching all posts from database")
-	rows, err := s.DB.Query("SELECT id, title, content, allow_comments FROM posts")
+
+	sortOrder := opts.SortOrder()
+	rowKey := postSortKeySQL(sortOrder, "created_at", "upvotes", "downvotes")
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions = append(conditions, listedSQL)
+	if since, limited := opts.TimeRange.Since(time.Now().UTC()); limited {
+		conditions = append(conditions, "created_at >= "+arg(since))
+	}
+	if len(opts.AuthorIDs) > 0 {
+		if err := validateIDs(opts.AuthorIDs); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "author_id = ANY("+arg(pq.Array(opts.AuthorIDs))+"::uuid[])")
+	}
+	if len(opts.CommunityIDs) > 0 {
+		if err := validateIDs(opts.CommunityIDs); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "community_id = ANY("+arg(pq.Array(opts.CommunityIDs))+"::uuid[])")
+	}
+	if len(opts.Tags) > 0 {
+		conditions = append(conditions, "tags && "+arg(pq.Array(opts.Tags))+"::text[]")
+	}
+	if opts.After != "" {
+		cursor, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		// Ключ курсора вычисляется тем же выражением, что и ключ строки
+		cursorKey := postSortKeySQL(sortOrder,
+			arg(cursor.CreatedAt)+"::timestamp", arg(cursor.Upvotes)+"::int", arg(cursor.Downvotes)+"::int")
+		conditions = append(conditions, fmt.Sprintf("(%s, id) < (%s, %s::uuid)", rowKey, cursorKey, arg(cursor.ID)))
+	}
+
+	query := "SELECT " + postColumns + " FROM posts WHERE " + strings.Join(conditions, " AND ")
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	limit := PageSize(opts.First)
+	query += fmt.Sprintf(" ORDER BY %s DESC, id DESC LIMIT %s", rowKey, arg(limit+1))
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Println("Error fetching posts:", err)
-		return nil, err
+		return nil, mapPostgresError(err, ErrPostNotFound)
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	page := &PostPage{Posts: make([]models.Post, 0, limit)}
 	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AllowComments); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			log.Println("Error scanning post row:", err)
-			return nil, err
+			return nil, mapPostgresError(err, ErrPostNotFound)
 		}
-		posts = append(posts, post)
+		if len(page.Posts) == limit {
+			page.HasNextPage = true
+			break
+		}
+		page.Posts = append(page.Posts, *post)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating post rows:", err)
+		return nil, mapPostgresError(err, ErrPostNotFound)
 	}
 	log.Println("Successfully fetched posts")
-	return posts, nil
+	return page, nil
 }
 
-func (s *PostgresStorage) GetPostByID(id string) (*models.Post, error) {
+func (s *PostgresStorage) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
 	log.Printf("Fetching post with ID: %s", id)
-	var post models.Post
-	err := s.DB.QueryRow("SELECT id, title, content, allow_comments FROM posts WHERE id=$1", id).
-		Scan(&post.ID, &post.Title, &post.Content, &post.AllowComments)
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	post, err := scanPost(s.DB.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id=$1", id))
 	if err != nil {
 		log.Println("Error fetching post:", err)
-		return nil, err
+		return nil, mapPostgresError(err, ErrPostNotFound)
 	}
-	return &post, nil
+	return post, nil
 }
 
-func (s *PostgresStorage) AddPost(title, content string, allowComments bool) (models.Post, error) {
+func (s *PostgresStorage) AddPost(ctx context.Context, authorID string, communityID *string, title, content string, tags []string,
+	allowComments bool, status models.ModerationStatus) (models.Post, error) {
+	if err := validateID(authorID); err != nil {
+		return models.Post{}, err
+	}
+	if err := checkInitialStatus(status); err != nil {
+		return models.Post{}, err
+	}
+	if err := validatePostContent(content); err != nil {
+		return models.Post{}, err
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if communityID != nil {
+		if _, err := s.GetCommunityByID(ctx, *communityID); err != nil {
+			return models.Post{}, err
+		}
+		if err := s.checkBan(ctx, *communityID, authorID); err != nil {
+			return models.Post{}, err
+		}
+	}
 	post := models.Post{
 		ID:            uuid.New().String(),
+		AuthorID:      &authorID,
+		CommunityID:   communityID,
 		Title:         title,
 		Content:       content,
+		ContentHTML:   markdown.Render(content),
+		Tags:          tags,
 		AllowComments: allowComments,
+		CreatedAt:     time.Now().UTC().Truncate(time.Microsecond),
+		ModStatus:     status,
 	}
+	post.UpdatedAt = post.CreatedAt
 	log.Printf("Adding new post: %+v", post)
-	_, err := s.DB.Exec("INSERT INTO posts (id, title, content, allow_comments) VALUES ($1, $2, $3, $4)",
-		post.ID, post.Title, post.Content, post.AllowComments)
+	_, err = s.DB.ExecContext(ctx, `INSERT INTO posts (id, author_id, community_id, title, content, content_html, tags,
+			allow_comments, created_at, updated_at, mod_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		post.ID, post.AuthorID, post.CommunityID, post.Title, post.Content, post.ContentHTML, pq.Array(post.Tags),
+		post.AllowComments, post.CreatedAt, post.UpdatedAt, post.ModStatus)
 	if err != nil {
 		log.Println("DB Insert Error:", err)
-		return models.Post{}, err
+		return models.Post{}, mapPostgresError(err, ErrUserNotFound)
 	}
+	publishPostAdded(ctx, s.Events, &post)
 	return post, nil
 }
 
-func (s *PostgresStorage) AddComment(postID string, parentID *string, content string) (*models.Comment, error) {
+func (s *PostgresStorage) UpdatePost(ctx context.Context, editorID, id string, update PostUpdate) (*models.Post, error) {
+	log.Printf("Updating post %s", id)
+	if err := validateID(editorID); err != nil {
+		return nil, err
+	}
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	// HTML отрисовывается заново вместе с новым текстом
+	var contentHTML *string
+	if update.Content != nil {
+		if err := validatePostContent(*update.Content); err != nil {
+			return nil, err
+		}
+		rendered := markdown.Render(*update.Content)
+		contentHTML = &rendered
+	}
+
+	// edited_at и edited_by меняются, только если изменился заголовок или текст;
+	// новую ревизию в этом случае записывает триггер posts_revision_update
+	post, err := scanPost(s.DB.QueryRowContext(ctx, `UPDATE posts SET
+			edited_at = CASE WHEN $2::text <> title OR $3::text <> content THEN $5 ELSE edited_at END,
+			edited_by = CASE WHEN $2::text <> title OR $3::text <> content THEN $6::uuid ELSE edited_by END,
+			title = COALESCE($2::text, title),
+			content = COALESCE($3::text, content),
+			content_html = COALESCE($7::text, content_html),
+			allow_comments = COALESCE($4::boolean, allow_comments),
+			updated_at = $5
+		WHERE id = $1
+		RETURNING `+postColumns,
+		id, update.Title, update.Content, update.AllowComments, time.Now().UTC().Truncate(time.Microsecond), editorID,
+		contentHTML))
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
+
+	publish(ctx, s.Events, events.PostTopic(post.ID), events.PostUpdated, post.ID, post)
+
+	return post, nil
+}
+
+func (s *PostgresStorage) AddComment(ctx context.Context, authorID, postID string, parentID *string, content string,
+	status models.ModerationStatus) (*models.Comment, error) {
 	log.Printf("Adding comment to post %s", postID)
-	var allowComments bool
-	err := s.DB.QueryRow("SELECT allow_comments FROM posts WHERE id=$1", postID).Scan(&allowComments)
+	if err := validateID(authorID); err != nil {
+		return nil, err
+	}
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
+	if err := checkInitialStatus(status); err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if err := validateID(*parentID); err != nil {
+			return nil, err
+		}
+	}
+	// Проверки поста и родителя и вставка идут в одной транзакции: FOR SHARE
+	// не даёт закрыть комментарии, скрыть, удалить или заблокировать родителя до вставки
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("DB Begin Error:", err)
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
+	defer tx.Rollback()
+
+	var (
+		allowComments bool
+		communityID   *string
+	)
+	err = tx.QueryRowContext(ctx, "SELECT allow_comments, community_id FROM posts WHERE id=$1 FOR SHARE", postID).
+		Scan(&allowComments, &communityID)
 	if err != nil {
 		log.Println("Post not found:", err)
-		return nil, errors.New("post not found")
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
+	if communityID != nil {
+		if err := s.checkBan(ctx, *communityID, authorID); err != nil {
+			return nil, err
+		}
 	}
 	if !allowComments {
-		return nil, errors.New("comments are disabled for this post")
+		return nil, ErrCommentsDisabled
 	}
-	if len(content) > 2000 {
-		return nil, errors.New("comment is too long")
+	if err := validateContent(content); err != nil {
+		return nil, err
 	}
 
 	comment := models.Comment{
-		ID:        uuid.New().String(),
-		PostID:    postID,
-		ParentID:  parentID,
-		Content:   content,
-		CreatedAt: time.Now(),
+		ID:          uuid.New().String(),
+		PostID:      postID,
+		ParentID:    parentID,
+		AuthorID:    &authorID,
+		Content:     content,
+		ContentHTML: markdown.Render(content),
+		CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
+		ModStatus:   status,
+	}
+
+	// Родитель должен существовать и относиться к тому же посту; отвечать
+	// в поддереве заблокированного комментария нельзя
+	parentPath := ""
+	if parentID != nil {
+		var (
+			parentPostID  string
+			parentDeleted bool
+		)
+		err := tx.QueryRowContext(ctx, `SELECT post_id, path, depth, deleted_at IS NOT NULL
+			FROM comments
+			WHERE id=$1 AND NOT hidden
+			FOR SHARE`, *parentID).
+			Scan(&parentPostID, &parentPath, &comment.Depth, &parentDeleted)
+		if err != nil {
+			log.Println("Parent comment not found:", err)
+			return nil, mapPostgresError(err, ErrCommentNotFound)
+		}
+		if parentPostID != postID {
+			return nil, ErrInvalidParent
+		}
+		if parentDeleted {
+			return nil, ErrCommentDeleted
+		}
+		if err := lockAncestors(ctx, tx, postID, parentPath); err != nil {
+			return nil, err
+		}
+		comment.Depth++
 	}
+	comment.Path = commentPath(parentPath, comment.CreatedAt, comment.ID)
 
-	_, ok := s.DB.Exec("INSERT INTO comments (id, post_id, parent_id, content, created_at) VALUES ($1, $2, $3, $4, $5)",
-		comment.ID, comment.PostID, comment.ParentID, comment.Content, comment.CreatedAt)
-	if ok != nil {
+	_, err = tx.ExecContext(ctx, `INSERT INTO comments (id, post_id, parent_id, author_id, content, content_html, created_at,
+			path, depth, mod_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		comment.ID, comment.PostID, comment.ParentID, comment.AuthorID, comment.Content, comment.ContentHTML, comment.CreatedAt,
+		comment.Path, comment.Depth, comment.ModStatus)
+	if err != nil {
 		log.Println("DB Insert Error:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("DB Commit Error:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+
+	publishComment(ctx, s.Events, events.CommentAdded, &comment)
+
+	log.Printf("Comment added: %+v", comment)
+	return &comment, nil
+}
+
+// lockAncestors блокирует родителя и его предков до конца транзакции и
+// возвращает ErrCommentLocked, если кто-то из них заблокирован
+func lockAncestors(ctx context.Context, tx *sql.Tx, postID, parentPath string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT locked FROM comments
+		WHERE post_id=$1 AND ($2 = path OR $2 LIKE path || '.%')
+		FOR SHARE`, postID, parentPath)
+	if err != nil {
+		log.Println("Error locking ancestors:", err)
+		return mapPostgresError(err, ErrCommentNotFound)
+	}
+	defer rows.Close()
+
+	locked := false
+	for rows.Next() {
+		var ancestorLocked bool
+		if err := rows.Scan(&ancestorLocked); err != nil {
+			return mapPostgresError(err, ErrCommentNotFound)
+		}
+		locked = locked || ancestorLocked
+	}
+	if err := rows.Err(); err != nil {
+		return mapPostgresError(err, ErrCommentNotFound)
+	}
+	if locked {
+		return ErrCommentLocked
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetCommentByID(ctx context.Context, id string) (*models.Comment, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	comment, err := scanComment(s.DB.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE id=$1 AND NOT hidden", id))
+	if err != nil {
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	return comment, nil
+}
+
+func (s *PostgresStorage) UpdateComment(ctx context.Context, editorID, id, content string) (*models.Comment, error) {
+	log.Printf("Updating comment %s", id)
+	if err := validateID(editorID); err != nil {
+		return nil, err
+	}
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	if err := validateContent(content); err != nil {
 		return nil, err
 	}
 
-	// Отправляем уведомление в PostgreSQL NOTIFY
-	notifyQuery := fmt.Sprintf("NOTIFY comments_channel, '%s|%s'", comment.PostID, comment.Content)
-	_, err = s.DB.Exec(notifyQuery)
+	// Ревизию при изменении текста записывает триггер comments_revision_update
+	comment, err := scanComment(s.DB.QueryRowContext(ctx, `UPDATE comments SET
+			edited_at = CASE WHEN content <> $2 THEN $3 ELSE edited_at END,
+			edited_by = CASE WHEN content <> $2 THEN $4::uuid ELSE edited_by END,
+			content = $2,
+			content_html = $5
+		WHERE id=$1 AND deleted_at IS NULL
+		RETURNING `+commentColumns,
+		id, content, time.Now().UTC().Truncate(time.Microsecond), editorID, markdown.Render(content)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.missingCommentError(ctx, id)
+	}
 	if err != nil {
-		log.Println("Notification error:", err)
+		log.Println("DB Update Error:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	publishComment(ctx, s.Events, events.CommentUpdated, comment)
+	return comment, nil
+}
+
+func (s *PostgresStorage) DeleteComment(ctx context.Context, id string) (*models.Comment, error) {
+	log.Printf("Deleting comment %s", id)
+	if err := validateID(id); err != nil {
 		return nil, err
 	}
 
-	log.Printf("Comment added: %+v", comment)
-	return &comment, nil
+	// Комментарий без видимых ответов скрывается, с ответами - остаётся заглушкой
+	comment, err := scanComment(s.DB.QueryRowContext(ctx, `UPDATE comments c SET deleted_at=$2,
+			hidden = NOT EXISTS(SELECT 1 FROM comments r WHERE r.parent_id = c.id AND NOT r.hidden)
+		WHERE c.id=$1 AND c.deleted_at IS NULL
+		RETURNING `+commentColumns,
+		id, time.Now().UTC().Truncate(time.Microsecond)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.missingCommentError(ctx, id)
+	}
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	publishComment(ctx, s.Events, events.CommentDeleted, comment)
+	return comment, nil
 }
 
-func (s *PostgresStorage) GetCommentsByPostID(postID string, limit, offset int) ([]*models.Comment, error) {
-	log.Printf("Getting comment by post id %s", postID)
-	rows, err := s.DB.Query("SELECT id, post_id, parent_id, content, created_at FROM comments WHERE post_id=$1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
-		postID, limit, offset)
+// missingCommentError объясняет, почему комментарий не удалось изменить:
+// его нет (или он скрыт) либо он уже удалён
+func (s *PostgresStorage) missingCommentError(ctx context.Context, id string) error {
+	var deleted bool
+	err := s.DB.QueryRowContext(ctx, "SELECT deleted_at IS NOT NULL FROM comments WHERE id=$1 AND NOT hidden", id).
+		Scan(&deleted)
 	if err != nil {
+		return mapPostgresError(err, ErrCommentNotFound)
+	}
+	if deleted {
+		return ErrCommentDeleted
+	}
+	return ErrCommentNotFound
+}
+
+func (s *PostgresStorage) GetCommentsByPostID(ctx context.Context, postID string, opts CommentListOptions) (*CommentPage, error) {
+	log.Printf("Getting comment by post id %s", postID)
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id=$1)", postID).Scan(&exists); err != nil {
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
+	if !exists {
 		log.Println("Post not found")
+		return nil, ErrPostNotFound
+	}
+
+	return s.queryCommentPage(ctx, "post_id", postID, opts)
+}
+
+func (s *PostgresStorage) GetReplies(ctx context.Context, parentID string, opts CommentListOptions) (*CommentPage, error) {
+	log.Printf("Getting replies to comment %s", parentID)
+	if err := validateID(parentID); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM comments WHERE id=$1 AND NOT hidden)", parentID).Scan(&exists); err != nil {
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	return s.queryCommentPage(ctx, "parent_id", parentID, opts)
+}
+
+func (s *PostgresStorage) GetCommentTree(ctx context.Context, postID string, maxDepth, maxChildren int, sortOrder CommentSort) ([]*models.CommentNode, error) {
+	log.Printf("Getting comment tree for post %s", postID)
+	if err := validateID(postID); err != nil {
 		return nil, err
 	}
+
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id=$1)", postID).Scan(&exists); err != nil {
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	maxDepth, maxChildren = treeLimits(maxDepth, maxChildren)
+	sortOrder = commentSortOrder(sortOrder)
+
+	// ranked нумерует комментарии внутри каждой группы соседей в порядке сортировки, tree спускается
+	// от корней не глубже maxDepth, path задаёт порядок обхода в глубину
+	rows, err := s.DB.QueryContext(ctx, `WITH RECURSIVE ranked AS (
+			SELECT c.*,
+				ROW_NUMBER() OVER (PARTITION BY c.parent_id ORDER BY `+commentOrderSQL(sortOrder, "c.")+`) AS rn,
+				COALESCE(cnt.replies, 0) AS replies
+			FROM comments c
+			LEFT JOIN (
+				SELECT parent_id, COUNT(*) AS replies FROM comments
+				WHERE post_id = $1 AND NOT hidden
+				GROUP BY parent_id
+			) cnt ON cnt.parent_id = c.id
+			WHERE c.post_id = $1 AND NOT c.hidden
+		), tree AS (
+			SELECT r.*, ARRAY[r.rn] AS ord
+			FROM ranked r
+			WHERE r.parent_id IS NULL AND r.rn <= $3
+			UNION ALL
+			SELECT r.*, t.ord || r.rn
+			FROM ranked r
+			JOIN tree t ON r.parent_id = t.id
+			WHERE r.depth <= $2 AND r.rn <= $3
+		)
+		SELECT `+commentColumns+`,
+			CASE WHEN depth = $2 THEN replies > 0 ELSE replies > $3 END AS has_more
+		FROM tree
+		ORDER BY ord`,
+		postID, maxDepth, maxChildren)
+	if err != nil {
+		log.Println("Error fetching comment tree:", err)
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
 	defer rows.Close()
 
-	var comments []*models.Comment
+	nodes := []*models.CommentNode{}
 	for rows.Next() {
-		var comment models.Comment
-		err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Content, &comment.CreatedAt)
+		var (
+			comment models.Comment
+			node    = models.CommentNode{Comment: &comment}
+		)
+		err := rows.Scan(append(commentFields(&comment), &node.HasMoreReplies)...)
 		if err != nil {
 			log.Println(err)
-			return nil, err
+			return nil, mapPostgresError(err, ErrCommentNotFound)
 		}
-		comment.PostID = postID
-		comments = append(comments, &comment)
+		node.Depth = comment.Depth
+		nodes = append(nodes, &node)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	return nodes, nil
+}
+
+// listedSQL - условие для постов и комментариев, не скрытых модерацией
+const listedSQL = "mod_status IN ('visible', 'approved')"
+
+// postColumns - колонки поста в порядке полей postFields
+const postColumns = "id, author_id, community_id, title, content, content_html, tags, allow_comments, created_at, " +
+	"updated_at, edited_at, upvotes, downvotes, mod_status, removal_reason"
+
+// postFields возвращает указатели на поля поста для Scan
+func postFields(p *models.Post) []interface{} {
+	return []interface{}{&p.ID, &p.AuthorID, &p.CommunityID, &p.Title, &p.Content, &p.ContentHTML, pq.Array(&p.Tags),
+		&p.AllowComments, &p.CreatedAt, &p.UpdatedAt, &p.EditedAt, &p.Upvotes, &p.Downvotes, &p.ModStatus, &p.RemovalReason}
+}
+
+func scanPost(row rowScanner) (*models.Post, error) {
+	var post models.Post
+	if err := row.Scan(postFields(&post)...); err != nil {
+		return nil, err
 	}
-	return comments, nil
+	return &post, nil
 }
 
-func (s *PostgresStorage) SubscribeToComments(postID string) (<-chan *models.Comment, error) {
-	log.Printf("Subscribing to comments for post %s", postID)
-	ch := make(chan *models.Comment)
+// postSortKeySQL возвращает SQL-выражение ключа сортировки постов
+// над переданными выражениями времени создания и счётчиков голосов
+func postSortKeySQL(sort PostSort, createdAt, upvotes, downvotes string) string {
+	switch sort {
+	case PostSortTop:
+		return fmt.Sprintf("(%s - %s)", upvotes, downvotes)
+	case PostSortHot:
+		return fmt.Sprintf("hot_rank(%s, %s, %s)", upvotes, downvotes, createdAt)
+	case PostSortControversial:
+		return fmt.Sprintf("controversy_rank(%s, %s)", upvotes, downvotes)
+	default:
+		return createdAt
+	}
+}
+
+// commentSortKeySQL возвращает SQL-выражение рейтинга комментария над переданными
+// выражениями счётчиков голосов; для сортировок по времени - пустую строку
+func commentSortKeySQL(sort CommentSort, upvotes, downvotes string) string {
+	switch sort {
+	case CommentSortBest:
+		return fmt.Sprintf("wilson(%s, %s)", upvotes, downvotes)
+	case CommentSortTop:
+		return fmt.Sprintf("(%s - %s)", upvotes, downvotes)
+	case CommentSortControversial:
+		return fmt.Sprintf("controversy_rank(%s, %s)", upvotes, downvotes)
+	default:
+		return ""
+	}
+}
 
-	// Подключаемся к LISTEN через pq.Listener
-	listener := pq.NewListener(s.DataSource, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+// commentOrderSQL возвращает выражение ORDER BY для сортировки комментариев,
+// table - префикс колонок ("c." или пустая строка). Порядок совпадает с commentRankLess
+func commentOrderSQL(sort CommentSort, table string) string {
+	if sort == CommentSortNew {
+		return table + "created_at DESC, " + table + "id DESC"
+	}
+	order := table + "created_at, " + table + "id"
+	if key := commentSortKeySQL(sort, table+"upvotes", table+"downvotes"); key != "" {
+		order = key + " DESC, " + order
+	}
+	return order
+}
+
+// commentAfterSQL возвращает условие «строка идёт после курсора» для сортировки комментариев.
+// arg добавляет значение в параметры запроса и возвращает его плейсхолдер
+func commentAfterSQL(sort CommentSort, cursor Cursor, arg func(v interface{}) string) string {
+	createdAt, id := arg(cursor.CreatedAt)+"::timestamp", arg(cursor.ID)+"::uuid"
+	if sort == CommentSortNew {
+		return fmt.Sprintf("(created_at, id) < (%s, %s)", createdAt, id)
+	}
+	after := fmt.Sprintf("(created_at, id) > (%s, %s)", createdAt, id)
+
+	rowKey := commentSortKeySQL(sort, "upvotes", "downvotes")
+	if rowKey == "" {
+		return after
+	}
+	// Ключ курсора вычисляется тем же выражением, что и ключ строки
+	cursorKey := commentSortKeySQL(sort, arg(cursor.Upvotes)+"::int", arg(cursor.Downvotes)+"::int")
+	return fmt.Sprintf("(%s < %s OR (%s = %s AND %s))", rowKey, cursorKey, rowKey, cursorKey, after)
+}
+
+// commentColumns - колонки комментария в порядке полей commentFields
+const commentColumns = "id, post_id, parent_id, author_id, content, content_html, created_at, edited_at, path, depth, " +
+	"deleted_at, hidden, upvotes, downvotes, mod_status, removal_reason, locked"
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// commentFields возвращает указатели на поля комментария для Scan
+func commentFields(c *models.Comment) []interface{} {
+	return []interface{}{&c.ID, &c.PostID, &c.ParentID, &c.AuthorID, &c.Content, &c.ContentHTML, &c.CreatedAt, &c.EditedAt,
+		&c.Path, &c.Depth, &c.DeletedAt, &c.Hidden, &c.Upvotes, &c.Downvotes, &c.ModStatus, &c.RemovalReason, &c.Locked}
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	var comment models.Comment
+	if err := row.Scan(commentFields(&comment)...); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (s *PostgresStorage) GetThread(ctx context.Context, commentID string) ([]*models.Comment, error) {
+	log.Printf("Getting thread of comment %s", commentID)
+	if err := validateID(commentID); err != nil {
+		return nil, err
+	}
+
+	var postID, path string
+	err := s.DB.QueryRowContext(ctx, "SELECT post_id, path FROM comments WHERE id=$1 AND NOT hidden", commentID).
+		Scan(&postID, &path)
+	if err != nil {
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+
+	// Поддерево - это комментарии, путь которых начинается с пути корня треда;
+	// сортировка по пути даёт порядок отображения за одно чтение индекса (post_id, path)
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+commentColumns+` FROM comments
+		WHERE post_id=$1 AND (path=$2 OR path LIKE $2 || '.%') AND NOT hidden
+		ORDER BY path`,
+		postID, path)
+	if err != nil {
+		log.Println("Error fetching thread:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	defer rows.Close()
+
+	var thread []*models.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			log.Println(err)
+			return nil, mapPostgresError(err, ErrCommentNotFound)
+		}
+		thread = append(thread, comment)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	return thread, nil
+}
+
+// queryCommentPage выбирает страницу комментариев с условием column = value
+// в порядке сортировки opts.Sort. column - имя колонки из кода, не из запроса клиента.
+func (s *PostgresStorage) queryCommentPage(ctx context.Context, column, value string, opts CommentListOptions) (*CommentPage, error) {
+	sortOrder := opts.sortOrder()
+	args := []interface{}{value}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE ` + column + `=$1 AND NOT hidden AND ` + listedSQL
+	if opts.After != "" {
+		cursor, err := DecodeCursor(opts.After)
 		if err != nil {
-			log.Println("Postgres Listener error:", err)
-		}
-	})
-
-	err := listener.Listen("comments_channel")
-	if err != nil {
-		log.Println("Failed to listen on comments_channel:", err)
-		return nil, fmt.Errorf("failed to listen on comments_channel: %w", err)
-	}
-
-	// Горутина для получения уведомлений
-	go func() {
-		defer close(ch)
-		defer listener.Close()
-
-		for {
-			select {
-			case <-time.After(90 * time.Second):
-				// Проверяем соединение каждые 90 секунд
-				err := listener.Ping()
-				if err != nil {
-					log.Println("Postgres Listener ping error:", err)
-					return
-				}
-
-			case notification := <-listener.Notify:
-				if notification == nil {
-					continue
-				}
-
-				// Разбираем сообщение "postID|content"
-				var notifPostID, content string
-				if _, err := fmt.Sscanf(notification.Extra, "%s|%s", &notifPostID, &content); err != nil {
-					log.Printf("Error parsing notification.Extra: %v\n", err)
-				}
-
-				// Если подписка на нужный пост, отправляем в канал
-				if notifPostID == postID {
-					ch <- &models.Comment{
-						PostID:    notifPostID,
-						Content:   content,
-						CreatedAt: time.Now(),
-					}
-				}
-			}
-		}
-	}()
-
-	log.Println("Listening for comments on comments_channel")
-	return ch, nil
+			return nil, err
+		}
+		query += " AND " + commentAfterSQL(sortOrder, cursor, arg)
+	}
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	limit := PageSize(opts.First)
+	query += " ORDER BY " + commentOrderSQL(sortOrder, "") + " LIMIT " + arg(limit+1)
+	if opts.After == "" {
+		query += " OFFSET " + arg(opts.Offset)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("Error fetching comments:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	defer rows.Close()
+
+	page := &CommentPage{Comments: make([]*models.Comment, 0, limit)}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			log.Println(err)
+			return nil, mapPostgresError(err, ErrCommentNotFound)
+		}
+		if len(page.Comments) == limit {
+			page.HasNextPage = true
+			break
+		}
+		page.Comments = append(page.Comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	return page, nil
 }
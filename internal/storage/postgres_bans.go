@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// banColumns - колонки бана в порядке полей banFields
+const banColumns = "id, community_id, user_id, moderator_id, reason, muted, created_at, expires_at"
+
+// banFields возвращает указатели на поля бана для Scan
+func banFields(b *models.Ban) []interface{} {
+	return []interface{}{&b.ID, &b.CommunityID, &b.UserID, &b.ModeratorID, &b.Reason, &b.Muted, &b.CreatedAt, &b.ExpiresAt}
+}
+
+// appealColumns - колонки апелляции в порядке полей appealFields
+const appealColumns = "id, community_id, user_id, message, created_at, resolved_at, accepted"
+
+// appealFields возвращает указатели на поля апелляции для Scan
+func appealFields(a *models.BanAppeal) []interface{} {
+	return []interface{}{&a.ID, &a.CommunityID, &a.UserID, &a.Message, &a.CreatedAt, &a.ResolvedAt, &a.Accepted}
+}
+
+// activeBanSQL - условие «бан ещё действует» с текущим временем в параметре
+const activeBanSQL = "(expires_at IS NULL OR expires_at > %s)"
+
+func (s *PostgresStorage) BanUser(ctx context.Context, opts BanOptions) (*models.Ban, error) {
+	log.Printf("Banning user %s in community %s", opts.UserID, opts.CommunityID)
+	reason, err := opts.check()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetCommunityByID(ctx, opts.CommunityID); err != nil {
+		return nil, err
+	}
+
+	// Повторный бан заменяет действующий, открытые апелляции закрываются тем же оператором
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	var ban models.Ban
+	err = s.DB.QueryRowContext(ctx, `WITH closed AS (
+			UPDATE ban_appeals SET resolved_at = $7
+			WHERE community_id = $2 AND user_id = $3 AND resolved_at IS NULL
+		)
+		INSERT INTO bans (id, community_id, user_id, moderator_id, reason, muted, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (community_id, user_id) DO UPDATE SET id = EXCLUDED.id, moderator_id = EXCLUDED.moderator_id,
+			reason = EXCLUDED.reason, muted = EXCLUDED.muted, created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		RETURNING `+banColumns,
+		uuid.New().String(), opts.CommunityID, opts.UserID, opts.ModeratorID, reason, opts.Muted, now,
+		opts.expiresAt(now)).Scan(banFields(&ban)...)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+
+	// Попутно чистим истёкшие баны сообщества
+	_, err = s.DB.ExecContext(ctx, "DELETE FROM bans WHERE community_id=$1 AND expires_at <= $2", opts.CommunityID, now)
+	if err != nil {
+		log.Println("Failed to prune bans:", err)
+	}
+	return &ban, nil
+}
+
+func (s *PostgresStorage) UnbanUser(ctx context.Context, communityID, userID string) error {
+	log.Printf("Unbanning user %s in community %s", userID, communityID)
+	if err := validateID(userID); err != nil {
+		return err
+	}
+	if _, err := s.GetCommunityByID(ctx, communityID); err != nil {
+		return err
+	}
+	_, err := s.DB.ExecContext(ctx, `WITH closed AS (
+			UPDATE ban_appeals SET resolved_at = $3
+			WHERE community_id = $1 AND user_id = $2 AND resolved_at IS NULL
+		)
+		DELETE FROM bans WHERE community_id = $1 AND user_id = $2`,
+		communityID, userID, time.Now().UTC().Truncate(time.Microsecond))
+	if err != nil {
+		log.Println("DB Delete Error:", err)
+		return mapPostgresError(err, ErrBanNotFound)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetBans(ctx context.Context, communityID string) ([]*models.Ban, error) {
+	log.Printf("Fetching bans of community %s", communityID)
+	if _, err := s.GetCommunityByID(ctx, communityID); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+banColumns+" FROM bans WHERE community_id=$1 AND "+
+		fmt.Sprintf(activeBanSQL, "$2")+" ORDER BY created_at DESC, id DESC", communityID, time.Now().UTC())
+	if err != nil {
+		log.Println("Error fetching bans:", err)
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	defer rows.Close()
+
+	bans := []*models.Ban{}
+	for rows.Next() {
+		var ban models.Ban
+		if err := rows.Scan(banFields(&ban)...); err != nil {
+			log.Println("Error scanning ban:", err)
+			return nil, err
+		}
+		bans = append(bans, &ban)
+	}
+	return bans, rows.Err()
+}
+
+func (s *PostgresStorage) AppealBan(ctx context.Context, communityID, userID, message string) (*models.BanAppeal, error) {
+	log.Printf("User %s appeals ban in community %s", userID, communityID)
+	message, err := normalizeText(message, MaxAppealLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateID(communityID); err != nil {
+		return nil, err
+	}
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+
+	var muted bool
+	err = s.DB.QueryRowContext(ctx, "SELECT muted FROM bans WHERE community_id=$1 AND user_id=$2 AND "+
+		fmt.Sprintf(activeBanSQL, "$3"), communityID, userID, time.Now().UTC()).Scan(&muted)
+	if err != nil {
+		return nil, mapPostgresError(err, ErrBanNotFound)
+	}
+	if muted {
+		return nil, ErrAppealsMuted
+	}
+
+	// Вторую открытую апелляцию отбрасывает уникальный индекс
+	var appeal models.BanAppeal
+	err = s.DB.QueryRowContext(ctx, `INSERT INTO ban_appeals (id, community_id, user_id, message, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+appealColumns,
+		uuid.New().String(), communityID, userID, message, time.Now().UTC().Truncate(time.Microsecond)).
+		Scan(appealFields(&appeal)...)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		err = mapPostgresError(err, ErrUserNotFound)
+		if errors.Is(err, ErrConflict) {
+			return nil, ErrAppealPending
+		}
+		return nil, err
+	}
+	return &appeal, nil
+}
+
+func (s *PostgresStorage) ResolveAppeal(ctx context.Context, appealID string, accepted bool) (*models.BanAppeal, error) {
+	log.Printf("Resolving ban appeal %s, accepted: %t", appealID, accepted)
+	if err := validateID(appealID); err != nil {
+		return nil, err
+	}
+
+	// Принятая апелляция снимает бан тем же оператором
+	var appeal models.BanAppeal
+	err := s.DB.QueryRowContext(ctx, `WITH resolved AS (
+			UPDATE ban_appeals SET resolved_at = $2, accepted = $3
+			WHERE id = $1 AND resolved_at IS NULL
+			RETURNING `+appealColumns+`
+		), lifted AS (
+			DELETE FROM bans USING resolved
+			WHERE $3 AND bans.community_id = resolved.community_id AND bans.user_id = resolved.user_id
+		)
+		SELECT `+appealColumns+` FROM resolved`,
+		appealID, time.Now().UTC().Truncate(time.Microsecond), accepted).Scan(appealFields(&appeal)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM ban_appeals WHERE id=$1)", appealID).
+			Scan(&exists); err != nil {
+			return nil, mapPostgresError(err, ErrAppealNotFound)
+		}
+		if exists {
+			return nil, ErrAppealResolved
+		}
+		return nil, ErrAppealNotFound
+	}
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return nil, mapPostgresError(err, ErrAppealNotFound)
+	}
+	return &appeal, nil
+}
+
+// appealQueue выбирает открытые апелляции на действующие баны для очереди модерации
+func (s *PostgresStorage) appealQueue(ctx context.Context, communityID string, limit int) ([]*ModQueueItem, error) {
+	args := []interface{}{time.Now().UTC(), limit}
+	condition := "resolved_at IS NULL"
+	if communityID != "" {
+		args = append(args, communityID)
+		condition += " AND community_id = $3"
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+appealColumns+`, b.reason FROM ban_appeals,
+			LATERAL (
+				SELECT reason FROM bans
+				WHERE bans.community_id = ban_appeals.community_id AND bans.user_id = ban_appeals.user_id
+					AND `+fmt.Sprintf(activeBanSQL, "$1")+`
+			) b
+		WHERE `+condition+`
+		ORDER BY created_at DESC, id DESC LIMIT $2`, args...)
+	if err != nil {
+		log.Println("Error fetching ban appeals:", err)
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	defer rows.Close()
+
+	var items []*ModQueueItem
+	for rows.Next() {
+		item := &ModQueueItem{Appeal: &models.BanAppeal{}, Reasons: make([]string, 1)}
+		if err := rows.Scan(append(appealFields(item.Appeal), &item.Reasons[0])...); err != nil {
+			log.Println("Error scanning ban appeal:", err)
+			return nil, err
+		}
+		item.LastReportedAt = item.Appeal.CreatedAt
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// checkBan возвращает *BannedError, если у пользователя есть действующий бан в сообществе
+func (s *PostgresStorage) checkBan(ctx context.Context, communityID, userID string) error {
+	ban := models.Ban{CommunityID: communityID}
+	err := s.DB.QueryRowContext(ctx, "SELECT reason, expires_at FROM bans WHERE community_id=$1 AND user_id=$2 AND "+
+		fmt.Sprintf(activeBanSQL, "$3"), communityID, userID, time.Now().UTC()).Scan(&ban.Reason, &ban.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Println("Error checking ban:", err)
+		return mapPostgresError(err, ErrBanNotFound)
+	}
+	return bannedError(&ban)
+}
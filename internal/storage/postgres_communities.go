@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// communityColumns - колонки сообщества в порядке полей communityFields
+const communityColumns = "id, name, description, creator_id, created_at, member_count"
+
+// communityFields возвращает указатели на поля сообщества для Scan
+func communityFields(c *models.Community) []interface{} {
+	return []interface{}{&c.ID, &c.Name, &c.Description, &c.CreatorID, &c.CreatedAt, &c.MemberCount}
+}
+
+func scanCommunity(row rowScanner) (*models.Community, error) {
+	var community models.Community
+	if err := row.Scan(communityFields(&community)...); err != nil {
+		return nil, err
+	}
+	return &community, nil
+}
+
+// Число участников поддерживает триггер community_members_apply
+
+func (s *PostgresStorage) CreateCommunity(ctx context.Context, creatorID, name, description string) (*models.Community, error) {
+	log.Printf("Creating community %s", name)
+	if err := validateID(creatorID); err != nil {
+		return nil, err
+	}
+	if err := validateCommunity(name, description); err != nil {
+		return nil, err
+	}
+
+	// Сообщество и членство создателя создаются одним оператором
+	community, err := scanCommunity(s.DB.QueryRowContext(ctx, `WITH created AS (
+			INSERT INTO communities (id, name, description, creator_id, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		), joined AS (
+			INSERT INTO community_members (community_id, user_id, joined_at)
+			SELECT id, $4, created_at FROM created
+		)
+		SELECT id, $2, $3, $4::uuid, created_at, 1 FROM created`,
+		uuid.New().String(), name, description, creatorID, time.Now().UTC().Truncate(time.Microsecond)))
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		err = mapPostgresError(err, ErrUserNotFound)
+		if errors.Is(err, ErrConflict) {
+			return nil, ErrCommunityTaken
+		}
+		return nil, err
+	}
+	return community, nil
+}
+
+func (s *PostgresStorage) GetCommunityByID(ctx context.Context, id string) (*models.Community, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	community, err := scanCommunity(s.DB.QueryRowContext(ctx, "SELECT "+communityColumns+" FROM communities WHERE id=$1", id))
+	if err != nil {
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	return community, nil
+}
+
+func (s *PostgresStorage) GetCommunityByName(ctx context.Context, name string) (*models.Community, error) {
+	community, err := scanCommunity(s.DB.QueryRowContext(ctx,
+		"SELECT "+communityColumns+" FROM communities WHERE lower(name)=lower($1)", name))
+	if err != nil {
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	return community, nil
+}
+
+func (s *PostgresStorage) JoinCommunity(ctx context.Context, userID, communityID string) (*models.Community, error) {
+	log.Printf("User %s joins community %s", userID, communityID)
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	if err := validateID(communityID); err != nil {
+		return nil, err
+	}
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO community_members (community_id, user_id, joined_at)
+		VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		communityID, userID, time.Now().UTC().Truncate(time.Microsecond))
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	return s.GetCommunityByID(ctx, communityID)
+}
+
+func (s *PostgresStorage) LeaveCommunity(ctx context.Context, userID, communityID string) (*models.Community, error) {
+	log.Printf("User %s leaves community %s", userID, communityID)
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	if err := validateID(communityID); err != nil {
+		return nil, err
+	}
+	_, err := s.DB.ExecContext(ctx, "DELETE FROM community_members WHERE community_id=$1 AND user_id=$2",
+		communityID, userID)
+	if err != nil {
+		log.Println("DB Delete Error:", err)
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	return s.GetCommunityByID(ctx, communityID)
+}
+
+func (s *PostgresStorage) IsMember(ctx context.Context, userID, communityID string) (bool, error) {
+	if err := validateID(userID); err != nil {
+		return false, err
+	}
+	if err := validateID(communityID); err != nil {
+		return false, err
+	}
+	var member bool
+	err := s.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM community_members
+		WHERE community_id=$1 AND user_id=$2)`, communityID, userID).Scan(&member)
+	if err != nil {
+		return false, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	return member, nil
+}
+
+func (s *PostgresStorage) GetJoinedCommunities(ctx context.Context, userID string) ([]*models.Community, error) {
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+communityColumns+` FROM communities
+		WHERE id IN (SELECT community_id FROM community_members WHERE user_id=$1)
+		ORDER BY lower(name)`, userID)
+	if err != nil {
+		log.Println("Error fetching joined communities:", err)
+		return nil, mapPostgresError(err, ErrCommunityNotFound)
+	}
+	defer rows.Close()
+
+	communities := []*models.Community{}
+	for rows.Next() {
+		community, err := scanCommunity(rows)
+		if err != nil {
+			return nil, err
+		}
+		communities = append(communities, community)
+	}
+	return communities, rows.Err()
+}
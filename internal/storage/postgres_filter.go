@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// filterDecisionColumns - колонки записи журнала фильтра в порядке полей filterDecisionFields
+const filterDecisionColumns = "id, kind, target_id, author_id, content, verdict, filter, reason, created_at"
+
+// filterDecisionFields возвращает указатели на поля записи журнала для Scan
+func filterDecisionFields(d *models.FilterDecision) []interface{} {
+	return []interface{}{&d.ID, &d.Kind, &d.TargetID, &d.AuthorID, &d.Content, &d.Verdict, &d.Filter, &d.Reason, &d.CreatedAt}
+}
+
+func (s *PostgresStorage) TrainClassifier(ctx context.Context, spam bool, tokens []string) error {
+	log.Printf("Training spam classifier, spam: %t", spam)
+	spamCount, hamCount := 0, 1
+	if spam {
+		spamCount, hamCount = 1, 0
+	}
+
+	// Счётчик текстов и счётчики слов обновляются одним оператором
+	_, err := s.DB.ExecContext(ctx, `WITH docs AS (
+			INSERT INTO classifier_docs (spam, count) VALUES ($1, 1)
+			ON CONFLICT (spam) DO UPDATE SET count = classifier_docs.count + 1
+		)
+		INSERT INTO classifier_tokens (token, spam_count, ham_count)
+		SELECT DISTINCT token, $3::int, $4::int FROM unnest($2::text[]) AS token
+		ON CONFLICT (token) DO UPDATE SET
+			spam_count = classifier_tokens.spam_count + EXCLUDED.spam_count,
+			ham_count = classifier_tokens.ham_count + EXCLUDED.ham_count`,
+		spam, pq.Array(tokens), spamCount, hamCount)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return mapPostgresError(err, ErrNotFound)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) UntrainClassifier(ctx context.Context, spam bool, tokens []string) error {
+	log.Printf("Untraining spam classifier, spam: %t", spam)
+	spamCount, hamCount := 0, 1
+	if spam {
+		spamCount, hamCount = 1, 0
+	}
+
+	// Счётчики вычитаются одним оператором и не опускаются ниже нуля
+	_, err := s.DB.ExecContext(ctx, `WITH docs AS (
+			UPDATE classifier_docs SET count = GREATEST(count - 1, 0) WHERE spam = $1
+		)
+		UPDATE classifier_tokens SET
+			spam_count = GREATEST(spam_count - $3::int, 0),
+			ham_count = GREATEST(ham_count - $4::int, 0)
+		WHERE token = ANY($2::text[])`,
+		spam, pq.Array(tokens), spamCount, hamCount)
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return mapPostgresError(err, ErrNotFound)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetClassifierStats(ctx context.Context, tokens []string) (*ClassifierStats, error) {
+	stats := &ClassifierStats{Tokens: make(map[string]TokenCounts)}
+	err := s.DB.QueryRowContext(ctx, `SELECT
+			COALESCE(SUM(count) FILTER (WHERE spam), 0), COALESCE(SUM(count) FILTER (WHERE NOT spam), 0)
+		FROM classifier_docs`).Scan(&stats.SpamDocs, &stats.HamDocs)
+	if err != nil {
+		log.Println("Error fetching classifier stats:", err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT token, spam_count, ham_count FROM classifier_tokens WHERE token = ANY($1)",
+		pq.Array(tokens))
+	if err != nil {
+		log.Println("Error fetching classifier tokens:", err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			token  string
+			counts TokenCounts
+		)
+		if err := rows.Scan(&token, &counts.Spam, &counts.Ham); err != nil {
+			log.Println("Error scanning classifier token:", err)
+			return nil, err
+		}
+		stats.Tokens[token] = counts
+	}
+	return stats, rows.Err()
+}
+
+func (s *PostgresStorage) RecordFilterDecision(ctx context.Context, decision models.FilterDecision) (*models.FilterDecision, error) {
+	log.Printf("Recording filter decision %s by %s", decision.Verdict, decision.Filter)
+	decision.ID = uuid.New().String()
+	decision.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO filter_decisions (`+filterDecisionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		decision.ID, decision.Kind, decision.TargetID, decision.AuthorID, decision.Content, decision.Verdict,
+		decision.Filter, decision.Reason, decision.CreatedAt)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	return &decision, nil
+}
+
+func (s *PostgresStorage) GetFilterDecisions(ctx context.Context, first int) ([]*models.FilterDecision, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+filterDecisionColumns+` FROM filter_decisions
+		ORDER BY created_at DESC, id DESC LIMIT $1`, PageSize(first))
+	if err != nil {
+		log.Println("Error fetching filter decisions:", err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	decisions := []*models.FilterDecision{}
+	for rows.Next() {
+		var decision models.FilterDecision
+		if err := rows.Scan(filterDecisionFields(&decision)...); err != nil {
+			log.Println("Error scanning filter decision:", err)
+			return nil, err
+		}
+		decisions = append(decisions, &decision)
+	}
+	return decisions, rows.Err()
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// interestColumns - колонки подписки в порядке полей interestFields
+const interestColumns = "user_id, kind, target, created_at"
+
+// interestFields возвращает указатели на поля подписки для Scan
+func interestFields(i *models.Interest) []interface{} {
+	return []interface{}{&i.UserID, &i.Kind, &i.Target, &i.CreatedAt}
+}
+
+func (s *PostgresStorage) FollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) (*models.Interest, error) {
+	log.Printf("User %s follows %s %s", userID, kind, target)
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	target, err := interestTarget(kind, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Подписка на автора возможна, только если автор существует
+	var interest models.Interest
+	err = s.DB.QueryRowContext(ctx, `INSERT INTO interests (user_id, kind, target, created_at)
+		SELECT $1, $2, $3, $4
+		WHERE $2 <> 'author' OR EXISTS (SELECT 1 FROM users WHERE id::text = $3)
+		ON CONFLICT (user_id, kind, target) DO UPDATE SET kind = EXCLUDED.kind
+		RETURNING `+interestColumns,
+		userID, kind, target, time.Now().UTC().Truncate(time.Microsecond)).Scan(interestFields(&interest)...)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	return &interest, nil
+}
+
+func (s *PostgresStorage) UnfollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) error {
+	log.Printf("User %s unfollows %s %s", userID, kind, target)
+	if err := validateID(userID); err != nil {
+		return err
+	}
+	target, err := interestTarget(kind, target)
+	if err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM interests WHERE user_id=$1 AND kind=$2 AND target=$3",
+		userID, kind, target); err != nil {
+		log.Println("DB Delete Error:", err)
+		return mapPostgresError(err, ErrUserNotFound)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetInterests(ctx context.Context, userID string) ([]*models.Interest, error) {
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+interestColumns+" FROM interests WHERE user_id=$1 ORDER BY created_at, target",
+		userID)
+	if err != nil {
+		log.Println("Error fetching interests:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	defer rows.Close()
+
+	interests := []*models.Interest{}
+	for rows.Next() {
+		var interest models.Interest
+		if err := rows.Scan(interestFields(&interest)...); err != nil {
+			return nil, err
+		}
+		interests = append(interests, &interest)
+	}
+	return interests, rows.Err()
+}
+
+// interestTarget проверяет цель подписки: ID автора должен быть UUID, тег приводится
+// к нижнему регистру
+func interestTarget(kind models.InterestKind, target string) (string, error) {
+	switch kind {
+	case models.InterestAuthor:
+		if err := validateID(target); err != nil {
+			return "", err
+		}
+		return target, nil
+	case models.InterestTag:
+		return normalizeTag(target)
+	default:
+		return "", ErrInvalidInterest
+	}
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func (s *PostgresStorage) Report(ctx context.Context, reporterID, targetID, reason string) error {
+	log.Printf("User %s reports %s", reporterID, targetID)
+	reason, err := normalizeReason(reason)
+	if err != nil {
+		return err
+	}
+	if err := validateID(reporterID); err != nil {
+		return err
+	}
+	if err := validateID(targetID); err != nil {
+		return err
+	}
+	column, err := s.targetColumn(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	table := "posts"
+	if column == "comment_id" {
+		table = "comments"
+	}
+
+	// Повторная открытая жалоба отбрасывается уникальным индексом. Вставленная строка
+	// не видна подзапросу того же оператора, поэтому считается отдельно
+	_, err = s.DB.ExecContext(ctx, `WITH inserted AS (
+			INSERT INTO reports (id, reporter_id, `+column+`, reason, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		UPDATE `+table+` SET mod_status = 'flagged'
+		WHERE id = $3 AND mod_status = 'visible'
+			AND (SELECT COUNT(*) FROM reports WHERE `+column+` = $3 AND resolved_at IS NULL)
+				+ (SELECT COUNT(*) FROM inserted) >= $6`,
+		uuid.New().String(), reporterID, targetID, reason, time.Now().UTC().Truncate(time.Microsecond), FlagReportThreshold)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return mapPostgresError(err, ErrUserNotFound)
+	}
+	return nil
+}
+
+// openReportsSQL - подзапрос открытых жалоб на строку таблицы через колонку column
+// reports: число, различные причины и время последней жалобы
+func openReportsSQL(table, column string) string {
+	return fmt.Sprintf(`LATERAL (
+			SELECT COUNT(*) AS report_count,
+				COALESCE(array_agg(DISTINCT reason ORDER BY reason), '{}') AS reasons,
+				MAX(created_at) AS last_reported
+			FROM reports WHERE reports.%s = %s.id AND resolved_at IS NULL
+		) r`, column, table)
+}
+
+// queueSQL - условие «объект ждёт решения модератора»
+const queueSQL = "(mod_status = 'flagged' OR (r.report_count > 0 AND mod_status <> 'removed'))"
+
+func (s *PostgresStorage) GetModQueue(ctx context.Context, opts ModQueueOptions) ([]*ModQueueItem, error) {
+	log.Println("Fetching moderation queue")
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	postConditions := []string{queueSQL}
+	commentConditions := []string{queueSQL, "NOT hidden", "deleted_at IS NULL"}
+	if opts.CommunityID != "" {
+		if err := validateID(opts.CommunityID); err != nil {
+			return nil, err
+		}
+		community := arg(opts.CommunityID)
+		postConditions = append(postConditions, "community_id = "+community)
+		commentConditions = append(commentConditions, "post_id IN (SELECT id FROM posts WHERE community_id = "+community+")")
+	}
+	limit := PageSize(opts.First)
+	order := fmt.Sprintf("ORDER BY r.report_count DESC, COALESCE(r.last_reported, created_at) DESC, id DESC LIMIT %s", arg(limit))
+	extra := "r.report_count, r.reasons, COALESCE(r.last_reported, created_at)"
+
+	// Посты и комментарии выбираются отдельно и сливаются в общем порядке
+	var items []*ModQueueItem
+	for _, query := range []struct {
+		sql      string
+		comments bool
+	}{
+		{fmt.Sprintf("SELECT %s, %s FROM posts, %s WHERE %s %s",
+			postColumns, extra, openReportsSQL("posts", "post_id"), strings.Join(postConditions, " AND "), order), false},
+		{fmt.Sprintf("SELECT %s, %s FROM comments, %s WHERE %s %s",
+			commentColumns, extra, openReportsSQL("comments", "comment_id"), strings.Join(commentConditions, " AND "), order), true},
+	} {
+		rows, err := s.DB.QueryContext(ctx, query.sql, args...)
+		if err != nil {
+			log.Println("Error fetching moderation queue:", err)
+			return nil, mapPostgresError(err, ErrCommunityNotFound)
+		}
+		for rows.Next() {
+			item := &ModQueueItem{}
+			var fields []interface{}
+			if query.comments {
+				item.Comment = &models.Comment{}
+				fields = commentFields(item.Comment)
+			} else {
+				item.Post = &models.Post{}
+				fields = postFields(item.Post)
+			}
+			if err := rows.Scan(append(fields, &item.ReportCount, pq.Array(&item.Reasons), &item.LastReportedAt)...); err != nil {
+				rows.Close()
+				log.Println("Error scanning moderation queue:", err)
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	appeals, err := s.appealQueue(ctx, opts.CommunityID, limit)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, appeals...)
+
+	return sortModQueue(items, limit), nil
+}
+
+func (s *PostgresStorage) Moderate(ctx context.Context, targetID string, status models.ModerationStatus, reason *string) (*ModerationResult, error) {
+	log.Printf("Setting moderation status of %s to %s", targetID, status)
+	reason, err := checkModeration(status, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateID(targetID); err != nil {
+		return nil, err
+	}
+	column, err := s.targetColumn(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Прежний статус читается под блокировкой строки, жалобы закрываются тем же
+	// оператором, что меняет статус
+	query := `WITH prev AS (
+			SELECT mod_status AS prev_status, removal_reason AS prev_reason FROM %[1]s WHERE id = $1 FOR UPDATE
+		), resolved AS (
+			UPDATE reports SET resolved_at = $4
+			WHERE ` + column + ` = $1 AND resolved_at IS NULL AND $5
+		)
+		UPDATE %[1]s SET mod_status = $2, removal_reason = $3 FROM prev WHERE id = $1
+		RETURNING %[2]s, prev_status, prev_reason`
+	args := []interface{}{targetID, status, reason, time.Now().UTC().Truncate(time.Microsecond), resolves(status)}
+
+	result := &ModerationResult{}
+	if column == "post_id" {
+		var post models.Post
+		err := s.DB.QueryRowContext(ctx, fmt.Sprintf(query, "posts", postColumns), args...).
+			Scan(append(postFields(&post), &result.PrevStatus, &result.PrevReason)...)
+		if err != nil {
+			log.Println("DB Update Error:", err)
+			return nil, mapPostgresError(err, ErrPostNotFound)
+		}
+		result.Post = &post
+	} else {
+		var comment models.Comment
+		err := s.DB.QueryRowContext(ctx, fmt.Sprintf(query, "comments", commentColumns), args...).
+			Scan(append(commentFields(&comment), &result.PrevStatus, &result.PrevReason)...)
+		if err != nil {
+			log.Println("DB Update Error:", err)
+			return nil, mapPostgresError(err, ErrCommentNotFound)
+		}
+		result.Comment = &comment
+	}
+	publishTarget(ctx, s.Events, &result.Target)
+	return result, nil
+}
+
+func (s *PostgresStorage) LockComment(ctx context.Context, id string, locked bool) (*models.Comment, error) {
+	log.Printf("Setting lock of comment %s to %t", id, locked)
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	comment, err := scanComment(s.DB.QueryRowContext(ctx, `UPDATE comments SET locked = $2
+		WHERE id = $1 AND NOT hidden AND deleted_at IS NULL
+		RETURNING `+commentColumns, id, locked))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.missingCommentError(ctx, id)
+	}
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	publishComment(ctx, s.Events, events.CommentUpdated, comment)
+	return comment, nil
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/events"
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// notificationColumns - колонки уведомления в порядке полей notificationFields
+const notificationColumns = "id, user_id, kind, actor_id, post_id, comment_id, created_at, read_at"
+
+// notificationFields возвращает указатели на поля уведомления для Scan
+func notificationFields(n *models.Notification) []interface{} {
+	return []interface{}{&n.ID, &n.UserID, &n.Kind, &n.ActorID, &n.PostID, &n.CommentID, &n.CreatedAt, &n.ReadAt}
+}
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var notification models.Notification
+	if err := row.Scan(notificationFields(&notification)...); err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (s *PostgresStorage) CreateNotification(ctx context.Context, notification models.Notification) (*models.Notification, error) {
+	log.Printf("Notifying user %s about comment %s", notification.UserID, notification.CommentID)
+	if err := validateID(notification.UserID); err != nil {
+		return nil, err
+	}
+
+	notification.ID = uuid.New().String()
+	notification.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	notification.ReadAt = nil
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO notifications (id, user_id, kind, actor_id, post_id, comment_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		notification.ID, notification.UserID, notification.Kind, notification.ActorID, notification.PostID,
+		notification.CommentID, notification.CreatedAt)
+	if err != nil {
+		log.Println("DB Insert Error:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+
+	publish(ctx, s.Events, events.UserTopic(notification.UserID), events.NotificationCreated, notification.ID, notification)
+
+	return &notification, nil
+}
+
+func (s *PostgresStorage) GetNotifications(ctx context.Context, userID string, opts NotificationListOptions) (*NotificationPage, error) {
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"user_id = " + arg(userID)}
+	if opts.UnreadOnly {
+		conditions = append(conditions, "read_at IS NULL")
+	}
+	if opts.After != "" {
+		cursor, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (%s, %s::uuid)", arg(cursor.CreatedAt), arg(cursor.ID)))
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	limit := PageSize(opts.First)
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+notificationColumns+" FROM notifications WHERE "+
+		strings.Join(conditions, " AND ")+" ORDER BY created_at DESC, id DESC LIMIT "+arg(limit+1), args...)
+	if err != nil {
+		log.Println("Error fetching notifications:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	defer rows.Close()
+
+	page := &NotificationPage{Notifications: []*models.Notification{}}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Notifications = append(page.Notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Notifications) > limit {
+		page.Notifications, page.HasNextPage = page.Notifications[:limit], true
+	}
+	return page, nil
+}
+
+func (s *PostgresStorage) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	if err := validateID(userID); err != nil {
+		return 0, err
+	}
+	var count int
+	err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id=$1 AND read_at IS NULL", userID).
+		Scan(&count)
+	if err != nil {
+		return 0, mapPostgresError(err, ErrUserNotFound)
+	}
+	return count, nil
+}
+
+func (s *PostgresStorage) MarkNotificationsRead(ctx context.Context, userID string, ids []string) (int, error) {
+	log.Printf("Marking notifications of user %s as read", userID)
+	if err := validateID(userID); err != nil {
+		return 0, err
+	}
+	if err := validateIDs(ids); err != nil {
+		return 0, err
+	}
+
+	// Пустой список отмечает все уведомления пользователя
+	result, err := s.DB.ExecContext(ctx, `UPDATE notifications SET read_at = $2
+		WHERE user_id = $1 AND read_at IS NULL AND (cardinality($3::uuid[]) = 0 OR id = ANY($3::uuid[]))`,
+		userID, time.Now().UTC().Truncate(time.Microsecond), pq.Array(ids))
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return 0, mapPostgresError(err, ErrUserNotFound)
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(marked), nil
+}
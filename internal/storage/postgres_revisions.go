@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// Ревизии пишут триггеры record_post_revision и record_comment_revision,
+// здесь они только читаются
+
+func (s *PostgresStorage) GetPostRevisions(ctx context.Context, postID string) ([]*models.Revision, error) {
+	if err := validateID(postID); err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id=$1)", postID).Scan(&exists); err != nil {
+		return nil, mapPostgresError(err, ErrPostNotFound)
+	}
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	return s.queryRevisions(ctx, `SELECT id, post_id, version, editor_id, title, content, created_at
+		FROM post_revisions WHERE post_id=$1 ORDER BY version`, postID)
+}
+
+func (s *PostgresStorage) GetCommentRevisions(ctx context.Context, commentID string) ([]*models.Revision, error) {
+	if err := validateID(commentID); err != nil {
+		return nil, err
+	}
+	var exists bool
+	err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM comments WHERE id=$1 AND NOT hidden)", commentID).
+		Scan(&exists)
+	if err != nil {
+		return nil, mapPostgresError(err, ErrCommentNotFound)
+	}
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	return s.queryRevisions(ctx, `SELECT id, comment_id, version, editor_id, '', content, created_at
+		FROM comment_revisions WHERE comment_id=$1 ORDER BY version`, commentID)
+}
+
+// queryRevisions читает ревизии запросом с колонками в порядке полей models.Revision
+func (s *PostgresStorage) queryRevisions(ctx context.Context, query, targetID string) ([]*models.Revision, error) {
+	rows, err := s.DB.QueryContext(ctx, query, targetID)
+	if err != nil {
+		log.Println("Error fetching revisions:", err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	revisions := []*models.Revision{}
+	for rows.Next() {
+		var r models.Revision
+		if err := rows.Scan(&r.ID, &r.TargetID, &r.Version, &r.EditorID, &r.Title, &r.Content, &r.CreatedAt); err != nil {
+			log.Println(err)
+			return nil, mapPostgresError(err, ErrNotFound)
+		}
+		revisions = append(revisions, &r)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+	return revisions, nil
+}
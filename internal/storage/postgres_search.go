@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// snippetSQL возвращает выражение подсвеченного фрагмента колонки column.
+// Текст экранируется для HTML до выделения слов, как htmlEscaper в памяти
+func snippetSQL(column string) string {
+	return fmt.Sprintf(`ts_headline('simple',
+		replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), q,
+		'StartSel=<mark>, StopSel=</mark>, MinWords=%d, MaxWords=%d')`, column, snippetMinWords, snippetMaxWords)
+}
+
+func (s *PostgresStorage) Search(ctx context.Context, opts SearchOptions) (*SearchPage, error) {
+	log.Printf("Searching %q", opts.Query)
+	searchType, err := opts.searchType()
+	if err != nil {
+		return nil, err
+	}
+	terms, err := queryTerms(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	table, columns := "posts", postColumns
+	conditions := []string{"search_vector @@ q", listedSQL}
+	if searchType == SearchComments {
+		table, columns = "comments", commentColumns
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	// Запрос составляется из тех же слов, что и в памяти: все слова должны встретиться
+	from := fmt.Sprintf("%s, plainto_tsquery('simple', %s) q", table, arg(strings.Join(terms, " ")))
+	// rank считает ts_rank, а не textRank, поэтому он отличается от rank в памяти
+	columns += ", ts_rank(search_vector, q, 1) AS rank, " + snippetSQL("content")
+
+	if opts.CommunityID != "" {
+		if err := validateID(opts.CommunityID); err != nil {
+			return nil, err
+		}
+		if searchType == SearchComments {
+			conditions = append(conditions, "post_id IN (SELECT id FROM posts WHERE community_id = "+arg(opts.CommunityID)+")")
+		} else {
+			conditions = append(conditions, "community_id = "+arg(opts.CommunityID))
+		}
+	}
+	if opts.After != "" {
+		cursor, err := DecodeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(ts_rank(search_vector, q, 1), id) < (%s::real, %s::uuid)",
+			arg(cursor.Rank), arg(cursor.ID)))
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	limit := PageSize(opts.First)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY rank DESC, id DESC LIMIT %s",
+		columns, from, strings.Join(conditions, " AND "), arg(limit+1))
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("Error searching:", err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	page := &SearchPage{}
+	for rows.Next() {
+		var result SearchResult
+		var fields []interface{}
+		if searchType == SearchComments {
+			result.Comment = &models.Comment{}
+			fields = commentFields(result.Comment)
+		} else {
+			result.Post = &models.Post{}
+			fields = postFields(result.Post)
+		}
+		if err := rows.Scan(append(fields, &result.Rank, &result.Snippet)...); err != nil {
+			log.Println("Error scanning search result:", err)
+			return nil, mapPostgresError(err, ErrNotFound)
+		}
+		page.Results = append(page.Results, result)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating search results:", err)
+		return nil, mapPostgresError(err, ErrNotFound)
+	}
+
+	if len(page.Results) > limit {
+		page.Results, page.HasNextPage = page.Results[:limit], true
+	}
+	return page, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// userColumns - колонки пользователя в порядке полей userFields
+const userColumns = "id, username, password_hash, role, created_at"
+
+// userFields возвращает указатели на поля пользователя для Scan
+func userFields(u *models.User) []interface{} {
+	return []interface{}{&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt}
+}
+
+func (s *PostgresStorage) CreateUser(ctx context.Context, username, passwordHash string) (*models.User, error) {
+	log.Printf("Creating user %s", username)
+	user := models.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         models.RoleUser,
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO users (id, username, password_hash, role, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		user.ID, user.Username, user.PasswordHash, user.Role, user.CreatedAt)
+	if err != nil {
+		err = mapPostgresError(err, ErrUserNotFound)
+		if errors.Is(err, ErrConflict) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *PostgresStorage) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	var user models.User
+	err := s.DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1", id).Scan(userFields(&user)...)
+	if err != nil {
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStorage) SetUserRole(ctx context.Context, userID string, role models.Role) (*models.User, error) {
+	log.Printf("Setting role of user %s to %s", userID, role)
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	var user models.User
+	err := s.DB.QueryRowContext(ctx, "UPDATE users SET role=$2 WHERE id=$1 RETURNING "+userColumns, userID, role).
+		Scan(userFields(&user)...)
+	if err != nil {
+		log.Println("DB Update Error:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	err := s.DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE lower(username)=lower($1)", username).
+		Scan(userFields(&user)...)
+	if err != nil {
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStorage) CreateSession(ctx context.Context, session models.Session) error {
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)`,
+		session.TokenHash, session.UserID, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return mapPostgresError(err, ErrUserNotFound)
+	}
+
+	// Попутно чистим истёкшие сессии пользователя
+	_, err = s.DB.ExecContext(ctx, "DELETE FROM sessions WHERE user_id=$1 AND expires_at <= $2",
+		session.UserID, time.Now().UTC())
+	if err != nil {
+		log.Println("Failed to prune sessions:", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	session := models.Session{TokenHash: tokenHash}
+	err := s.DB.QueryRowContext(ctx, `SELECT user_id, created_at, expires_at FROM sessions
+		WHERE token_hash=$1 AND expires_at > $2`, tokenHash, time.Now().UTC()).
+		Scan(&session.UserID, &session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, mapPostgresError(err, ErrSessionNotFound)
+	}
+	return &session, nil
+}
+
+func (s *PostgresStorage) DeleteSession(ctx context.Context, tokenHash string) error {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash=$1", tokenHash)
+	if err != nil {
+		return mapPostgresError(err, ErrSessionNotFound)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// Счётчики голосов поста и комментария обновляет триггер votes_apply,
+// здесь меняется только строка голоса
+
+func (s *PostgresStorage) Vote(ctx context.Context, userID, targetID string, direction models.VoteDirection) (*Target, error) {
+	log.Printf("User %s votes %d for %s", userID, direction, targetID)
+	if !direction.Valid() {
+		return nil, ErrInvalidVote
+	}
+	if err := validateID(userID); err != nil {
+		return nil, err
+	}
+	if err := validateID(targetID); err != nil {
+		return nil, err
+	}
+
+	column, err := s.targetColumn(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if direction == models.VoteNone {
+		_, err = s.DB.ExecContext(ctx, "DELETE FROM votes WHERE user_id=$1 AND "+column+"=$2", userID, targetID)
+	} else {
+		_, err = s.DB.ExecContext(ctx, `INSERT INTO votes (user_id, `+column+`, value) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, `+column+`) WHERE `+column+` IS NOT NULL
+			DO UPDATE SET value = EXCLUDED.value WHERE votes.value <> EXCLUDED.value`,
+			userID, targetID, int(direction))
+	}
+	if err != nil {
+		log.Println("DB Vote Error:", err)
+		return nil, mapPostgresError(err, ErrUserNotFound)
+	}
+
+	target := &Target{}
+	if column == "post_id" {
+		target.Post, err = s.GetPostByID(ctx, targetID)
+	} else {
+		target.Comment, err = s.GetCommentByID(ctx, targetID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	publishScore(ctx, s.Events, target)
+	return target, nil
+}
+
+func (s *PostgresStorage) GetVote(ctx context.Context, userID, targetID string) (models.VoteDirection, error) {
+	if err := validateID(userID); err != nil {
+		return models.VoteNone, err
+	}
+	if err := validateID(targetID); err != nil {
+		return models.VoteNone, err
+	}
+
+	var value int
+	err := s.DB.QueryRowContext(ctx, "SELECT value FROM votes WHERE user_id=$1 AND (post_id=$2 OR comment_id=$2)",
+		userID, targetID).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.VoteNone, nil
+	}
+	if err != nil {
+		return models.VoteNone, mapPostgresError(err, ErrTargetNotFound)
+	}
+	return models.VoteDirection(value), nil
+}
+
+// targetColumn определяет, пост или комментарий находится по ID, и возвращает
+// колонку votes и reports, ссылающуюся на него. За удалённый комментарий нельзя
+// голосовать, на него нельзя пожаловаться и его нельзя модерировать
+func (s *PostgresStorage) targetColumn(ctx context.Context, targetID string) (string, error) {
+	var isPost bool
+	err := s.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id=$1)", targetID).Scan(&isPost)
+	if err != nil {
+		return "", mapPostgresError(err, ErrTargetNotFound)
+	}
+	if isPost {
+		return "post_id", nil
+	}
+
+	var deleted bool
+	err = s.DB.QueryRowContext(ctx, "SELECT deleted_at IS NOT NULL FROM comments WHERE id=$1 AND NOT hidden", targetID).
+		Scan(&deleted)
+	if err != nil {
+		return "", mapPostgresError(err, ErrTargetNotFound)
+	}
+	if deleted {
+		return "", ErrCommentDeleted
+	}
+	return "comment_id", nil
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"math"
+	"time"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// PostSort - порядок сортировки постов
+type PostSort string
+
+const (
+	PostSortNew           PostSort = "NEW"
+	PostSortTop           PostSort = "TOP"
+	PostSortHot           PostSort = "HOT"
+	PostSortControversial PostSort = "CONTROVERSIAL"
+)
+
+// CommentSort - порядок сортировки комментариев внутри группы соседей
+type CommentSort string
+
+const (
+	CommentSortBest          CommentSort = "BEST"
+	CommentSortTop           CommentSort = "TOP"
+	CommentSortNew           CommentSort = "NEW"
+	CommentSortOld           CommentSort = "OLD"
+	CommentSortControversial CommentSort = "CONTROVERSIAL"
+)
+
+// TimeRange - период, за который выбираются посты
+type TimeRange string
+
+const (
+	TimeRangeDay   TimeRange = "DAY"
+	TimeRangeWeek  TimeRange = "WEEK"
+	TimeRangeMonth TimeRange = "MONTH"
+	TimeRangeYear  TimeRange = "YEAR"
+	TimeRangeAll   TimeRange = "ALL"
+)
+
+// Since возвращает нижнюю границу времени создания для периода
+// и false, если период не ограничен.
+func (r TimeRange) Since(now time.Time) (time.Time, bool) {
+	switch r {
+	case TimeRangeDay:
+		return now.AddDate(0, 0, -1), true
+	case TimeRangeWeek:
+		return now.AddDate(0, 0, -7), true
+	case TimeRangeMonth:
+		return now.AddDate(0, -1, 0), true
+	case TimeRangeYear:
+		return now.AddDate(-1, 0, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// hotEpoch - точка отсчёта для формулы hot (как у Reddit)
+const hotEpoch = 1134028003
+
+// hotRank - рейтинг «горячих» постов: логарифм рейтинга плюс бонус за свежесть.
+// Формула совпадает с SQL-функцией hot_rank из миграций.
+func hotRank(upvotes, downvotes int, createdAt time.Time) float64 {
+	score := upvotes - downvotes
+	order := math.Log10(math.Max(math.Abs(float64(score)), 1))
+	sign := 0.0
+	if score > 0 {
+		sign = 1
+	} else if score < 0 {
+		sign = -1
+	}
+	seconds := float64(createdAt.UnixMicro())/1e6 - hotEpoch
+	return sign*order + seconds/45000
+}
+
+// controversyRank - «спорность»: много голосов, поделённых примерно поровну.
+// Формула совпадает с SQL-функцией controversy_rank из миграций.
+func controversyRank(upvotes, downvotes int) float64 {
+	if upvotes <= 0 || downvotes <= 0 {
+		return 0
+	}
+	magnitude := float64(upvotes + downvotes)
+	balance := float64(min(upvotes, downvotes)) / float64(max(upvotes, downvotes))
+	return math.Pow(magnitude, balance)
+}
+
+// Less сообщает, идёт ли пост a раньше поста b при сортировке s.
+// Порядок совпадает с порядком GetAllPosts в обоих хранилищах
+func (s PostSort) Less(a, b *models.Post) bool {
+	return postRankLess(s, a, b)
+}
+
+// postRankLess сообщает, идёт ли пост a раньше поста b при сортировке sort.
+// Все сортировки убывающие, при равенстве ключа порядок задаёт ID.
+func postRankLess(sort PostSort, a, b *models.Post) bool {
+	var ka, kb float64
+	switch sort {
+	case PostSortTop:
+		ka, kb = float64(a.Score()), float64(b.Score())
+	case PostSortHot:
+		ka, kb = hotRank(a.Upvotes, a.Downvotes, a.CreatedAt), hotRank(b.Upvotes, b.Downvotes, b.CreatedAt)
+	case PostSortControversial:
+		ka, kb = controversyRank(a.Upvotes, a.Downvotes), controversyRank(b.Upvotes, b.Downvotes)
+	default:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+	if ka != kb {
+		return ka > kb
+	}
+	return a.ID > b.ID
+}
+
+// wilsonZ - квантиль нормального распределения для доверительного уровня 80%
+const wilsonZ = 1.281551565545
+
+// wilson - нижняя граница доверительного интервала Уилсона для доли положительных голосов.
+// Комментарий с немногими голосами не обгоняет комментарий с многими при той же доле.
+// Формула совпадает с SQL-функцией wilson из миграций.
+func wilson(upvotes, downvotes int) float64 {
+	n := float64(upvotes + downvotes)
+	if n <= 0 {
+		return 0
+	}
+	phat := float64(upvotes) / n
+	z2 := wilsonZ * wilsonZ
+	return (phat + z2/(2*n) - wilsonZ*math.Sqrt((phat*(1-phat)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// commentRankLess сообщает, идёт ли комментарий a раньше комментария b при сортировке sort.
+// Сортировки по голосам убывающие, при равенстве рейтинга раньше идёт более старый комментарий.
+func commentRankLess(sort CommentSort, a, b *models.Comment) bool {
+	if sort == CommentSortNew {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+
+	older := a.CreatedAt.Before(b.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && a.ID < b.ID)
+	var ka, kb float64
+	switch sort {
+	case CommentSortBest:
+		ka, kb = wilson(a.Upvotes, a.Downvotes), wilson(b.Upvotes, b.Downvotes)
+	case CommentSortTop:
+		ka, kb = float64(a.Score()), float64(b.Score())
+	case CommentSortControversial:
+		ka, kb = controversyRank(a.Upvotes, a.Downvotes), controversyRank(b.Upvotes, b.Downvotes)
+	default:
+		return older
+	}
+	if ka != kb {
+		return ka > kb
+	}
+	return older
+}
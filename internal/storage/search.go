@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// SearchType - по каким объектам идёт поиск
+type SearchType string
+
+const (
+	SearchPosts    SearchType = "POST"
+	SearchComments SearchType = "COMMENT"
+)
+
+var ErrInvalidSearch = errors.New("invalid search query")
+
+// Веса вхождений слова: совпадение в заголовке поста важнее совпадения в тексте.
+// Совпадают с весами A и B функции ts_rank по умолчанию
+const (
+	titleWeight   = 1.0
+	contentWeight = 0.4
+)
+
+// Границы подсвеченного фрагмента в словах, как MinWords и MaxWords у ts_headline
+const (
+	snippetMinWords = 15
+	snippetMaxWords = 35
+)
+
+// SearchOptions - параметры полнотекстового поиска. Найденными считаются объекты,
+// содержащие все слова запроса без учёта регистра. Результаты упорядочены
+// по убыванию релевантности, при равенстве - по ID; курсор After берётся из SearchCursor.
+// Пустой Type означает поиск по постам, CommunityID ограничивает поиск постами
+// сообщества и комментариями к ним.
+type SearchOptions struct {
+	Query       string
+	Type        SearchType
+	CommunityID string
+	First       int
+	After       string
+}
+
+// searchType возвращает тип поиска с учётом значения по умолчанию
+func (o SearchOptions) searchType() (SearchType, error) {
+	switch o.Type {
+	case "", SearchPosts:
+		return SearchPosts, nil
+	case SearchComments:
+		return SearchComments, nil
+	default:
+		return "", fmt.Errorf("%w: unknown type %q", ErrInvalidSearch, o.Type)
+	}
+}
+
+// SearchResult - найденный пост или комментарий: заполнено ровно одно из полей
+// Post и Comment. Snippet - фрагмент текста, экранированный для HTML, в котором
+// слова запроса обёрнуты в <mark>
+type SearchResult struct {
+	Post    *models.Post
+	Comment *models.Comment
+	Rank    float64
+	Snippet string
+}
+
+// ID возвращает ID найденного объекта
+func (r *SearchResult) ID() string {
+	if r.Post != nil {
+		return r.Post.ID
+	}
+	return r.Comment.ID
+}
+
+// SearchPage - страница результатов поиска
+type SearchPage struct {
+	Results     []SearchResult
+	HasNextPage bool
+}
+
+// SearchCursor возвращает курсор, указывающий на результат поиска
+func SearchCursor(r *SearchResult) string {
+	return EncodeCursor(Cursor{ID: r.ID(), Rank: r.Rank})
+}
+
+// searchAfter сообщает, идёт ли результат (rank, id) после курсора в выдаче поиска
+func (c Cursor) searchAfter(rank float64, id string) bool {
+	if rank != c.Rank {
+		return rank < c.Rank
+	}
+	return id < c.ID
+}
+
+// wordPattern выделяет слова так же, как парсер конфигурации simple: буквы и цифры
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// tokenize разбивает текст на слова в нижнем регистре
+func tokenize(text string) []string {
+	words := wordPattern.FindAllString(text, -1)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+// queryTerms возвращает различные слова поискового запроса
+func queryTerms(query string) ([]string, error) {
+	var terms []string
+	seen := map[string]bool{}
+	for _, term := range tokenize(query) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: no words to search", ErrInvalidSearch)
+	}
+	return terms, nil
+}
+
+// textRank - релевантность документа: сумма весов вхождений слов запроса,
+// делённая на 1 + ln(длина документа). Это приближение ts_rank с нормализацией 1,
+// а не та же формула: rank и порядок близких результатов в памяти и в PostgreSQL
+// различаются, поэтому курсоры поиска не переносятся между хранилищами
+func textRank(weights map[string]float64, length int, terms []string) float64 {
+	var rank float64
+	for _, term := range terms {
+		rank += weights[term]
+	}
+	return rank / (1 + math.Log(float64(max(length, 1))))
+}
+
+// htmlEscaper экранирует текст фрагмента так же, как выражение в postgresSnippetSQL
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// snippet возвращает фрагмент текста вокруг первого найденного слова
+// с подсветкой слов запроса. Без совпадений фрагмент берётся с начала текста
+func snippet(text string, terms []string) string {
+	matches := map[string]bool{}
+	for _, term := range terms {
+		matches[term] = true
+	}
+
+	words := wordPattern.FindAllStringIndex(text, -1)
+	if len(words) == 0 {
+		return htmlEscaper.Replace(text)
+	}
+	first := 0
+	for i, w := range words {
+		if matches[strings.ToLower(text[w[0]:w[1]])] {
+			first = i
+			break
+		}
+	}
+	// Совпадение оказывается ближе к началу фрагмента, но не в самом начале
+	start := max(0, first-snippetMinWords/3)
+	end := min(len(words), start+snippetMaxWords)
+	if end-start < snippetMinWords {
+		start = max(0, end-snippetMinWords)
+	}
+
+	var b strings.Builder
+	pos := words[start][0]
+	for _, w := range words[start:end] {
+		b.WriteString(htmlEscaper.Replace(text[pos:w[0]]))
+		word := htmlEscaper.Replace(text[w[0]:w[1]])
+		if matches[strings.ToLower(text[w[0]:w[1]])] {
+			word = "<mark>" + word + "</mark>"
+		}
+		b.WriteString(word)
+		pos = w[1]
+	}
+	return b.String()
+}
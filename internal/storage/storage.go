@@ -1,13 +1,255 @@
 package storage
 
-import "github.com/MosinFAM/graphql-posts/internal/models"
+import (
+	"context"
+	"slices"
 
-// Storage - интерфейс для всех типов хранилищ (in-memory и PostgreSQL)
+	"github.com/MosinFAM/graphql-posts/internal/models"
+)
+
+// Storage - интерфейс для всех типов хранилищ (in-memory и PostgreSQL).
+// Все методы принимают контекст запроса: отмена или дедлайн контекста
+// прерывают операцию.
+//
+// Хранилища публикуют в брокер событий новые посты (темы events.PostsTopic
+// и events.CommunityTopic), изменения поста и его комментариев (events.PostTopic),
+// новые счётчики голосов (events.ScoreTopic) и уведомления (events.UserTopic).
+//
+// Каждая версия текста поста и комментария сохраняется в истории ревизий:
+// первая - при создании, следующие - при правке заголовка или текста.
+// Ревизии возвращаются в порядке версий.
+//
+// DeleteComment удаляет комментарий мягко: комментарий с ответами остаётся
+// в дереве заглушкой с DeletedAt, комментарий без ответов скрывается из всех выборок.
+//
+// Комментарии выбираются в порядке CommentSort; в дереве сортировка применяется
+// к каждой группе соседей (ответов на один комментарий) отдельно.
+//
+// Имя сообщества уникально без учёта регистра, создатель сразу становится участником.
+// JoinCommunity и LeaveCommunity идемпотентны и возвращают сообщество с новым числом участников.
+//
+// CreateNotification присваивает уведомлению ID и время создания и публикует его
+// в тему получателя. MarkNotificationsRead отмечает прочитанными уведомления
+// пользователя с переданными ID (все, если список пуст) и возвращает число отмеченных;
+// чужие и уже прочитанные уведомления не учитываются.
+//
+// Теги поста и подписок на теги приводятся к нижнему регистру. FollowInterest
+// и UnfollowInterest идемпотентны, GetInterests возвращает подписки в порядке создания.
+//
+// Search ищет посты или комментарии по словам запроса; удалённые комментарии
+// не находятся, правка текста сразу отражается в поиске.
+//
+// Report сохраняет жалобу пользователя на пост или комментарий; повторная жалоба
+// на тот же объект до решения модератора ничего не меняет. Когда открытых жалоб
+// набирается FlagReportThreshold, непроверенный объект получает статус ModerationFlagged.
+// AddPost и AddComment сохраняют объект со статусом ModerationVisible или ModerationFlagged -
+// второй скрывает его до проверки модератором ещё до публикации события.
+// Moderate меняет статус модерации; одобрение и удаление закрывают открытые жалобы.
+// Вместе с объектом Moderate возвращает его статус и причину удаления до решения.
+// Скрытые модерацией посты и комментарии не попадают в GetAllPosts, GetCommentsByPostID,
+// GetReplies и поиск, но остаются в дереве и треде, чтобы не терять ответы.
+// На заблокированный LockComment комментарий и его поддерево нельзя отвечать.
+//
+// BanUser банит пользователя в сообществе, повторный бан заменяет действующий.
+// Забаненный пользователь не может публиковать посты и комментарии в сообществе:
+// AddPost и AddComment возвращают *BannedError. Истёкшие баны не действуют и не
+// возвращаются GetBans. Открытая апелляция AppealBan попадает в очередь модерации;
+// ResolveAppeal закрывает её, а принятая апелляция снимает бан. Новый бан и UnbanUser
+// закрывают открытые апелляции без снятия бана.
+//
+// TrainClassifier добавляет обучающий текст классификатора спама: каждое слово
+// учитывается один раз на текст, UntrainClassifier вычитает ранее добавленный текст,
+// не опуская счётчики ниже нуля. GetClassifierStats возвращает статистику только
+// по переданным словам. RecordFilterDecision присваивает записи журнала фильтра
+// контента ID и время создания; GetFilterDecisions возвращает записи от новых к старым.
+//
+// Vote ставит, меняет или отзывает (VoteNone) голос пользователя за пост или комментарий;
+// у каждого пользователя не больше одного голоса за объект. Счётчики Upvotes и Downvotes
+// хранятся вместе с постом и комментарием и обновляются при голосовании.
 type Storage interface {
-	GetAllPosts() ([]models.Post, error)
-	GetPostByID(id string) (*models.Post, error)
-	AddPost(title, content string, allowComments bool) (models.Post, error)
-	AddComment(postID string, parentID *string, content string) (*models.Comment, error)
-	GetCommentsByPostID(postID string, limit, offset int) ([]*models.Comment, error)
-	SubscribeToComments(postID string) (<-chan *models.Comment, error)
+	GetAllPosts(ctx context.Context, opts PostListOptions) (*PostPage, error)
+	GetPostByID(ctx context.Context, id string) (*models.Post, error)
+	AddPost(ctx context.Context, authorID string, communityID *string, title, content string, tags []string,
+		allowComments bool, status models.ModerationStatus) (models.Post, error)
+	UpdatePost(ctx context.Context, editorID, id string, update PostUpdate) (*models.Post, error)
+	GetPostRevisions(ctx context.Context, postID string) ([]*models.Revision, error)
+	AddComment(ctx context.Context, authorID, postID string, parentID *string, content string,
+		status models.ModerationStatus) (*models.Comment, error)
+	GetCommentByID(ctx context.Context, id string) (*models.Comment, error)
+	UpdateComment(ctx context.Context, editorID, id, content string) (*models.Comment, error)
+	GetCommentRevisions(ctx context.Context, commentID string) ([]*models.Revision, error)
+	DeleteComment(ctx context.Context, id string) (*models.Comment, error)
+	GetCommentsByPostID(ctx context.Context, postID string, opts CommentListOptions) (*CommentPage, error)
+	GetReplies(ctx context.Context, parentID string, opts CommentListOptions) (*CommentPage, error)
+	GetCommentTree(ctx context.Context, postID string, maxDepth, maxChildren int, sort CommentSort) ([]*models.CommentNode, error)
+	GetThread(ctx context.Context, commentID string) ([]*models.Comment, error)
+	Vote(ctx context.Context, userID, targetID string, direction models.VoteDirection) (*Target, error)
+	GetVote(ctx context.Context, userID, targetID string) (models.VoteDirection, error)
+	Search(ctx context.Context, opts SearchOptions) (*SearchPage, error)
+
+	Report(ctx context.Context, reporterID, targetID, reason string) error
+	GetModQueue(ctx context.Context, opts ModQueueOptions) ([]*ModQueueItem, error)
+	Moderate(ctx context.Context, targetID string, status models.ModerationStatus, reason *string) (*ModerationResult, error)
+	LockComment(ctx context.Context, id string, locked bool) (*models.Comment, error)
+
+	BanUser(ctx context.Context, opts BanOptions) (*models.Ban, error)
+	UnbanUser(ctx context.Context, communityID, userID string) error
+	GetBans(ctx context.Context, communityID string) ([]*models.Ban, error)
+	AppealBan(ctx context.Context, communityID, userID, message string) (*models.BanAppeal, error)
+	ResolveAppeal(ctx context.Context, appealID string, accepted bool) (*models.BanAppeal, error)
+
+	TrainClassifier(ctx context.Context, spam bool, tokens []string) error
+	UntrainClassifier(ctx context.Context, spam bool, tokens []string) error
+	GetClassifierStats(ctx context.Context, tokens []string) (*ClassifierStats, error)
+	RecordFilterDecision(ctx context.Context, decision models.FilterDecision) (*models.FilterDecision, error)
+	GetFilterDecisions(ctx context.Context, first int) ([]*models.FilterDecision, error)
+
+	CreateCommunity(ctx context.Context, creatorID, name, description string) (*models.Community, error)
+	GetCommunityByID(ctx context.Context, id string) (*models.Community, error)
+	GetCommunityByName(ctx context.Context, name string) (*models.Community, error)
+	JoinCommunity(ctx context.Context, userID, communityID string) (*models.Community, error)
+	LeaveCommunity(ctx context.Context, userID, communityID string) (*models.Community, error)
+	IsMember(ctx context.Context, userID, communityID string) (bool, error)
+	GetJoinedCommunities(ctx context.Context, userID string) ([]*models.Community, error)
+
+	CreateNotification(ctx context.Context, notification models.Notification) (*models.Notification, error)
+	GetNotifications(ctx context.Context, userID string, opts NotificationListOptions) (*NotificationPage, error)
+	CountUnreadNotifications(ctx context.Context, userID string) (int, error)
+	MarkNotificationsRead(ctx context.Context, userID string, ids []string) (int, error)
+
+	FollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) (*models.Interest, error)
+	UnfollowInterest(ctx context.Context, userID string, kind models.InterestKind, target string) error
+	GetInterests(ctx context.Context, userID string) ([]*models.Interest, error)
+
+	CreateUser(ctx context.Context, username, passwordHash string) (*models.User, error)
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	SetUserRole(ctx context.Context, userID string, role models.Role) (*models.User, error)
+	CreateSession(ctx context.Context, session models.Session) error
+	GetSession(ctx context.Context, tokenHash string) (*models.Session, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
+
+// PostListOptions - параметры выборки постов: курсорная пагинация,
+// порядок сортировки, период и фильтры. Пустые Sort и TimeRange означают HOT и ALL.
+// Непустой фильтр оставляет посты, у которых автор, сообщество или хотя бы один тег
+// входят в список; несколько фильтров применяются вместе.
+type PostListOptions struct {
+	First        int
+	After        string
+	Sort         PostSort
+	TimeRange    TimeRange
+	AuthorIDs    []string
+	CommunityIDs []string
+	Tags         []string
+}
+
+// SortOrder возвращает порядок сортировки с учётом значения по умолчанию
+func (o PostListOptions) SortOrder() PostSort {
+	if o.Sort == "" {
+		return PostSortHot
+	}
+	return o.Sort
+}
+
+// matches - проходит ли пост фильтры выборки
+func (o PostListOptions) matches(post *models.Post) bool {
+	if len(o.AuthorIDs) > 0 && (post.AuthorID == nil || !slices.Contains(o.AuthorIDs, *post.AuthorID)) {
+		return false
+	}
+	if len(o.CommunityIDs) > 0 && (post.CommunityID == nil || !slices.Contains(o.CommunityIDs, *post.CommunityID)) {
+		return false
+	}
+	if len(o.Tags) > 0 && !slices.ContainsFunc(post.Tags, func(tag string) bool { return slices.Contains(o.Tags, tag) }) {
+		return false
+	}
+	return true
+}
+
+// PostUpdate - изменения поста, nil означает, что поле не меняется.
+// Правка заголовка или текста отмечается в EditedAt, любое изменение - в UpdatedAt.
+type PostUpdate struct {
+	Title         *string
+	Content       *string
+	AllowComments *bool
+}
+
+// edits - меняет ли обновление текст поста
+func (u PostUpdate) edits(post *models.Post) bool {
+	return (u.Title != nil && *u.Title != post.Title) || (u.Content != nil && *u.Content != post.Content)
+}
+
+// Target - пост или комментарий, например объект голосования после изменения голоса:
+// заполнено ровно одно поле
+type Target struct {
+	Post    *models.Post
+	Comment *models.Comment
+}
+
+// PostPage - страница постов
+type PostPage struct {
+	Posts       []models.Post
+	HasNextPage bool
+}
+
+// NotificationListOptions - параметры выборки уведомлений: от новых к старым
+// по (created_at, id), курсор After берётся из NotificationCursor
+type NotificationListOptions struct {
+	First      int
+	After      string
+	UnreadOnly bool
+}
+
+// NotificationPage - страница уведомлений
+type NotificationPage struct {
+	Notifications []*models.Notification
+	HasNextPage   bool
+}
+
+// CommentListOptions - параметры выборки комментариев.
+// Комментарии упорядочены сортировкой Sort (пустая означает OLD - по (created_at, id));
+// если задан курсор After, выборка начинается строго после него, иначе используется устаревший Offset.
+type CommentListOptions struct {
+	First  int
+	After  string
+	Offset int
+	Sort   CommentSort
+}
+
+// sortOrder возвращает порядок сортировки с учётом значения по умолчанию
+func (o CommentListOptions) sortOrder() CommentSort {
+	return commentSortOrder(o.Sort)
+}
+
+// commentSortOrder подставляет сортировку комментариев по умолчанию
+func commentSortOrder(sort CommentSort) CommentSort {
+	if sort == "" {
+		return CommentSortOld
+	}
+	return sort
+}
+
+// CommentPage - страница комментариев
+type CommentPage struct {
+	Comments    []*models.Comment
+	HasNextPage bool
+}
+
+// Ограничения дерева комментариев
+const (
+	DefaultTreeDepth = 10
+	MaxTreeDepth     = 100
+)
+
+// treeLimits приводит ограничения дерева к допустимым значениям:
+// maxDepth - максимальная глубина (0 - только корневые комментарии),
+// maxChildren - сколько дочерних комментариев показывать у каждого узла.
+func treeLimits(maxDepth, maxChildren int) (int, int) {
+	if maxDepth < 0 {
+		maxDepth = DefaultTreeDepth
+	}
+	if maxDepth > MaxTreeDepth {
+		maxDepth = MaxTreeDepth
+	}
+	return maxDepth, PageSize(maxChildren)
 }
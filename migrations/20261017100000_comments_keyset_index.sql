@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS comments_post_created_idx ON comments (post_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_post_created_idx;
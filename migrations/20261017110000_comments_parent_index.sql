@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS comments_parent_created_idx ON comments (parent_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_parent_created_idx;
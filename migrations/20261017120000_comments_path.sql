@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS path TEXT COLLATE "C";
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INT NOT NULL DEFAULT 0;
+
+-- Заполняем путь и глубину для существующих комментариев.
+-- Сегмент: время создания в микросекундах (16 hex-символов) и ID без дефисов.
+WITH RECURSIVE tree AS (
+    SELECT id,
+           lpad(to_hex((EXTRACT(EPOCH FROM created_at) * 1000000)::BIGINT), 16, '0') || replace(id::TEXT, '-', '') AS path,
+           0 AS depth
+    FROM comments
+    WHERE parent_id IS NULL
+    UNION ALL
+    SELECT c.id,
+           t.path || '.' || lpad(to_hex((EXTRACT(EPOCH FROM c.created_at) * 1000000)::BIGINT), 16, '0') || replace(c.id::TEXT, '-', ''),
+           t.depth + 1
+    FROM comments c
+    JOIN tree t ON c.parent_id = t.id
+)
+UPDATE comments c SET path = tree.path, depth = tree.depth
+FROM tree
+WHERE c.id = tree.id;
+
+ALTER TABLE comments ALTER COLUMN path SET NOT NULL;
+CREATE INDEX IF NOT EXISTS comments_post_path_idx ON comments (post_id, path);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_post_path_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS depth;
+ALTER TABLE comments DROP COLUMN IF EXISTS path;
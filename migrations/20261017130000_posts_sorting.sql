@@ -0,0 +1,38 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT NOW();
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS upvotes INT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS downvotes INT NOT NULL DEFAULT 0;
+
+-- Формулы совпадают с hotRank и controversyRank в internal/storage/ranking.go
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION hot_rank(upvotes INT, downvotes INT, created_at TIMESTAMP)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT SIGN(upvotes - downvotes)::DOUBLE PRECISION * LOG(GREATEST(ABS(upvotes - downvotes), 1)::DOUBLE PRECISION)
+        + (EXTRACT(EPOCH FROM created_at)::DOUBLE PRECISION - 1134028003) / 45000
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION controversy_rank(upvotes INT, downvotes INT)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE
+        WHEN upvotes <= 0 OR downvotes <= 0 THEN 0
+        ELSE POWER((upvotes + downvotes)::DOUBLE PRECISION,
+                   LEAST(upvotes, downvotes)::DOUBLE PRECISION / GREATEST(upvotes, downvotes))
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+CREATE INDEX IF NOT EXISTS posts_new_idx ON posts (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS posts_top_idx ON posts ((upvotes - downvotes) DESC, id DESC);
+CREATE INDEX IF NOT EXISTS posts_hot_idx ON posts (hot_rank(upvotes, downvotes, created_at) DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_hot_idx;
+DROP INDEX IF EXISTS posts_top_idx;
+DROP INDEX IF EXISTS posts_new_idx;
+DROP FUNCTION IF EXISTS controversy_rank(INT, INT);
+DROP FUNCTION IF EXISTS hot_rank(INT, INT, TIMESTAMP);
+ALTER TABLE posts DROP COLUMN IF EXISTS downvotes;
+ALTER TABLE posts DROP COLUMN IF EXISTS upvotes;
+ALTER TABLE posts DROP COLUMN IF EXISTS created_at;
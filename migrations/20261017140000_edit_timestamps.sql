@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+UPDATE posts SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE posts ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE posts ALTER COLUMN updated_at SET DEFAULT NOW();
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP NULL;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP NULL;
+UPDATE comments SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE comments ALTER COLUMN created_at SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE comments ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    username TEXT NOT NULL CHECK (LENGTH(username) BETWEEN 3 AND 32),
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Имена уникальны без учёта регистра
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_idx ON users (lower(username));
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions (user_id, expires_at);
+
+-- Существующие посты и комментарии остаются анонимными
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author_id UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS author_id UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS posts_author_idx ON posts (author_id);
+CREATE INDEX IF NOT EXISTS comments_author_idx ON comments (author_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_author_idx;
+DROP INDEX IF EXISTS posts_author_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS author_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS author_id;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Комментарии удаляются мягко, поэтому физическое удаление родителя
+-- не должно каскадно стирать всю ветку ответов. NO ACTION проверяется в конце
+-- оператора, так что удаление поста вместе со всеми комментариями по-прежнему работает
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_parent_id_fkey;
+ALTER TABLE comments ADD CONSTRAINT comments_parent_id_fkey
+    FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE NO ACTION;
+
+-- +goose Down
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_parent_id_fkey;
+ALTER TABLE comments ADD CONSTRAINT comments_parent_id_fkey
+    FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments DROP COLUMN IF EXISTS hidden;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
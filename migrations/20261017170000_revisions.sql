@@ -0,0 +1,100 @@
+-- +goose Up
+-- Кто внёс последнюю правку текста (автор или модератор)
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    editor_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (post_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    editor_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (comment_id, version)
+);
+
+-- Существующие тексты становятся первой версией
+INSERT INTO post_revisions (post_id, version, editor_id, title, content, created_at)
+SELECT id, 1, author_id, title, content, created_at FROM posts
+ON CONFLICT DO NOTHING;
+
+INSERT INTO comment_revisions (comment_id, version, editor_id, content, created_at)
+SELECT id, 1, author_id, content, created_at FROM comments
+ON CONFLICT DO NOTHING;
+
+-- Ревизия пишется тем же оператором, что создаёт или правит текст.
+-- Строка поста заблокирована UPDATE, поэтому номера версий не пересекаются
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_post_revision() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO post_revisions (post_id, version, editor_id, title, content, created_at)
+    SELECT NEW.id, COALESCE(MAX(version), 0) + 1, COALESCE(NEW.edited_by, NEW.author_id),
+        NEW.title, NEW.content, COALESCE(NEW.edited_at, NEW.created_at)
+    FROM post_revisions WHERE post_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_comment_revision() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO comment_revisions (comment_id, version, editor_id, content, created_at)
+    SELECT NEW.id, COALESCE(MAX(version), 0) + 1, COALESCE(NEW.edited_by, NEW.author_id),
+        NEW.content, COALESCE(NEW.edited_at, NEW.created_at)
+    FROM comment_revisions WHERE comment_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION forbid_revision_update() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'revisions are append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER posts_revision_insert AFTER INSERT ON posts
+    FOR EACH ROW EXECUTE FUNCTION record_post_revision();
+CREATE TRIGGER posts_revision_update AFTER UPDATE OF title, content ON posts
+    FOR EACH ROW WHEN (OLD.title IS DISTINCT FROM NEW.title OR OLD.content IS DISTINCT FROM NEW.content)
+    EXECUTE FUNCTION record_post_revision();
+
+CREATE TRIGGER comments_revision_insert AFTER INSERT ON comments
+    FOR EACH ROW EXECUTE FUNCTION record_comment_revision();
+CREATE TRIGGER comments_revision_update AFTER UPDATE OF content ON comments
+    FOR EACH ROW WHEN (OLD.content IS DISTINCT FROM NEW.content)
+    EXECUTE FUNCTION record_comment_revision();
+
+CREATE TRIGGER post_revisions_append_only BEFORE UPDATE ON post_revisions
+    FOR EACH ROW EXECUTE FUNCTION forbid_revision_update();
+CREATE TRIGGER comment_revisions_append_only BEFORE UPDATE ON comment_revisions
+    FOR EACH ROW EXECUTE FUNCTION forbid_revision_update();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS comment_revisions_append_only ON comment_revisions;
+DROP TRIGGER IF EXISTS post_revisions_append_only ON post_revisions;
+DROP TRIGGER IF EXISTS comments_revision_update ON comments;
+DROP TRIGGER IF EXISTS comments_revision_insert ON comments;
+DROP TRIGGER IF EXISTS posts_revision_update ON posts;
+DROP TRIGGER IF EXISTS posts_revision_insert ON posts;
+DROP FUNCTION IF EXISTS forbid_revision_update();
+DROP FUNCTION IF EXISTS record_comment_revision();
+DROP FUNCTION IF EXISTS record_post_revision();
+DROP TABLE IF EXISTS comment_revisions;
+DROP TABLE IF EXISTS post_revisions;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_by;
+ALTER TABLE posts DROP COLUMN IF EXISTS edited_by;
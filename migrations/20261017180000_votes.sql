@@ -0,0 +1,58 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS upvotes INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS downvotes INT NOT NULL DEFAULT 0;
+
+-- Голос относится ровно к одному посту или комментарию
+CREATE TABLE IF NOT EXISTS votes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NULL REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id UUID NULL REFERENCES comments(id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+-- Не больше одного голоса пользователя за объект
+CREATE UNIQUE INDEX IF NOT EXISTS votes_user_post_idx ON votes (user_id, post_id) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS votes_user_comment_idx ON votes (user_id, comment_id) WHERE comment_id IS NOT NULL;
+
+-- Денормализованные счётчики upvotes/downvotes поддерживает триггер,
+-- поэтому выборки постов и комментариев не агрегируют голоса
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION apply_vote() RETURNS TRIGGER AS $$
+DECLARE
+    up_delta INT := 0;
+    down_delta INT := 0;
+    target_post UUID;
+    target_comment UUID;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.value = 1 THEN up_delta := up_delta - 1; ELSE down_delta := down_delta - 1; END IF;
+        target_post := OLD.post_id;
+        target_comment := OLD.comment_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.value = 1 THEN up_delta := up_delta + 1; ELSE down_delta := down_delta + 1; END IF;
+        target_post := NEW.post_id;
+        target_comment := NEW.comment_id;
+    END IF;
+
+    IF target_post IS NOT NULL THEN
+        UPDATE posts SET upvotes = upvotes + up_delta, downvotes = downvotes + down_delta WHERE id = target_post;
+    ELSE
+        UPDATE comments SET upvotes = upvotes + up_delta, downvotes = downvotes + down_delta WHERE id = target_comment;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER votes_apply AFTER INSERT OR UPDATE OR DELETE ON votes
+    FOR EACH ROW EXECUTE FUNCTION apply_vote();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS votes_apply ON votes;
+DROP FUNCTION IF EXISTS apply_vote();
+DROP TABLE IF EXISTS votes;
+ALTER TABLE comments DROP COLUMN IF EXISTS downvotes;
+ALTER TABLE comments DROP COLUMN IF EXISTS upvotes;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Нижняя граница интервала Уилсона (80%) для доли положительных голосов.
+-- Формула совпадает с wilson в internal/storage/ranking.go
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION wilson(upvotes INT, downvotes INT)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE
+        WHEN upvotes + downvotes <= 0 THEN 0
+        ELSE (p + z * z / (2 * n) - z * SQRT((p * (1 - p) + z * z / (4 * n)) / n)) / (1 + z * z / n)
+    END
+    FROM (SELECT (upvotes + downvotes)::DOUBLE PRECISION AS n,
+                 upvotes::DOUBLE PRECISION / NULLIF(upvotes + downvotes, 0) AS p,
+                 1.281551565545::DOUBLE PRECISION AS z) AS v
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS wilson(INT, INT);
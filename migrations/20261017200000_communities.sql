@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS communities (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL CHECK (name ~ '^[A-Za-z0-9_]{3,21}$'),
+    description TEXT NOT NULL DEFAULT '' CHECK (LENGTH(description) <= 500),
+    creator_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    member_count INT NOT NULL DEFAULT 0
+);
+
+-- Имена уникальны без учёта регистра
+CREATE UNIQUE INDEX IF NOT EXISTS communities_name_idx ON communities (lower(name));
+
+CREATE TABLE IF NOT EXISTS community_members (
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (community_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS community_members_user_idx ON community_members (user_id);
+
+-- Денормализованный member_count поддерживает триггер
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION apply_membership() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE communities SET member_count = member_count + 1 WHERE id = NEW.community_id;
+    ELSE
+        UPDATE communities SET member_count = member_count - 1 WHERE id = OLD.community_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER community_members_apply AFTER INSERT OR DELETE ON community_members
+    FOR EACH ROW EXECUTE FUNCTION apply_membership();
+
+-- Существующие посты остаются вне сообществ
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS community_id UUID NULL REFERENCES communities(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS posts_community_idx ON posts (community_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_community_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS community_id;
+DROP TRIGGER IF EXISTS community_members_apply ON community_members;
+DROP FUNCTION IF EXISTS apply_membership();
+DROP TABLE IF EXISTS community_members;
+DROP TABLE IF EXISTS communities;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Теги хранятся в нижнем регистре, пересечение с подписками ищется по GIN-индексу
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'
+    CHECK (cardinality(tags) <= 5);
+
+CREATE INDEX IF NOT EXISTS posts_tags_idx ON posts USING GIN (tags);
+
+CREATE INDEX IF NOT EXISTS posts_author_created_idx ON posts (author_id, created_at DESC, id DESC);
+
+-- Подписки на авторов и теги для домашней ленты
+CREATE TABLE IF NOT EXISTS interests (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('author', 'tag')),
+    target TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, kind, target)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS interests;
+DROP INDEX IF EXISTS posts_author_created_idx;
+DROP INDEX IF EXISTS posts_tags_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS tags;
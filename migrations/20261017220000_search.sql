@@ -0,0 +1,22 @@
+-- +goose Up
+-- Поисковые векторы вычисляются из текста при каждой вставке и правке.
+-- Конфигурация simple не выделяет основы слов и одинаково работает для любого языка,
+-- заголовок поста весит больше текста
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', content), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS posts_search_idx ON posts USING GIN (search_vector);
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (setweight(to_tsvector('simple', content), 'B')) STORED;
+
+-- Удалённые комментарии в поиск не попадают
+CREATE INDEX IF NOT EXISTS comments_search_idx ON comments USING GIN (search_vector) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_search_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS posts_search_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
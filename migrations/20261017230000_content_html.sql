@@ -0,0 +1,10 @@
+-- +goose Up
+-- HTML, отрисованный из Markdown, хранится рядом с текстом и обновляется вместе с ним.
+-- Пустая строка у непустого текста означает, что HTML ещё не отрисован:
+-- у существующих строк он строится при чтении
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE comments DROP COLUMN IF EXISTS content_html;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_html;
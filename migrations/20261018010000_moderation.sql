@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS mod_status TEXT NOT NULL DEFAULT 'visible'
+        CHECK (mod_status IN ('visible', 'flagged', 'approved', 'removed')),
+    ADD COLUMN IF NOT EXISTS removal_reason TEXT NULL CHECK (LENGTH(removal_reason) <= 200);
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS mod_status TEXT NOT NULL DEFAULT 'visible'
+        CHECK (mod_status IN ('visible', 'flagged', 'approved', 'removed')),
+    ADD COLUMN IF NOT EXISTS removal_reason TEXT NULL CHECK (LENGTH(removal_reason) <= 200),
+    ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Скрытые до проверки объекты выбираются в очередь модерации без жалоб
+CREATE INDEX IF NOT EXISTS posts_flagged_idx ON posts (id) WHERE mod_status = 'flagged';
+CREATE INDEX IF NOT EXISTS comments_flagged_idx ON comments (id) WHERE mod_status = 'flagged';
+
+CREATE TABLE IF NOT EXISTS reports (
+    id UUID PRIMARY KEY,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NULL REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id UUID NULL REFERENCES comments(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (LENGTH(reason) <= 200),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+-- У пользователя не больше одной открытой жалобы на объект
+CREATE UNIQUE INDEX IF NOT EXISTS reports_post_open_idx ON reports (post_id, reporter_id)
+    WHERE post_id IS NOT NULL AND resolved_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS reports_comment_open_idx ON reports (comment_id, reporter_id)
+    WHERE comment_id IS NOT NULL AND resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS reports;
+DROP INDEX IF EXISTS comments_flagged_idx;
+DROP INDEX IF EXISTS posts_flagged_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS locked, DROP COLUMN IF EXISTS removal_reason, DROP COLUMN IF EXISTS mod_status;
+ALTER TABLE posts DROP COLUMN IF EXISTS removal_reason, DROP COLUMN IF EXISTS mod_status;
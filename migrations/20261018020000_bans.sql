@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS bans (
+    id UUID PRIMARY KEY,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moderator_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL CHECK (LENGTH(reason) <= 200),
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- NULL у бессрочного бана
+    expires_at TIMESTAMP NULL,
+    UNIQUE (community_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS ban_appeals (
+    id UUID PRIMARY KEY,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL CHECK (LENGTH(message) <= 1000),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP NULL,
+    accepted BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- У пользователя не больше одной открытой апелляции в сообществе
+CREATE UNIQUE INDEX IF NOT EXISTS ban_appeals_open_idx ON ban_appeals (community_id, user_id)
+    WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS ban_appeals;
+DROP TABLE IF EXISTS bans;
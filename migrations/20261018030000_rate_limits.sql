@@ -0,0 +1,14 @@
+-- +goose Up
+-- Корзины токенов ограничителя частоты запросов
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    -- Когда корзина наполнится и строку можно удалить
+    full_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_idx ON rate_limits (full_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS rate_limits;
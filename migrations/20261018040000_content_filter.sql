@@ -0,0 +1,33 @@
+-- +goose Up
+-- Статистика обучения классификатора спама
+CREATE TABLE IF NOT EXISTS classifier_docs (
+    spam BOOLEAN PRIMARY KEY,
+    count INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS classifier_tokens (
+    token TEXT PRIMARY KEY,
+    spam_count INT NOT NULL DEFAULT 0,
+    ham_count INT NOT NULL DEFAULT 0
+);
+
+-- Журнал решений фильтра контента. target_id ссылается на пост или комментарий
+-- в зависимости от kind и пуст у отклонённого контента
+CREATE TABLE IF NOT EXISTS filter_decisions (
+    id UUID PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('post', 'comment')),
+    target_id UUID NULL,
+    author_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    content TEXT NOT NULL,
+    verdict TEXT NOT NULL CHECK (verdict IN ('accept', 'flag', 'reject')),
+    filter TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS filter_decisions_created_at_idx ON filter_decisions (created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS filter_decisions;
+DROP TABLE IF EXISTS classifier_tokens;
+DROP TABLE IF EXISTS classifier_docs;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Длина текста поста ограничена, как у комментария; существующие посты не проверяются
+ALTER TABLE posts ADD CONSTRAINT posts_content_length CHECK (LENGTH(content) <= 40000) NOT VALID;
+
+-- +goose Down
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_content_length;